@@ -0,0 +1,104 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+import "math/bits"
+
+// AbsorbAscending moves every item of src into t, replacing items of t that
+// are equal to them, and leaves src empty.  It is meant for compaction jobs
+// that merge a "delta" tree into a "base" tree.  Both trees must be ordered
+// the same way.
+//
+// AbsorbAscending picks the cheapest of three strategies:
+//   - if src's items all come after t's, it appends them with AppendMax,
+//     which makes one comparison per item;
+//   - if src is large relative to t, it merges both trees' items in a
+//     single linear pass and rebuilds t with fully packed nodes, as Rebuild
+//     does;
+//   - otherwise it inserts src's items one at a time.
+func (t *BTreeG[T]) AbsorbAscending(src *BTreeG[T]) {
+	if src.Len() == 0 {
+		return
+	}
+	start, n := t.traceStart(), src.Len()
+	defer t.traceEnd("AbsorbAscending", start, n)
+	first, _ := src.Min()
+	last, ok := t.Max()
+	switch {
+	case !ok || t.cow.less(last, first):
+		src.Ascend(func(item T) bool {
+			t.AppendMax(item)
+			return true
+		})
+	case src.Len64()*int64(bits.Len(uint(t.Len()))) > t.Len64()+src.Len64():
+		t.mergeFrom(src)
+	default:
+		src.Ascend(func(item T) bool {
+			t.ReplaceOrInsert(item)
+			return true
+		})
+	}
+	src.Clear(true)
+}
+
+// mergeFrom merges src's items into t and rebuilds t from the result.
+func (t *BTreeG[T]) mergeFrom(src *BTreeG[T]) {
+	ours, theirs := t.all(), src.all()
+	merged := make([]T, 0, len(ours)+len(theirs))
+	added := func(item, old T, replaced bool) {
+		if t.limit != nil {
+			t.usage += t.limit.weigh(item)
+			if replaced {
+				t.usage -= t.limit.weigh(old)
+			}
+		}
+		for _, v := range t.views {
+			if replaced {
+				v.Remove(old)
+			}
+			v.Add(item)
+		}
+	}
+	for len(ours) > 0 && len(theirs) > 0 {
+		switch {
+		case t.cow.less(ours[0], theirs[0]):
+			merged = append(merged, ours[0])
+			ours = ours[1:]
+		case t.cow.less(theirs[0], ours[0]):
+			merged = append(merged, theirs[0])
+			added(theirs[0], theirs[0], false)
+			theirs = theirs[1:]
+		default:
+			merged = append(merged, theirs[0])
+			added(theirs[0], ours[0], true)
+			ours, theirs = ours[1:], theirs[1:]
+		}
+	}
+	merged = append(merged, ours...)
+	for _, item := range theirs {
+		merged = append(merged, item)
+		added(item, item, false)
+	}
+	usage := t.usage
+	t.clear(true)
+	t.bloom, t.keys = nil, nil
+	t.root = t.cow.buildSorted(merged, t.maxItems())
+	t.length, t.usage = len(merged), usage
+	t.refresh()
+	t.enforceLimit()
+}
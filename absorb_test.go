@@ -0,0 +1,105 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestAbsorbAscending(t *testing.T) {
+	type kv struct{ k, v int }
+	less := func(a, b kv) bool { return a.k < b.k }
+	r := rand.New(rand.NewSource(1))
+	for _, sizes := range []struct{ base, delta, max int }{
+		{0, 100, 1000},     // empty base: appended
+		{1000, 10, 10000},  // small delta: inserted
+		{1000, 1000, 3000}, // large delta: merged
+		{100, 100, 100},    // mostly replacements
+	} {
+		base, delta := NewG(*btreeDegree, less), NewG(*btreeDegree, less)
+		want := map[int]int{}
+		for i := 0; i < sizes.base; i++ {
+			k := r.Intn(sizes.max)
+			base.ReplaceOrInsert(kv{k, 0})
+			want[k] = 0
+		}
+		for i := 0; i < sizes.delta; i++ {
+			k := r.Intn(sizes.max)
+			delta.ReplaceOrInsert(kv{k, 1})
+			want[k] = 1
+		}
+		counts := NewBucketSumG(func(x kv) int { return x.v }, func(kv) int { return 1 })
+		base.AddView(counts)
+		base.AbsorbAscending(delta)
+		if delta.Len() != 0 {
+			t.Errorf("%+v: delta not drained", sizes)
+		}
+		if err := base.Verify(); err != nil {
+			t.Fatal(err)
+		}
+		got := map[int]int{}
+		base.Ascend(func(x kv) bool {
+			got[x.k] = x.v
+			return true
+		})
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%+v: wrong contents", sizes)
+		}
+		if counts.Get(0)+counts.Get(1) != base.Len() {
+			t.Errorf("%+v: view counts %d + %d, Len %d", sizes, counts.Get(0), counts.Get(1), base.Len())
+		}
+	}
+}
+
+func TestAbsorbAscendingAppend(t *testing.T) {
+	base, delta := NewOrderedG[int](2), NewOrderedG[int](2)
+	for i := 0; i < 10; i++ {
+		base.ReplaceOrInsert(i)
+		delta.ReplaceOrInsert(i + 10)
+	}
+	base.AbsorbAscending(delta)
+	if got := base.all(); !reflect.DeepEqual(got, intRange(20, false)) {
+		t.Errorf("got %v", got)
+	}
+}
+
+func BenchmarkAbsorbAscending(b *testing.B) {
+	for _, size := range []int{100, 10000} {
+		b.Run(fmt.Sprint(size), func(b *testing.B) {
+			base := NewOrderedG[int](*btreeDegree)
+			for _, v := range rand.Perm(100000) {
+				base.ReplaceOrInsert(v * 2)
+			}
+			delta := rand.Perm(size)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				dst := base.Clone()
+				src := NewOrderedG[int](*btreeDegree)
+				for _, v := range delta {
+					src.ReplaceOrInsert(v*20 + 1)
+				}
+				b.StartTimer()
+				dst.AbsorbAscending(src)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+import "sort"
+
+// OpKind is the kind of write an Op makes.
+type OpKind int
+
+const (
+	// OpInsert inserts Item, replacing any equal item, like ReplaceOrInsert.
+	OpInsert OpKind = iota
+	// OpDelete deletes the item equal to Item, if any, like Delete.
+	OpDelete
+	// OpDeleteRange deletes every item within the range [Item, End).
+	OpDeleteRange
+)
+
+// Op is a single write in a batch passed to Apply.  Ops are plain values, so
+// a batch can be logged, replicated, and replayed as a unit.
+type Op[T any] struct {
+	Kind OpKind
+	Item T
+	// End is the end of the range deleted by an OpDeleteRange, and is
+	// otherwise unused.
+	End T
+}
+
+// Apply applies a batch of ops to the tree.  The result is the same as
+// applying the ops one at a time, in order, but Apply first works out the
+// net effect of the batch, so that an item written several times, or
+// inserted and then deleted by a later range deletion, is written only
+// once, and then makes the writes in ascending order.
+//
+// Resolving a batch of k ops takes O(k log k) time, or up to O(k²) if it
+// holds many disjoint range deletions; each write that survives, and each
+// item deleted by a range, then takes O(log n) time.
+func (t *BTreeG[T]) Apply(ops []Op[T]) {
+	less := t.cow.less
+	// Work backwards, collecting the ranges deleted so far, so that point
+	// ops hidden by a later range deletion can be dropped.  (The batch is
+	// resolved with slices, since a method of BTreeG[T] can't build a tree
+	// of Ops or ranges without instantiating BTreeG without end.)
+	var deleted []RangeG[T] // sorted and disjoint
+	points := make([]int, 0, len(ops))
+	for i := len(ops) - 1; i >= 0; i-- {
+		op := ops[i]
+		if op.Kind == OpDeleteRange {
+			if less(op.Item, op.End) {
+				deleted = addRange(deleted, op.Item, op.End, less)
+			}
+		} else if !rangesContain(deleted, op.Item, less) {
+			points = append(points, i)
+		}
+	}
+	// points is in descending order of index, so after a stable sort by
+	// item, the last op on each item comes first among its equals.
+	sort.SliceStable(points, func(i, j int) bool {
+		return less(ops[points[i]].Item, ops[points[j]].Item)
+	})
+	// The surviving point ops all came after the range deletions covering
+	// their items, so make the deletions first.
+	var doomed []T
+	for _, r := range deleted {
+		doomed = t.AppendRange(doomed[:0], r.Start, r.End)
+		for _, item := range doomed {
+			t.Delete(item)
+		}
+	}
+	for i, p := range points {
+		op := ops[p]
+		if i > 0 && !less(ops[points[i-1]].Item, op.Item) {
+			continue // hidden by a later op on the same item
+		}
+		if op.Kind == OpInsert {
+			t.ReplaceOrInsert(op.Item)
+		} else {
+			t.Delete(op.Item)
+		}
+	}
+}
+
+// addRange adds [start, end) to rs, a sorted slice of disjoint ranges,
+// merging it with any ranges it overlaps or touches.
+func addRange[T any](rs []RangeG[T], start, end T, less LessFunc[T]) []RangeG[T] {
+	// Ranges before i end before start; ranges from j on start after end.
+	i := sort.Search(len(rs), func(k int) bool { return !less(rs[k].End, start) })
+	j := sort.Search(len(rs), func(k int) bool { return less(end, rs[k].Start) })
+	if i < j {
+		if less(rs[i].Start, start) {
+			start = rs[i].Start
+		}
+		if less(end, rs[j-1].End) {
+			end = rs[j-1].End
+		}
+	}
+	r := RangeG[T]{start, end}
+	if i == j {
+		rs = append(rs, r)
+		copy(rs[i+1:], rs[i:])
+		rs[i] = r
+		return rs
+	}
+	rs[i] = r
+	return append(rs[:i+1], rs[j:]...)
+}
+
+// rangesContain reports whether x is within one of rs, a sorted slice of
+// disjoint ranges.
+func rangesContain[T any](rs []RangeG[T], x T, less LessFunc[T]) bool {
+	i := sort.Search(len(rs), func(k int) bool { return less(x, rs[k].End) })
+	return i < len(rs) && !less(x, rs[i].Start)
+}
@@ -0,0 +1,58 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestApply(t *testing.T) {
+	for trial := 0; trial < 50; trial++ {
+		batched := NewOrderedG[int](*btreeDegree)
+		for _, v := range rand.Perm(100) {
+			batched.ReplaceOrInsert(v * 2)
+		}
+		sequential := batched.Clone()
+		var ops []Op[int]
+		for i := 0; i < 60; i++ {
+			op := Op[int]{Kind: OpKind(rand.Intn(3)), Item: rand.Intn(220)}
+			if op.Kind == OpDeleteRange {
+				op.End = op.Item + rand.Intn(30) - 5
+			}
+			ops = append(ops, op)
+		}
+		for _, op := range ops {
+			switch op.Kind {
+			case OpInsert:
+				sequential.ReplaceOrInsert(op.Item)
+			case OpDelete:
+				sequential.Delete(op.Item)
+			case OpDeleteRange:
+				for _, v := range sequential.AppendRange(nil, op.Item, op.End) {
+					sequential.Delete(v)
+				}
+			}
+		}
+		batched.Apply(ops)
+		if got, want := batched.all(), sequential.all(); !reflect.DeepEqual(got, want) {
+			t.Fatalf("ops %v:\ngot  %v\nwant %v", ops, got, want)
+		}
+	}
+}
@@ -0,0 +1,275 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+// Augment describes a summary, of type A, of a run of consecutive items,
+// such as their count, sum, or maximum.  An AugmentedG keeps the summary of
+// every subtree up to date, so that it can summarize any range of items in
+// O(log n) time.
+type Augment[T, A any] struct {
+	// Of returns the summary of a single item.
+	Of func(item T) A
+	// Combine returns the summary of the items summarized by a followed by
+	// those summarized by b.  It must be associative, and must not modify
+	// a or b.
+	Combine func(a, b A) A
+}
+
+// AugmentedG is a BTreeG that maintains a summary (see Augment) of every
+// subtree.  All of BTreeG's methods are available, and writes keep the
+// summaries current at the cost of O(log n) extra calls to Augment.Combine.
+//
+// Summaries are recomputed by writes, never by reads, so an AugmentedG is
+// safe for concurrent reads just like a BTreeG.
+type AugmentedG[T, A any] struct {
+	*BTreeG[T]
+	aug Augment[T, A]
+}
+
+// NewAugmentedG creates a new augmented B-Tree with the given degree,
+// ordered by less and summarized by aug.
+func NewAugmentedG[T, A any](degree int, less LessFunc[T], aug Augment[T, A]) *AugmentedG[T, A] {
+	t := &AugmentedG[T, A]{BTreeG: NewG(degree, less), aug: aug}
+	t.cow.augment = aug.refresh
+	return t
+}
+
+// Clone clones the tree lazily, like BTreeG.Clone.
+func (t *AugmentedG[T, A]) Clone() *AugmentedG[T, A] {
+	return &AugmentedG[T, A]{BTreeG: t.BTreeG.Clone(), aug: t.aug}
+}
+
+// summary accumulates a summary, which is empty until the first add.
+type summary[A any] struct {
+	a  A
+	ok bool
+}
+
+func (s *summary[A]) add(combine func(a, b A) A, a A) {
+	if s.ok {
+		s.a = combine(s.a, a)
+	} else {
+		s.a, s.ok = a, true
+	}
+}
+
+// sumNode holds the summary of a node's subtree, and those of its
+// children's subtrees.  Summaries are kept beside the nodes, rather than in
+// them, so that trees without summaries don't pay for them.  Like shared
+// nodes, sumNodes are never modified, so clones share them freely.
+type sumNode[T, A any] struct {
+	n        *node[T]
+	sum      A
+	children []*sumNode[T, A]
+}
+
+// refresh recomputes the summaries of the nodes in t.cow.touched.  Writes
+// touch every node they modify, and can only reach a node by modifying its
+// parent, so every other node's summary, and its children's, is found by
+// walking the touched nodes of the old summaries.
+func (aug Augment[T, A]) refresh(t *BTreeG[T]) {
+	touched := t.cow.touched
+	t.cow.touched = nil
+	old, _ := t.sums.(*sumNode[T, A])
+	if t.root == nil {
+		t.sums = nil
+		return
+	}
+	if old != nil && old.n == t.root && !touched[t.root] {
+		return
+	}
+	prev := map[*node[T]]*sumNode[T, A]{}
+	var walk func(s *sumNode[T, A])
+	walk = func(s *sumNode[T, A]) {
+		prev[s.n] = s
+		if touched[s.n] {
+			for _, c := range s.children {
+				walk(c)
+			}
+		}
+	}
+	if old != nil {
+		walk(old)
+	}
+	t.sums = aug.build(t.root, prev, touched)
+}
+
+// build returns the summaries of n's subtree, reusing those in prev for
+// untouched nodes.
+func (aug Augment[T, A]) build(n *node[T], prev map[*node[T]]*sumNode[T, A], touched map[*node[T]]bool) *sumNode[T, A] {
+	if s, ok := prev[n]; ok && !touched[n] {
+		return s
+	}
+	out := &sumNode[T, A]{n: n}
+	if len(n.children) > 0 {
+		out.children = make([]*sumNode[T, A], len(n.children))
+		for i, c := range n.children {
+			out.children[i] = aug.build(c, prev, touched)
+		}
+	}
+	var s summary[A]
+	for i, item := range n.items {
+		if len(out.children) > 0 {
+			s.add(aug.Combine, out.children[i].sum)
+		}
+		s.add(aug.Combine, aug.Of(item))
+	}
+	if len(out.children) > 0 {
+		s.add(aug.Combine, out.children[len(n.items)].sum)
+	}
+	out.sum = s.a
+	return out
+}
+
+// touch records that n is about to change, or has been copied or freed, so
+// that its summary must be recomputed.
+func (c *copyOnWriteContext[T]) touch(n *node[T]) {
+	if c.touched == nil {
+		c.touched = map[*node[T]]bool{}
+	}
+	c.touched[n] = true
+}
+
+// refreshAug brings the tree's summaries, if it has any, up to date after a
+// write.
+func (t *BTreeG[T]) refreshAug() {
+	if t.cow.augment != nil {
+		t.cow.augment(t)
+	}
+}
+
+// sumRoot returns the summaries of the root's subtree, or nil if the tree is
+// empty.
+func (t *AugmentedG[T, A]) sumRoot() *sumNode[T, A] {
+	s, _ := t.sums.(*sumNode[T, A])
+	return s
+}
+
+// Summary returns the summary of every item in the tree, or
+// (zeroValue, false) if the tree is empty.
+func (t *AugmentedG[T, A]) Summary() (_ A, _ bool) {
+	if t.root == nil || len(t.root.items) == 0 {
+		return
+	}
+	return t.sumRoot().sum, true
+}
+
+// SummaryRange returns the summary of the items within the range
+// [greaterOrEqual, lessThan), or (zeroValue, false) if there are none.
+func (t *AugmentedG[T, A]) SummaryRange(greaterOrEqual, lessThan T) (A, bool) {
+	return t.summaryRange(optional(greaterOrEqual), optional(lessThan))
+}
+
+// SummaryLessThan returns the summary of the items less than pivot, or
+// (zeroValue, false) if there are none.
+func (t *AugmentedG[T, A]) SummaryLessThan(pivot T) (A, bool) {
+	return t.summaryRange(empty[T](), optional(pivot))
+}
+
+// SummaryGreaterOrEqual returns the summary of the items greater than or
+// equal to pivot, or (zeroValue, false) if there are none.
+func (t *AugmentedG[T, A]) SummaryGreaterOrEqual(pivot T) (A, bool) {
+	return t.summaryRange(optional(pivot), empty[T]())
+}
+
+func (t *AugmentedG[T, A]) summaryRange(lo, hi optionalItem[T]) (A, bool) {
+	var s summary[A]
+	if t.root != nil {
+		t.addRange(&s, t.sumRoot(), lo, hi)
+	}
+	return s.a, s.ok
+}
+
+// addRange adds the summary of the items in sn's subtree within [lo, hi) to
+// s.
+func (t *AugmentedG[T, A]) addRange(s *summary[A], sn *sumNode[T, A], lo, hi optionalItem[T]) {
+	if !lo.valid && !hi.valid {
+		s.add(t.aug.Combine, sn.sum)
+		return
+	}
+	n := sn.n
+	i, j := 0, len(n.items)
+	if lo.valid {
+		i, _ = n.find(lo.item)
+	}
+	if hi.valid {
+		j, _ = n.find(hi.item)
+	}
+	if len(n.children) == 0 {
+		for k := i; k < j; k++ {
+			s.add(t.aug.Combine, t.aug.Of(n.items[k]))
+		}
+		return
+	}
+	if i >= j {
+		// The whole range falls within one child (and may be empty).
+		t.addRange(s, sn.children[i], lo, hi)
+		return
+	}
+	t.addRange(s, sn.children[i], lo, empty[T]())
+	for k := i; k < j; k++ {
+		s.add(t.aug.Combine, t.aug.Of(n.items[k]))
+		if k+1 < j {
+			s.add(t.aug.Combine, sn.children[k+1].sum)
+		}
+	}
+	t.addRange(s, sn.children[j], empty[T](), hi)
+}
+
+// Search returns the first item, in ascending order, for which pred is true
+// of the summary of all the items up to and including it, or
+// (zeroValue, false) if there is none.  pred must be monotonic: once true for
+// some prefix of the items, it must be true for every longer prefix.
+//
+// For example, with a summary that counts items, Search can find the item
+// at a given rank; with one that sums weights, it can find the item at which
+// a running total first exceeds a threshold.  Search takes O(log n) time.
+func (t *AugmentedG[T, A]) Search(pred func(prefix A) bool) (_ T, _ bool) {
+	if t.root == nil {
+		return
+	}
+	var s summary[A]
+	sn := t.sumRoot()
+	for {
+		n := sn.n
+		next := s
+		descended := false
+		for i, item := range n.items {
+			if len(n.children) > 0 {
+				next.add(t.aug.Combine, sn.children[i].sum)
+				if pred(next.a) {
+					sn, descended = sn.children[i], true
+					break
+				}
+				s = next
+			}
+			next.add(t.aug.Combine, t.aug.Of(item))
+			if pred(next.a) {
+				return item, true
+			}
+			s = next
+		}
+		if descended {
+			continue
+		}
+		if len(n.children) == 0 {
+			return
+		}
+		sn = sn.children[len(n.items)]
+	}
+}
@@ -0,0 +1,160 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// sumCount is a test summary: the number and sum of a run of ints.
+type sumCount struct{ n, sum int }
+
+var sumCountAug = Augment[int, sumCount]{
+	Of:      func(item int) sumCount { return sumCount{1, item} },
+	Combine: func(a, b sumCount) sumCount { return sumCount{a.n + b.n, a.sum + b.sum} },
+}
+
+func TestAugmentedSummaryRange(t *testing.T) {
+	for _, degree := range []int{2, 3, 8} {
+		tr := NewAugmentedG(degree, Less[int](), sumCountAug)
+		in := map[int]bool{}
+		r := rand.New(rand.NewSource(int64(degree)))
+		var clone *AugmentedG[int, sumCount]
+		var cloneWant sumCount
+		for i := 0; i < 2000; i++ {
+			v := r.Intn(300)
+			switch r.Intn(4) {
+			case 0, 1:
+				tr.ReplaceOrInsert(v)
+				in[v] = true
+			case 2:
+				tr.Delete(v)
+				delete(in, v)
+			case 3:
+				tr.DeleteMin()
+				tr.AppendMax(300 + i)
+				in = map[int]bool{}
+				tr.Ascend(func(item int) bool {
+					in[item] = true
+					return true
+				})
+			}
+			if i == 1000 {
+				clone = tr.Clone()
+				cloneWant, _ = clone.Summary()
+			}
+			lo, hi := r.Intn(400)-50, r.Intn(400)-50
+			var want sumCount
+			for item := range in {
+				if item >= lo && item < hi {
+					want.n++
+					want.sum += item
+				}
+			}
+			got, ok := tr.SummaryRange(lo, hi)
+			if got != want || ok != (want.n > 0) {
+				t.Fatalf("degree %d, op %d: SummaryRange(%d, %d) = %v, %v; want %v", degree, i, lo, hi, got, ok, want)
+			}
+		}
+		if got, _ := clone.Summary(); got != cloneWant {
+			t.Errorf("clone summary changed from %v to %v", cloneWant, got)
+		}
+	}
+}
+
+func TestAugmentedLessThanGreaterOrEqual(t *testing.T) {
+	tr := NewAugmentedG(3, Less[int](), sumCountAug)
+	for _, v := range rand.Perm(100) {
+		tr.ReplaceOrInsert(v)
+	}
+	for _, pivot := range []int{-1, 0, 37, 99, 100} {
+		lt, _ := tr.SummaryLessThan(pivot)
+		ge, _ := tr.SummaryGreaterOrEqual(pivot)
+		all, _ := tr.Summary()
+		if lt.n+ge.n != all.n || lt.sum+ge.sum != all.sum {
+			t.Errorf("pivot %d: %v + %v != %v", pivot, lt, ge, all)
+		}
+	}
+	if _, ok := tr.SummaryRange(50, 50); ok {
+		t.Errorf("empty range has a summary")
+	}
+}
+
+func TestAugmentedSearch(t *testing.T) {
+	tr := NewAugmentedG(2, Less[int](), sumCountAug)
+	for _, v := range rand.Perm(100) {
+		tr.ReplaceOrInsert(v * 2)
+	}
+	for rank := 0; rank < 100; rank++ {
+		got, ok := tr.Search(func(s sumCount) bool { return s.n > rank })
+		if !ok || got != rank*2 {
+			t.Errorf("item at rank %d: got %d, %v", rank, got, ok)
+		}
+	}
+	if _, ok := tr.Search(func(s sumCount) bool { return s.n > 100 }); ok {
+		t.Errorf("found item past the end")
+	}
+	// The first item at which the running total reaches 100: 0+2+...+18 = 90,
+	// +20 = 110.
+	if got, _ := tr.Search(func(s sumCount) bool { return s.sum >= 100 }); got != 20 {
+		t.Errorf("running total search got %d, want 20", got)
+	}
+}
+
+func TestAugmentedRebuild(t *testing.T) {
+	tr := NewAugmentedG(3, Less[int](), sumCountAug)
+	for i := 0; i < 100; i++ {
+		tr.ReplaceOrInsert(i)
+	}
+	for i := 0; i < 100; i += 2 {
+		tr.Delete(i)
+	}
+	tr.Rebuild()
+	if got, _ := tr.SummaryRange(10, 20); got != (sumCount{5, 11 + 13 + 15 + 17 + 19}) {
+		t.Errorf("after Rebuild got %v", got)
+	}
+}
+
+func TestAugmentedRefreshIsIncremental(t *testing.T) {
+	calls := 0
+	aug := sumCountAug
+	aug.Of = func(item int) sumCount {
+		calls++
+		return sumCount{1, item}
+	}
+	tr := NewAugmentedG(8, Less[int](), aug)
+	for _, v := range rand.Perm(10000) {
+		tr.ReplaceOrInsert(v * 2)
+	}
+	clone := tr.Clone()
+	// Each write should only resummarize the nodes on its path: at most 15
+	// items in each of about 4 levels, for both the original and the clone,
+	// whose first write copies that path.
+	for _, c := range []*AugmentedG[int, sumCount]{tr, clone, tr} {
+		calls = 0
+		c.ReplaceOrInsert(1)
+		c.Delete(1)
+		if calls > 200 {
+			t.Errorf("a write and a delete called Of %d times", calls)
+		}
+		if got, _ := c.Summary(); got.n != 10000 {
+			t.Errorf("Summary().n = %d, want 10000", got.n)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+)
+
+// WorkloadSpec describes a workload for Autotune to measure.
+type WorkloadSpec struct {
+	// Gets, Inserts, Deletes and Scans are the relative frequencies of
+	// lookups, inserts, deletes and range scans.  If all are zero, the
+	// operations are equally frequent.
+	Gets, Inserts, Deletes, Scans float64
+	// ScanLength is the number of items each scan visits.  The default is
+	// 100.
+	ScanLength int
+	// Ops is the number of operations timed for each configuration.  The
+	// default is 10000.
+	Ops int
+	// Degrees are the degrees to try.  The default is 4, 8, 16, 32, 64 and
+	// 128.
+	Degrees []int
+}
+
+// TuningResult is the measured performance of one configuration.
+type TuningResult struct {
+	Degree      int
+	SplitPolicy SplitPolicy
+	// PerOp is the mean time per operation, over the best of three runs.
+	PerOp time.Duration
+	// FillFactor is the tree's fill factor after the run; see Stats.
+	FillFactor float64
+}
+
+// TuningReport is the result of Autotune.
+type TuningReport struct {
+	// Recommended is the fastest configuration.
+	Recommended TuningResult
+	// Results holds every configuration measured, fastest first.
+	Results []TuningResult
+}
+
+// String formats the report as a table, one configuration per line.
+func (r TuningReport) String() string {
+	var b strings.Builder
+	for _, res := range r.Results {
+		policy := "even"
+		if res.SplitPolicy == SplitRightBiased {
+			policy = "right-biased"
+		}
+		fmt.Fprintf(&b, "degree %4d, %-12s split: %10v/op, fill %3.0f%%\n", res.Degree, policy, res.PerOp, 100*res.FillFactor)
+	}
+	return b.String()
+}
+
+// autotuneOp is one operation of the workload Autotune replays.
+type autotuneOp struct {
+	kind int // 0 get, 1 insert, 2 delete, 3 scan
+	item int // index into the sample
+}
+
+// Autotune measures how trees of sample's items perform under workload with
+// each candidate degree and split policy, and recommends the fastest.  It
+// replaces rules of thumb with measurements on the caller's own items and
+// LessFunc, which dominate the cost of comparisons and so the best degree.
+//
+// sample should hold items like those the application stores, in the order
+// it inserts them, since that determines which split policy is best: each
+// run loads the first half of sample into a tree, then replays the same
+// random sequence of operations on it, inserting items from the second
+// half and looking up, deleting and scanning from items of either half.
+// Autotune takes roughly the time of a few runs of workload.Ops operations
+// per configuration, so it's meant for tools and tests, not for production
+// start-up.
+func Autotune[T any](sample []T, less LessFunc[T], workload WorkloadSpec) TuningReport {
+	if len(sample) < 2 {
+		panic("btree: Autotune needs a sample of at least 2 items")
+	}
+	if workload.Gets+workload.Inserts+workload.Deletes+workload.Scans <= 0 {
+		workload.Gets, workload.Inserts, workload.Deletes, workload.Scans = 1, 1, 1, 1
+	}
+	if workload.ScanLength <= 0 {
+		workload.ScanLength = 100
+	}
+	if workload.Ops <= 0 {
+		workload.Ops = 10000
+	}
+	degrees := workload.Degrees
+	if len(degrees) == 0 {
+		degrees = []int{4, 8, 16, 32, 64, 128}
+	}
+
+	r := rand.New(rand.NewSource(1))
+	total := workload.Gets + workload.Inserts + workload.Deletes + workload.Scans
+	ops := make([]autotuneOp, workload.Ops)
+	nextInsert := len(sample) / 2
+	for i := range ops {
+		x := r.Float64() * total
+		switch {
+		case x < workload.Gets:
+			ops[i] = autotuneOp{0, r.Intn(len(sample))}
+		case x < workload.Gets+workload.Inserts:
+			ops[i] = autotuneOp{1, nextInsert}
+			if nextInsert++; nextInsert == len(sample) {
+				nextInsert = len(sample) / 2
+			}
+		case x < workload.Gets+workload.Inserts+workload.Deletes:
+			ops[i] = autotuneOp{2, r.Intn(len(sample))}
+		default:
+			ops[i] = autotuneOp{3, r.Intn(len(sample))}
+		}
+	}
+
+	var report TuningReport
+	for _, degree := range degrees {
+		for _, policy := range []SplitPolicy{SplitEven, SplitRightBiased} {
+			res := TuningResult{Degree: degree, SplitPolicy: policy}
+			for run := 0; run < 3; run++ {
+				perOp, fill := autotuneRun(sample, less, degree, policy, ops, workload.ScanLength)
+				if run == 0 || perOp < res.PerOp {
+					res.PerOp = perOp
+				}
+				res.FillFactor = fill
+			}
+			report.Results = append(report.Results, res)
+		}
+	}
+	sort.SliceStable(report.Results, func(i, j int) bool {
+		return report.Results[i].PerOp < report.Results[j].PerOp
+	})
+	report.Recommended = report.Results[0]
+	return report
+}
+
+// autotuneRun times one run of ops, returning the mean time per operation
+// and the tree's fill factor afterwards.
+func autotuneRun[T any](sample []T, less LessFunc[T], degree int, policy SplitPolicy, ops []autotuneOp, scanLength int) (time.Duration, float64) {
+	t := NewWithOptions(WithLess(less), WithDegree[T](degree), WithSplitPolicy[T](policy))
+	for _, item := range sample[:len(sample)/2] {
+		t.ReplaceOrInsert(item)
+	}
+	start := time.Now()
+	for _, op := range ops {
+		item := sample[op.item]
+		switch op.kind {
+		case 0:
+			t.Get(item)
+		case 1:
+			t.ReplaceOrInsert(item)
+		case 2:
+			t.Delete(item)
+		case 3:
+			n := 0
+			t.AscendGreaterOrEqual(item, func(T) bool {
+				n++
+				return n < scanLength
+			})
+		}
+	}
+	return time.Since(start) / time.Duration(len(ops)), t.FillFactor()
+}
@@ -0,0 +1,52 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestAutotune(t *testing.T) {
+	report := Autotune(rand.Perm(2000), Less[int](), WorkloadSpec{
+		Gets:    8,
+		Inserts: 1,
+		Scans:   1,
+		Ops:     1000,
+		Degrees: []int{2, 16},
+	})
+	if len(report.Results) != 4 {
+		t.Fatalf("got %d results, want 4:\n%v", len(report.Results), report)
+	}
+	for i, res := range report.Results {
+		if res.PerOp <= 0 || res.FillFactor <= 0 || res.FillFactor > 1 {
+			t.Errorf("result %d: %+v", i, res)
+		}
+		if i > 0 && res.PerOp < report.Results[i-1].PerOp {
+			t.Errorf("results out of order:\n%v", report)
+		}
+	}
+	if report.Recommended != report.Results[0] {
+		t.Errorf("recommended %+v, not the fastest", report.Recommended)
+	}
+	if s := report.String(); strings.Count(s, "\n") != 4 || !strings.Contains(s, "right-biased") {
+		t.Errorf("String() = %q", s)
+	}
+	expectPanic(t, "at least 2 items", func() { Autotune([]int{1}, Less[int](), WorkloadSpec{}) })
+}
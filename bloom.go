@@ -0,0 +1,171 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+// WithBloomFilter makes the tree keep a Bloom filter of its items, so that
+// Get and Has can usually answer for an absent key without descending the
+// tree.  This helps workloads where most lookups miss, such as duplicate
+// checks and negative caches.  It costs a few extra hash probes on every
+// insert and delete, and 10 to 20 bytes per item: the filter is sized for
+// twice the tree's length whenever it's rebuilt, and is rebuilt once the
+// tree grows to fill it.
+//
+// hash must be consistent with the tree's ordering: items that are equal
+// according to the LessFunc must hash equally.  For example, for string
+// items, hash could use hash/maphash.
+//
+// The filter counts, rather than just sets, its bits, so that deletes keep it
+// accurate, and it doubles in size, in O(n) time, as the tree grows.  Clones
+// share the filter, copying each 4KB chunk of it on their first write to
+// that chunk.  Trees derived from this one with the same ordering (by
+// WithDegree, CloneFiltered, and so on) build their own filter on their
+// first insert; until then their lookups descend as usual.  Trees reordered
+// by Resort have no filter, since hash need not be consistent with their
+// ordering.
+func WithBloomFilter[T any](hash func(T) uint64) Option[T] {
+	return func(o *options[T]) { o.bloomHash = hash }
+}
+
+const (
+	bloomCountersPerItem = 10
+	bloomProbes          = 7 // optimal for 10 counters per item: ~1% false positives
+	bloomMinCapacity     = 64
+	bloomChunkShift      = 12 // 4096 counters per chunk
+	bloomChunkSize       = 1 << bloomChunkShift
+)
+
+// bloomFilter is a counting Bloom filter.  Its counters are split into
+// chunks, which are shared by clones of a tree until one of them writes to
+// the chunk, like the tree's nodes.
+type bloomFilter struct {
+	// chunks hold the counters, bloomChunkSize of them in each chunk but
+	// the last.  Counters saturate at 255, and then are never decremented.
+	chunks [][]uint8
+	// owned records which chunks this filter may write in place; the rest
+	// are shared with clones.
+	owned    []bool
+	size     uint32 // total number of counters
+	capacity int    // number of items the filter is sized for
+	shared   bool
+}
+
+func newBloomFilter(capacity int) *bloomFilter {
+	if capacity < bloomMinCapacity {
+		capacity = bloomMinCapacity
+	}
+	b := &bloomFilter{size: uint32(capacity * bloomCountersPerItem), capacity: capacity}
+	for left := int(b.size); left > 0; left -= bloomChunkSize {
+		n := left
+		if n > bloomChunkSize {
+			n = bloomChunkSize
+		}
+		b.chunks = append(b.chunks, make([]uint8, n))
+		b.owned = append(b.owned, true)
+	}
+	return b
+}
+
+// probe calls f with the index of each of h's counters, stopping if f
+// returns false.
+func (b *bloomFilter) probe(h uint64, f func(i uint32) bool) bool {
+	h1, h2 := uint32(h), uint32(h>>32)|1
+	for i := uint32(0); i < bloomProbes; i++ {
+		if !f((h1 + i*h2) % b.size) {
+			return false
+		}
+	}
+	return true
+}
+
+// counter returns counter i.
+func (b *bloomFilter) counter(i uint32) uint8 {
+	return b.chunks[i>>bloomChunkShift][i&(bloomChunkSize-1)]
+}
+
+// mutableCounter returns a pointer to counter i, first copying its chunk if
+// it's shared.
+func (b *bloomFilter) mutableCounter(i uint32) *uint8 {
+	c := i >> bloomChunkShift
+	if !b.owned[c] {
+		b.chunks[c] = append([]uint8(nil), b.chunks[c]...)
+		b.owned[c] = true
+	}
+	return &b.chunks[c][i&(bloomChunkSize-1)]
+}
+
+func (b *bloomFilter) add(h uint64) {
+	b.probe(h, func(i uint32) bool {
+		if c := b.mutableCounter(i); *c < 255 {
+			*c++
+		}
+		return true
+	})
+}
+
+func (b *bloomFilter) remove(h uint64) {
+	b.probe(h, func(i uint32) bool {
+		if c := b.counter(i); c > 0 && c < 255 {
+			*b.mutableCounter(i)--
+		}
+		return true
+	})
+}
+
+func (b *bloomFilter) mayContain(h uint64) bool {
+	return b.probe(h, func(i uint32) bool { return b.counter(i) > 0 })
+}
+
+// mutableBloom returns t's filter, copying it first if it's shared.  The
+// copy shares all of the chunks, which mutableCounter copies as needed.
+func (t *BTreeG[T]) mutableBloom() *bloomFilter {
+	if t.bloom.shared {
+		b := *t.bloom
+		b.chunks = append([][]uint8(nil), b.chunks...)
+		b.owned = make([]bool, len(b.chunks))
+		b.shared = false
+		t.bloom = &b
+	}
+	return t.bloom
+}
+
+// bloomInserted updates the filter, if any, after item was added to the tree
+// (rather than replacing an equal one).  If the filter doesn't exist yet or
+// is overloaded, it's rebuilt from every item.
+func (t *BTreeG[T]) bloomInserted(item T) {
+	if t.bloom != nil && t.length <= t.bloom.capacity {
+		t.mutableBloom().add(t.cow.bloomHash(item))
+		return
+	}
+	t.bloom = newBloomFilter(2 * t.length)
+	t.ascend(func(item T) bool {
+		t.bloom.add(t.cow.bloomHash(item))
+		return true
+	})
+}
+
+// bloomDeleted updates the filter, if any, after item was removed.
+func (t *BTreeG[T]) bloomDeleted(item T) {
+	if t.bloom != nil {
+		t.mutableBloom().remove(t.cow.bloomHash(item))
+	}
+}
+
+// bloomExcludes returns true if the filter shows that key isn't in the tree.
+func (t *BTreeG[T]) bloomExcludes(key T) bool {
+	return t.bloom != nil && !t.bloom.mayContain(t.cow.bloomHash(key))
+}
@@ -0,0 +1,91 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func hashInt(v int) uint64 {
+	x := uint64(v) * 0x9E3779B97F4A7C15
+	return x ^ x>>29
+}
+
+func TestBloomFilter(t *testing.T) {
+	tr := NewWithOptions(WithOrdered[int](), WithDegree[int](*btreeDegree),
+		WithBloomFilter(hashInt), WithComparisonCounting[int]())
+	const n = 5000
+	for _, v := range rand.Perm(n) {
+		tr.ReplaceOrInsert(v * 2)
+	}
+	for i := 0; i < n; i += 2 {
+		tr.Delete(i * 2)
+	}
+	clone := tr.Clone()
+	clone.ReplaceOrInsert(1)
+	for v := 0; v < 2*n; v++ {
+		want := v%4 == 2
+		if got := tr.Has(v); got != want {
+			t.Fatalf("Has(%d) = %v, want %v", v, got, want)
+		}
+		if got := clone.Has(v); got != (want || v == 1) {
+			t.Fatalf("clone.Has(%d) = %v", v, got)
+		}
+	}
+
+	// Most misses should be answered without comparisons.
+	before := tr.Stats().Comparisons.Lookups
+	for v := 0; v < 1000; v++ {
+		tr.Has(-1 - v)
+	}
+	after := tr.Stats().Comparisons.Lookups
+	if cmps := after.Comparisons - before.Comparisons; cmps > 1000 {
+		t.Errorf("1000 misses made %d comparisons", cmps)
+	}
+
+	tr.Clear(true)
+	if tr.Has(2) {
+		t.Error("Has(2) after Clear")
+	}
+	tr.ReplaceOrInsert(2)
+	if !tr.Has(2) {
+		t.Error("Has(2) after reinsert failed")
+	}
+}
+
+func TestBloomFilterCloneCopiesChunks(t *testing.T) {
+	tr := NewWithOptions(WithOrdered[int](), WithBloomFilter(hashInt))
+	for v := 0; v < 10000; v++ {
+		tr.ReplaceOrInsert(v)
+	}
+	clone := tr.Clone()
+	clone.ReplaceOrInsert(-1)
+	copied := 0
+	for i, c := range clone.bloom.chunks {
+		if &c[0] != &tr.bloom.chunks[i][0] {
+			copied++
+		}
+	}
+	if copied == 0 || copied > bloomProbes {
+		t.Errorf("one insert after Clone copied %d of %d chunks", copied, len(clone.bloom.chunks))
+	}
+	if tr.Has(-1) || !clone.Has(-1) {
+		t.Error("insert into clone changed the original's filter")
+	}
+}
@@ -0,0 +1,447 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+// BPlusTreeG is a B+ tree mapping keys of type K to values of type V.
+//
+// Unlike a BTreeG, whose items are spread through every level, a B+ tree
+// keeps only keys in its internal nodes, to guide searches, and keeps every
+// key and value in its leaves, which are linked in order.  Scans walk along
+// the leaves without climbing back up the tree, and internal nodes, holding
+// no values, are smaller and stay in cache, which suits workloads dominated
+// by range scans.  Point operations cost about the same as in a BTreeG.
+//
+// A BPlusTreeG has no copy-on-write support, so it can't be cloned, and is
+// not safe for concurrent use if any goroutine writes to it.
+type BPlusTreeG[K, V any] struct {
+	degree int
+	less   LessFunc[K]
+	root   *bplusNode[K, V]
+	// first and last are the leftmost and rightmost leaves, or nil if the
+	// tree is empty.
+	first, last *bplusNode[K, V]
+	length      int
+}
+
+// bplusNode is a node of a BPlusTreeG.  A leaf holds keys and their values;
+// an internal node holds keys and one more child than keys, where every key
+// in children[i] is less than keys[i], and every key in children[i+1] is
+// greater than or equal to it.
+type bplusNode[K, V any] struct {
+	keys     []K
+	values   []V                // leaves only
+	children []*bplusNode[K, V] // internal nodes only
+	// prev and next link the leaves in order.
+	prev, next *bplusNode[K, V]
+}
+
+func (n *bplusNode[K, V]) leaf() bool {
+	return n.children == nil
+}
+
+// NewBPlusTreeG creates a new B+ tree with the given degree, ordering keys
+// with less.  Every node but the root holds between degree-1 and 2*degree-1
+// keys.
+//
+// NewBPlusTreeG panics if degree is less than 2.
+func NewBPlusTreeG[K, V any](degree int, less LessFunc[K]) *BPlusTreeG[K, V] {
+	if degree <= 1 {
+		panic("bad degree")
+	}
+	return &BPlusTreeG[K, V]{degree: degree, less: less}
+}
+
+// NewOrderedBPlusTreeG creates a new B+ tree ordering its keys with the '<'
+// operator.
+func NewOrderedBPlusTreeG[K Ordered, V any](degree int) *BPlusTreeG[K, V] {
+	return NewBPlusTreeG[K, V](degree, Less[K]())
+}
+
+// Len returns the number of keys in the tree.
+func (t *BPlusTreeG[K, V]) Len() int {
+	return t.length
+}
+
+func (t *BPlusTreeG[K, V]) maxKeys() int {
+	return t.degree*2 - 1
+}
+
+func (t *BPlusTreeG[K, V]) minKeys() int {
+	return t.degree - 1
+}
+
+// search returns the index of the first key in n that is not less than key,
+// and whether that key equals it.
+func (t *BPlusTreeG[K, V]) search(n *bplusNode[K, V], key K) (int, bool) {
+	lo, hi := 0, len(n.keys)
+	for lo < hi {
+		mid := int(uint(lo+hi) >> 1)
+		if t.less(n.keys[mid], key) {
+			lo = mid + 1
+		} else {
+			hi = mid
+		}
+	}
+	return lo, lo < len(n.keys) && !t.less(key, n.keys[lo])
+}
+
+// child returns the index of the child of internal node n whose keys cover
+// key.
+func (t *BPlusTreeG[K, V]) child(n *bplusNode[K, V], key K) int {
+	i, found := t.search(n, key)
+	if found {
+		i++
+	}
+	return i
+}
+
+// leafFor returns the leaf that holds, or would hold, key.
+func (t *BPlusTreeG[K, V]) leafFor(key K) *bplusNode[K, V] {
+	n := t.root
+	for !n.leaf() {
+		n = n.children[t.child(n, key)]
+	}
+	return n
+}
+
+// Get returns the value for key, or (zeroValue, false) if key isn't in the
+// tree.
+func (t *BPlusTreeG[K, V]) Get(key K) (_ V, _ bool) {
+	if t.root == nil {
+		return
+	}
+	n := t.leafFor(key)
+	if i, found := t.search(n, key); found {
+		return n.values[i], true
+	}
+	return
+}
+
+// Has returns true if key is in the tree.
+func (t *BPlusTreeG[K, V]) Has(key K) bool {
+	_, ok := t.Get(key)
+	return ok
+}
+
+// Min returns the smallest key in the tree and its value, or (zeroValue,
+// zeroValue, false) if the tree is empty.
+func (t *BPlusTreeG[K, V]) Min() (_ K, _ V, _ bool) {
+	if t.first == nil {
+		return
+	}
+	return t.first.keys[0], t.first.values[0], true
+}
+
+// Max returns the largest key in the tree and its value, or (zeroValue,
+// zeroValue, false) if the tree is empty.
+func (t *BPlusTreeG[K, V]) Max() (_ K, _ V, _ bool) {
+	if t.last == nil {
+		return
+	}
+	i := len(t.last.keys) - 1
+	return t.last.keys[i], t.last.values[i], true
+}
+
+// ReplaceOrInsert sets the value for key.  If key was already in the tree,
+// its old value is returned and the second return value is true; otherwise
+// it returns (zeroValue, false).
+func (t *BPlusTreeG[K, V]) ReplaceOrInsert(key K, value V) (_ V, _ bool) {
+	if t.root == nil {
+		t.root = &bplusNode[K, V]{keys: []K{key}, values: []V{value}}
+		t.first, t.last = t.root, t.root
+		t.length = 1
+		return
+	}
+	old, replaced, right, sep := t.insert(t.root, key, value)
+	if right != nil {
+		t.root = &bplusNode[K, V]{keys: []K{sep}, children: []*bplusNode[K, V]{t.root, right}}
+	}
+	if !replaced {
+		t.length++
+	}
+	return old, replaced
+}
+
+// insert sets key to value in the subtree rooted at n.  If n overflows, it's
+// split, and the new right half is returned along with the key separating
+// it from n.
+func (t *BPlusTreeG[K, V]) insert(n *bplusNode[K, V], key K, value V) (old V, replaced bool, right *bplusNode[K, V], sep K) {
+	if n.leaf() {
+		i, found := t.search(n, key)
+		if found {
+			old, n.values[i] = n.values[i], value
+			return old, true, nil, sep
+		}
+		n.keys = insertAt(n.keys, i, key)
+		n.values = insertAt(n.values, i, value)
+		if len(n.keys) > t.maxKeys() {
+			right, sep = t.splitLeaf(n)
+		}
+		return old, false, right, sep
+	}
+	i := t.child(n, key)
+	old, replaced, childRight, childSep := t.insert(n.children[i], key, value)
+	if childRight != nil {
+		n.keys = insertAt(n.keys, i, childSep)
+		n.children = insertAt(n.children, i+1, childRight)
+		if len(n.keys) > t.maxKeys() {
+			right, sep = t.splitInternal(n)
+		}
+	}
+	return old, replaced, right, sep
+}
+
+// splitLeaf moves the upper half of leaf n to a new leaf, which it returns
+// with its first key.
+func (t *BPlusTreeG[K, V]) splitLeaf(n *bplusNode[K, V]) (*bplusNode[K, V], K) {
+	mid := len(n.keys) / 2
+	right := &bplusNode[K, V]{
+		keys:   append([]K(nil), n.keys[mid:]...),
+		values: append([]V(nil), n.values[mid:]...),
+		prev:   n,
+		next:   n.next,
+	}
+	n.keys = truncate(n.keys, mid)
+	n.values = truncate(n.values, mid)
+	if n.next != nil {
+		n.next.prev = right
+	} else {
+		t.last = right
+	}
+	n.next = right
+	return right, right.keys[0]
+}
+
+// splitInternal moves the keys and children of internal node n above its
+// middle key to a new node, returning the new node and the middle key.
+func (t *BPlusTreeG[K, V]) splitInternal(n *bplusNode[K, V]) (*bplusNode[K, V], K) {
+	mid := len(n.keys) / 2
+	sep := n.keys[mid]
+	right := &bplusNode[K, V]{
+		keys:     append([]K(nil), n.keys[mid+1:]...),
+		children: append([]*bplusNode[K, V](nil), n.children[mid+1:]...),
+	}
+	n.keys = truncate(n.keys, mid)
+	n.children = truncate(n.children, mid+1)
+	return right, sep
+}
+
+// Delete removes key from the tree, returning its value, or (zeroValue,
+// false) if key wasn't in the tree.
+func (t *BPlusTreeG[K, V]) Delete(key K) (_ V, _ bool) {
+	if t.root == nil {
+		return
+	}
+	old, deleted := t.remove(t.root, key)
+	if !deleted {
+		return
+	}
+	t.length--
+	switch {
+	case t.root.leaf() && len(t.root.keys) == 0:
+		t.root, t.first, t.last = nil, nil, nil
+	case !t.root.leaf() && len(t.root.keys) == 0:
+		t.root = t.root.children[0]
+	}
+	return old, true
+}
+
+// remove deletes key from the subtree rooted at n, leaving it to n's parent
+// to fix n if it's left with too few keys.
+func (t *BPlusTreeG[K, V]) remove(n *bplusNode[K, V], key K) (old V, deleted bool) {
+	if n.leaf() {
+		i, found := t.search(n, key)
+		if !found {
+			return old, false
+		}
+		old = n.values[i]
+		n.keys = removeAt(n.keys, i)
+		n.values = removeAt(n.values, i)
+		return old, true
+	}
+	i := t.child(n, key)
+	if old, deleted = t.remove(n.children[i], key); deleted && len(n.children[i].keys) < t.minKeys() {
+		t.rebalance(n, i)
+	}
+	return old, deleted
+}
+
+// rebalance refills n.children[i], which has too few keys, by borrowing a
+// key from a sibling or, if neither can spare one, merging it with one.
+func (t *BPlusTreeG[K, V]) rebalance(n *bplusNode[K, V], i int) {
+	c := n.children[i]
+	if i > 0 {
+		if left := n.children[i-1]; len(left.keys) > t.minKeys() {
+			t.borrowLeft(n, i, left, c)
+			return
+		}
+	}
+	if i+1 < len(n.children) {
+		if right := n.children[i+1]; len(right.keys) > t.minKeys() {
+			t.borrowRight(n, i, c, right)
+			return
+		}
+	}
+	if i > 0 {
+		i--
+	}
+	t.merge(n, i)
+}
+
+// borrowLeft moves the last key of left, n.children[i-1], to c,
+// n.children[i].
+func (t *BPlusTreeG[K, V]) borrowLeft(n *bplusNode[K, V], i int, left, c *bplusNode[K, V]) {
+	last := len(left.keys) - 1
+	if c.leaf() {
+		c.keys = insertAt(c.keys, 0, left.keys[last])
+		c.values = insertAt(c.values, 0, left.values[last])
+		left.keys = truncate(left.keys, last)
+		left.values = truncate(left.values, last)
+		n.keys[i-1] = c.keys[0]
+		return
+	}
+	c.keys = insertAt(c.keys, 0, n.keys[i-1])
+	c.children = insertAt(c.children, 0, left.children[last+1])
+	n.keys[i-1] = left.keys[last]
+	left.keys = truncate(left.keys, last)
+	left.children = truncate(left.children, last+1)
+}
+
+// borrowRight moves the first key of right, n.children[i+1], to c,
+// n.children[i].
+func (t *BPlusTreeG[K, V]) borrowRight(n *bplusNode[K, V], i int, c, right *bplusNode[K, V]) {
+	if c.leaf() {
+		c.keys = append(c.keys, right.keys[0])
+		c.values = append(c.values, right.values[0])
+		right.keys = removeAt(right.keys, 0)
+		right.values = removeAt(right.values, 0)
+		n.keys[i] = right.keys[0]
+		return
+	}
+	c.keys = append(c.keys, n.keys[i])
+	c.children = append(c.children, right.children[0])
+	n.keys[i] = right.keys[0]
+	right.keys = removeAt(right.keys, 0)
+	right.children = removeAt(right.children, 0)
+}
+
+// merge moves everything in n.children[i+1] into n.children[i], and removes
+// n.children[i+1] and the key separating them from n.
+func (t *BPlusTreeG[K, V]) merge(n *bplusNode[K, V], i int) {
+	left, right := n.children[i], n.children[i+1]
+	if left.leaf() {
+		left.keys = append(left.keys, right.keys...)
+		left.values = append(left.values, right.values...)
+		left.next = right.next
+		if right.next != nil {
+			right.next.prev = left
+		} else {
+			t.last = left
+		}
+	} else {
+		left.keys = append(append(left.keys, n.keys[i]), right.keys...)
+		left.children = append(left.children, right.children...)
+	}
+	n.keys = removeAt(n.keys, i)
+	n.children = removeAt(n.children, i+1)
+}
+
+// Clear removes every key from the tree.
+func (t *BPlusTreeG[K, V]) Clear() {
+	t.root, t.first, t.last, t.length = nil, nil, nil, 0
+}
+
+// Ascend calls iterator for every key in the tree and its value, in order,
+// until iterator returns false.
+func (t *BPlusTreeG[K, V]) Ascend(iterator func(key K, value V) bool) {
+	t.ascendFrom(t.first, 0, empty[K](), iterator)
+}
+
+// AscendGreaterOrEqual calls iterator for every key in the tree greater than
+// or equal to pivot and its value, in order, until iterator returns false.
+func (t *BPlusTreeG[K, V]) AscendGreaterOrEqual(pivot K, iterator func(key K, value V) bool) {
+	if t.root == nil {
+		return
+	}
+	n := t.leafFor(pivot)
+	i, _ := t.search(n, pivot)
+	t.ascendFrom(n, i, empty[K](), iterator)
+}
+
+// AscendRange calls iterator for every key in the tree within the range
+// [greaterOrEqual, lessThan) and its value, in order, until iterator returns
+// false.
+func (t *BPlusTreeG[K, V]) AscendRange(greaterOrEqual, lessThan K, iterator func(key K, value V) bool) {
+	if t.root == nil {
+		return
+	}
+	n := t.leafFor(greaterOrEqual)
+	i, _ := t.search(n, greaterOrEqual)
+	t.ascendFrom(n, i, optional(lessThan), iterator)
+}
+
+// ascendFrom walks the leaves from the i'th key of n, stopping before stop.
+func (t *BPlusTreeG[K, V]) ascendFrom(n *bplusNode[K, V], i int, stop optionalItem[K], iterator func(key K, value V) bool) {
+	for ; n != nil; n, i = n.next, 0 {
+		for ; i < len(n.keys); i++ {
+			if stop.valid && !t.less(n.keys[i], stop.item) {
+				return
+			}
+			if !iterator(n.keys[i], n.values[i]) {
+				return
+			}
+		}
+	}
+}
+
+// Descend calls iterator for every key in the tree and its value, in
+// reverse order, until iterator returns false.
+func (t *BPlusTreeG[K, V]) Descend(iterator func(key K, value V) bool) {
+	for n := t.last; n != nil; n = n.prev {
+		for i := len(n.keys) - 1; i >= 0; i-- {
+			if !iterator(n.keys[i], n.values[i]) {
+				return
+			}
+		}
+	}
+}
+
+// insertAt returns s with v inserted at index i.
+func insertAt[E any](s []E, i int, v E) []E {
+	var zero E
+	s = append(s, zero)
+	copy(s[i+1:], s[i:])
+	s[i] = v
+	return s
+}
+
+// removeAt returns s without its i'th element, clearing the vacated slot so
+// it doesn't hold on to garbage.
+func removeAt[E any](s []E, i int) []E {
+	copy(s[i:], s[i+1:])
+	return truncate(s, len(s)-1)
+}
+
+// truncate returns s[:n], clearing the elements after n.
+func truncate[E any](s []E, n int) []E {
+	var zero E
+	for i := n; i < len(s); i++ {
+		s[i] = zero
+	}
+	return s[:n]
+}
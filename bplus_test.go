@@ -0,0 +1,195 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// check returns an error if t's structure is invalid.
+func (t *BPlusTreeG[K, V]) check() error {
+	if t.root == nil {
+		if t.length != 0 || t.first != nil || t.last != nil {
+			return fmt.Errorf("empty tree has length %d", t.length)
+		}
+		return nil
+	}
+	var leaves []*bplusNode[K, V]
+	count := 0
+	var walk func(n *bplusNode[K, V], depth int, lo, hi optionalItem[K]) (int, error)
+	walk = func(n *bplusNode[K, V], depth int, lo, hi optionalItem[K]) (int, error) {
+		if len(n.keys) > t.maxKeys() || (n != t.root && len(n.keys) < t.minKeys()) {
+			return 0, fmt.Errorf("node at depth %d has %d keys", depth, len(n.keys))
+		}
+		for i, k := range n.keys {
+			if (i > 0 && !t.less(n.keys[i-1], k)) || (lo.valid && t.less(k, lo.item)) || (hi.valid && !t.less(k, hi.item)) {
+				return 0, fmt.Errorf("key %v out of order at depth %d", k, depth)
+			}
+		}
+		if n.leaf() {
+			if len(n.values) != len(n.keys) {
+				return 0, fmt.Errorf("leaf has %d keys and %d values", len(n.keys), len(n.values))
+			}
+			leaves = append(leaves, n)
+			count += len(n.keys)
+			return depth, nil
+		}
+		if len(n.children) != len(n.keys)+1 {
+			return 0, fmt.Errorf("node has %d keys and %d children", len(n.keys), len(n.children))
+		}
+		leafDepth := -1
+		for i, c := range n.children {
+			clo, chi := lo, hi
+			if i > 0 {
+				clo = optional(n.keys[i-1])
+			}
+			if i < len(n.keys) {
+				chi = optional(n.keys[i])
+			}
+			d, err := walk(c, depth+1, clo, chi)
+			if err != nil {
+				return 0, err
+			}
+			if leafDepth >= 0 && d != leafDepth {
+				return 0, fmt.Errorf("leaves at depths %d and %d", leafDepth, d)
+			}
+			leafDepth = d
+		}
+		return leafDepth, nil
+	}
+	if _, err := walk(t.root, 0, empty[K](), empty[K]()); err != nil {
+		return err
+	}
+	if count != t.length {
+		return fmt.Errorf("Len is %d but the tree holds %d keys", t.length, count)
+	}
+	if t.first != leaves[0] || t.last != leaves[len(leaves)-1] {
+		return fmt.Errorf("first or last leaf is wrong")
+	}
+	for i, n := range leaves {
+		if (i > 0 && n.prev != leaves[i-1]) || (i+1 < len(leaves) && n.next != leaves[i+1]) {
+			return fmt.Errorf("leaf %d is linked wrongly", i)
+		}
+	}
+	return nil
+}
+
+func TestBPlusTree(t *testing.T) {
+	tr := NewOrderedBPlusTreeG[int, string](*btreeDegree)
+	m := make(map[int]string)
+	for i := 0; i < 5000; i++ {
+		k := rand.Intn(1000)
+		switch rand.Intn(3) {
+		case 0, 1:
+			v := fmt.Sprint(i)
+			old, replaced := tr.ReplaceOrInsert(k, v)
+			if want, ok := m[k]; old != want || replaced != ok {
+				t.Fatalf("ReplaceOrInsert(%d) = %q, %v, want %q, %v", k, old, replaced, want, ok)
+			}
+			m[k] = v
+		case 2:
+			old, deleted := tr.Delete(k)
+			if want, ok := m[k]; old != want || deleted != ok {
+				t.Fatalf("Delete(%d) = %q, %v, want %q, %v", k, old, deleted, want, ok)
+			}
+			delete(m, k)
+		}
+		if i%100 == 0 {
+			if err := tr.check(); err != nil {
+				t.Fatalf("after %d ops: %v", i, err)
+			}
+		}
+	}
+	if err := tr.check(); err != nil {
+		t.Fatal(err)
+	}
+	for k := 0; k < 1000; k++ {
+		if got, ok := tr.Get(k); got != m[k] || ok != (m[k] != "") {
+			t.Fatalf("Get(%d) = %q, %v, want %q", k, got, ok, m[k])
+		}
+	}
+	var keys []int
+	tr.Ascend(func(k int, v string) bool {
+		if v != m[k] {
+			t.Fatalf("Ascend: %d has value %q, want %q", k, v, m[k])
+		}
+		keys = append(keys, k)
+		return true
+	})
+	if len(keys) != len(m) || tr.Len() != len(m) {
+		t.Fatalf("Ascend visited %d keys, Len is %d, want %d", len(keys), tr.Len(), len(m))
+	}
+	var rev []int
+	tr.Descend(func(k int, _ string) bool {
+		rev = append(rev, k)
+		return true
+	})
+	for i := range rev {
+		if rev[i] != keys[len(keys)-1-i] {
+			t.Fatalf("Descend disagrees with Ascend at %d", i)
+		}
+	}
+	if k, _, _ := tr.Min(); k != keys[0] {
+		t.Errorf("Min = %d, want %d", k, keys[0])
+	}
+	if k, _, _ := tr.Max(); k != keys[len(keys)-1] {
+		t.Errorf("Max = %d, want %d", k, keys[len(keys)-1])
+	}
+	for k := range m {
+		tr.Delete(k)
+	}
+	if err := tr.check(); err != nil || tr.Len() != 0 {
+		t.Errorf("after deleting everything: Len %d, %v", tr.Len(), err)
+	}
+}
+
+func TestBPlusTreeRanges(t *testing.T) {
+	tr := NewOrderedBPlusTreeG[int, int](2)
+	for i := 0; i < 100; i += 2 {
+		tr.ReplaceOrInsert(i, i*10)
+	}
+	var got []int
+	collect := func(k, v int) bool {
+		if v != k*10 {
+			t.Fatalf("key %d has value %d", k, v)
+		}
+		got = append(got, k)
+		return len(got) < 5
+	}
+	tr.AscendRange(11, 17, collect)
+	if want := []int{12, 14, 16}; !reflect.DeepEqual(got, want) {
+		t.Errorf("AscendRange(11, 17) = %v, want %v", got, want)
+	}
+	got = nil
+	tr.AscendGreaterOrEqual(90, collect)
+	if want := []int{90, 92, 94, 96, 98}; !reflect.DeepEqual(got, want) {
+		t.Errorf("AscendGreaterOrEqual(90) = %v, want %v", got, want)
+	}
+	got = nil
+	tr.AscendRange(200, 300, collect)
+	if len(got) != 0 {
+		t.Errorf("AscendRange past the end = %v", got)
+	}
+	tr.Clear()
+	if _, _, ok := tr.Min(); ok || tr.Len() != 0 {
+		t.Error("Clear left keys behind")
+	}
+}
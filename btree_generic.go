@@ -61,11 +61,9 @@
 package btree
 
 import (
-	"fmt"
-	"io"
 	"sort"
-	"strings"
 	"sync"
+	"time"
 )
 
 // Item represents a single object in the tree.
@@ -80,6 +78,9 @@ type Item interface {
 
 const (
 	DefaultFreeListSize = 32
+	// DefaultDegree is the degree used by NewWithOptions when WithDegree is
+	// not given.
+	DefaultDegree = 32
 )
 
 // FreeListG represents a free list of btree nodes. By default each
@@ -141,6 +142,21 @@ func NewOrderedG[T Ordered](degree int) *BTreeG[T] {
 	return NewG[T](degree, Less[T]())
 }
 
+// NewInterpolatedG creates a new B-Tree for numeric types that searches
+// within nodes by interpolation rather than binary search: it estimates an
+// item's position from the smallest and largest items in the node.  When
+// items are roughly uniformly distributed, such as sequential IDs or
+// timestamps, this needs fewer comparisons per lookup than binary search,
+// which matters most for trees with a large degree.  On skewed data it
+// degrades gracefully to O(log degree) comparisons per node.
+//
+// Items are ordered with '<', so NaN must not be stored in trees of floats.
+func NewInterpolatedG[T Numeric](degree int) *BTreeG[T] {
+	t := NewOrderedG[T](degree)
+	t.cow.search = interpolationFind[T]
+	return t
+}
+
 // NewG creates a new B-Tree with the given degree.
 //
 // NewG(2), for example, will create a 2-3-4 tree (each node contains 1-3 items
@@ -158,7 +174,7 @@ func NewWithFreeListG[T any](degree int, less LessFunc[T], f *FreeListG[T]) *BTr
 	}
 	return &BTreeG[T]{
 		degree: degree,
-		cow:    &copyOnWriteContext[T]{freelist: f, less: less},
+		cow:    &copyOnWriteContext[T]{freelist: f, less: less, pointerFree: pointerFree[T]()},
 	}
 }
 
@@ -208,19 +224,120 @@ func (s *items[T]) truncate(index int) {
 	}
 }
 
+// linearSearchMax is the largest node size for which find scans items
+// linearly instead of binary searching.  For small nodes a linear scan is
+// faster: it avoids sort.Search's closure calls and its branches are easy to
+// predict.  The value was picked with BenchmarkFindG, where the two
+// approaches break even at around 7 items for integer keys; it covers every
+// node of trees with degree 4 or less.
+const linearSearchMax = 7
+
 // find returns the index where the given item should be inserted into this
 // list.  'found' is true if the item already exists in the list at the given
 // index.
 func (s items[T]) find(item T, less func(T, T) bool) (index int, found bool) {
+	if len(s) <= linearSearchMax {
+		return s.findLinear(item, less)
+	}
+	return s.findBinary(item, less)
+}
+
+// findLinear is find by a linear scan.
+func (s items[T]) findLinear(item T, less func(T, T) bool) (index int, found bool) {
+	var i int
+	for _, v := range s {
+		if less(item, v) {
+			break
+		}
+		i++
+	}
+	return s.found(i, item, less)
+}
+
+// findBinary is find by binary search.
+func (s items[T]) findBinary(item T, less func(T, T) bool) (index int, found bool) {
 	i := sort.Search(len(s), func(i int) bool {
 		return less(item, s[i])
 	})
+	return s.found(i, item, less)
+}
+
+// found returns the result of find, given the index i of the first item
+// greater than item.
+func (s items[T]) found(i int, item T, less func(T, T) bool) (index int, found bool) {
 	if i > 0 && !less(s[i-1], item) {
 		return i - 1, true
 	}
 	return i, false
 }
 
+// findFrom is like find, but starts looking at index hint and widens its
+// search exponentially (galloping) before binary searching.  When the result
+// is close to hint this takes O(log distance) comparisons rather than
+// O(log len(s)).
+func (s items[T]) findFrom(hint int, item T, less func(T, T) bool) (index int, found bool) {
+	if len(s) == 0 {
+		return 0, false
+	}
+	if hint < 0 {
+		hint = 0
+	} else if hint >= len(s) {
+		hint = len(s) - 1
+	}
+	// Find lo < hi such that the result lies in (lo, hi].
+	var lo, hi int
+	if less(item, s[hint]) {
+		hi, lo = hint, hint-1
+		for step := 1; lo >= 0 && less(item, s[lo]); step *= 2 {
+			hi, lo = lo, lo-step
+		}
+	} else {
+		lo, hi = hint, hint+1
+		for step := 1; hi < len(s) && !less(item, s[hi]); step *= 2 {
+			lo, hi = hi, hi+step
+		}
+		if hi > len(s) {
+			hi = len(s)
+		}
+	}
+	if lo < -1 {
+		lo = -1
+	}
+	i := lo + 1 + sort.Search(hi-lo-1, func(i int) bool {
+		return less(item, s[lo+1+i])
+	})
+	if i > 0 && !less(s[i-1], item) {
+		return i - 1, true
+	}
+	return i, false
+}
+
+// Numeric is the set of integer and floating-point types, whose values can
+// be interpolated between.
+type Numeric interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 | ~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64 | ~float32 | ~float64
+}
+
+// interpolationFind is a find function for numeric items.  It guesses where
+// item falls by interpolating between the first and last items, then
+// gallops from that guess, so it takes O(1) comparisons for uniformly
+// distributed items and O(log len(s)) in the worst case.  It compares items
+// with less, which must order them as '<' does, so that comparisons are
+// checked and counted like any others.
+func interpolationFind[T Numeric](s items[T], item T, less LessFunc[T]) (index int, found bool) {
+	if len(s) < 3 {
+		return s.find(item, less)
+	}
+	first, last := s[0], s[len(s)-1]
+	guess := 0
+	if first < item && item < last {
+		guess = int(float64(len(s)-1) * ((float64(item) - float64(first)) / (float64(last) - float64(first))))
+	} else if item >= last {
+		guess = len(s) - 1
+	}
+	return s.findFrom(guess, item, less)
+}
+
 // node is an internal node in a tree.
 //
 // It must at all times maintain the invariant that either
@@ -232,10 +349,26 @@ type node[T any] struct {
 	cow      *copyOnWriteContext[T]
 }
 
+// find returns the index where the given item should be inserted into this
+// node's items, using the tree's search function.  'found' is true if the
+// item already exists at that index.
+func (n *node[T]) find(item T) (index int, found bool) {
+	if n.cow.search != nil {
+		return n.cow.search(n.items, item, n.cow.less)
+	}
+	return n.items.find(item, n.cow.less)
+}
+
 func (n *node[T]) mutableFor(cow *copyOnWriteContext[T]) *node[T] {
 	if n.cow == cow {
+		if cow.augment != nil {
+			cow.touch(n) // the caller is about to modify n
+		}
 		return n
 	}
+	if cow.augment != nil {
+		cow.touch(n) // n's summary can't be reused for its copy
+	}
 	out := cow.newNode()
 	if cap(out.items) >= len(n.items) {
 		out.items = out.items[:len(n.items)]
@@ -266,22 +399,26 @@ func (n *node[T]) split(i int) (T, *node[T]) {
 	item := n.items[i]
 	next := n.cow.newNode()
 	next.items = append(next.items, n.items[i+1:]...)
-	n.items.truncate(i)
+	n.truncateItems(i)
 	if len(n.children) > 0 {
 		next.children = append(next.children, n.children[i+1:]...)
 		n.children.truncate(i + 1)
 	}
+	if h := n.cow.hooks; h != nil && h.OnSplit != nil {
+		h.OnSplit(Node[T]{n}, Node[T]{next}, item)
+	}
 	return item, next
 }
 
 // maybeSplitChild checks if a child should be split, and if so splits it.
-// Returns whether or not a split occurred.
-func (n *node[T]) maybeSplitChild(i, maxItems int) bool {
+// Returns whether or not a split occurred.  If edge is true, the child is on
+// the right edge of the tree, and inserting is the item about to be inserted.
+func (n *node[T]) maybeSplitChild(i, maxItems int, edge bool, inserting T) bool {
 	if len(n.children[i].items) < maxItems {
 		return false
 	}
 	first := n.mutableChild(i)
-	item, second := first.split(maxItems / 2)
+	item, second := first.split(first.splitIndex(maxItems, edge, inserting))
 	n.items.insertAt(i, item)
 	n.children.insertAt(i+1, second)
 	return true
@@ -290,36 +427,46 @@ func (n *node[T]) maybeSplitChild(i, maxItems int) bool {
 // insert inserts an item into the subtree rooted at this node, making sure
 // no nodes in the subtree exceed maxItems items.  Should an equivalent item be
 // be found/replaced by insert, it will be returned.
+//
+// insert makes a single top-down pass, splitting full children before
+// descending into them, so it runs as a loop rather than recursing.
 func (n *node[T]) insert(item T, maxItems int) (_ T, _ bool) {
-	i, found := n.items.find(item, n.cow.less)
-	if found {
-		out := n.items[i]
-		n.items[i] = item
-		return out, true
-	}
-	if len(n.children) == 0 {
-		n.items.insertAt(i, item)
-		return
-	}
-	if n.maybeSplitChild(i, maxItems) {
-		inTree := n.items[i]
-		switch {
-		case n.cow.less(item, inTree):
-			// no change, we want first split node
-		case n.cow.less(inTree, item):
-			i++ // we want second split node
-		default:
+	edge := true // whether n is on the right edge of the tree
+	for {
+		i, found := n.find(item)
+		if found {
 			out := n.items[i]
 			n.items[i] = item
 			return out, true
 		}
+		if len(n.children) == 0 {
+			n.items.insertAt(i, item)
+			if n.cow.checkOrder {
+				n.items.checkNeighbors(i, n.cow.less)
+			}
+			return
+		}
+		if n.maybeSplitChild(i, maxItems, edge && i == len(n.children)-1, item) {
+			inTree := n.items[i]
+			switch {
+			case n.cow.less(item, inTree):
+				// no change, we want first split node
+			case n.cow.less(inTree, item):
+				i++ // we want second split node
+			default:
+				out := n.items[i]
+				n.items[i] = item
+				return out, true
+			}
+		}
+		edge = edge && i == len(n.children)-1
+		n = n.mutableChild(i)
 	}
-	return n.mutableChild(i).insert(item, maxItems)
 }
 
 // get finds the given key in the subtree and returns it.
 func (n *node[T]) get(key T) (_ T, _ bool) {
-	i, found := n.items.find(key, n.cow.less)
+	i, found := n.find(key)
 	if found {
 		return n.items[i], true
 	} else if len(n.children) > 0 {
@@ -366,58 +513,79 @@ const (
 )
 
 // remove removes an item from the subtree rooted at this node.
+//
+// Like insert, remove makes a single top-down pass: before descending into a
+// child it makes sure that child can spare an item, so nothing needs fixing
+// up on the way back and the descent runs as a loop.
 func (n *node[T]) remove(item T, minItems int, typ toRemove) (_ T, _ bool) {
-	var i int
-	var found bool
-	switch typ {
-	case removeMax:
-		if len(n.children) == 0 {
-			return n.items.pop(), true
-		}
-		i = len(n.items)
-	case removeMin:
-		if len(n.children) == 0 {
-			return n.items.removeAt(0), true
+	// grownFrom is the size of the last child grown at this node, or -1.
+	grownFrom := -1
+	for {
+		var i int
+		var found bool
+		switch typ {
+		case removeMax:
+			if len(n.children) == 0 {
+				return n.popItem(), true
+			}
+			i = len(n.items)
+		case removeMin:
+			if len(n.children) == 0 {
+				return n.removeItem(0), true
+			}
+			i = 0
+		case removeItem:
+			i, found = n.find(item)
+			if len(n.children) == 0 {
+				if found {
+					out := n.removeItem(i)
+					if n.cow.checkOrder && i > 0 {
+						n.items.checkNeighbors(i-1, n.cow.less)
+					}
+					return out, true
+				}
+				return
+			}
+		default:
+			panic("invalid type")
 		}
-		i = 0
-	case removeItem:
-		i, found = n.items.find(item, n.cow.less)
-		if len(n.children) == 0 {
-			if found {
-				return n.items.removeAt(i), true
+		// If we get to here, we have children.
+		if size := len(n.children[i].items); size <= minItems {
+			// Growing a child always leaves the child we select next with
+			// more items than before (and, unless it was below the minimum
+			// to begin with, enough of them), unless the Less function is
+			// inconsistent.  Catch that here rather than looping forever.
+			if grownFrom >= 0 && size <= grownFrom {
+				panic("btree: inconsistent Less function")
 			}
-			return
+			n.growChild(i, minItems)
+			grownFrom = size
+			continue
 		}
-	default:
-		panic("invalid type")
-	}
-	// If we get to here, we have children.
-	if len(n.children[i].items) <= minItems {
-		return n.growChildAndRemove(i, item, minItems, typ)
-	}
-	child := n.mutableChild(i)
-	// Either we had enough items to begin with, or we've done some
-	// merging/stealing, because we've got enough now and we're ready to return
-	// stuff.
-	if found {
-		// The item exists at index 'i', and the child we've selected can give us a
-		// predecessor, since if we've gotten here it's got > minItems items in it.
-		out := n.items[i]
-		// We use our special-case 'remove' call with typ=maxItem to pull the
-		// predecessor of item i (the rightmost leaf of our immediate left child)
-		// and set it into where we pulled the item from.
-		var zero T
-		n.items[i], _ = child.remove(zero, minItems, removeMax)
-		return out, true
+		child := n.mutableChild(i)
+		// Either we had enough items to begin with, or we've done some
+		// merging/stealing, because we've got enough now and we're ready to return
+		// stuff.
+		if found {
+			// The item exists at index 'i', and the child we've selected can give us a
+			// predecessor, since if we've gotten here it's got > minItems items in it.
+			out := n.items[i]
+			// We use our special-case 'remove' call with typ=maxItem to pull the
+			// predecessor of item i (the rightmost leaf of our immediate left child)
+			// and set it into where we pulled the item from.
+			var zero T
+			n.items[i], _ = child.remove(zero, minItems, removeMax)
+			return out, true
+		}
+		// Once we're here, we know that the item isn't in this node and that the
+		// child is big enough to remove from, so continue the descent there.
+		n, grownFrom = child, -1
 	}
-	// Final recursive call.  Once we're here, we know that the item isn't in this
-	// node and that the child is big enough to remove from.
-	return child.remove(item, minItems, typ)
 }
 
-// growChildAndRemove grows child 'i' to make sure it's possible to remove an
-// item from it while keeping it at minItems, then calls remove to actually
-// remove it.
+// growChild grows child 'i' to make sure it's possible to remove an item from
+// it while keeping it at minItems.  The caller then redoes its remove step at
+// this node.
 //
 // Most documentation says we have to do two sets of special casing:
 //   1) item is in this node
@@ -431,15 +599,15 @@ func (n *node[T]) remove(item T, minItems int, typ toRemove) (_ T, _ bool) {
 //   c) we must merge
 // To simplify our code here, we handle cases #1 and #2 the same:
 // If a node doesn't have enough items, we make sure it does (using a,b,c).
-// We then simply redo our remove call, and the second time (regardless of
+// We then simply redo our remove step, and the second time (regardless of
 // whether we're in case 1 or 2), we'll have enough items and can guarantee
 // that we hit case A.
-func (n *node[T]) growChildAndRemove(i int, item T, minItems int, typ toRemove) (T, bool) {
+func (n *node[T]) growChild(i int, minItems int) {
 	if i > 0 && len(n.children[i-1].items) > minItems {
 		// Steal from left child
 		child := n.mutableChild(i)
 		stealFrom := n.mutableChild(i - 1)
-		stolenItem := stealFrom.items.pop()
+		stolenItem := stealFrom.popItem()
 		child.items.insertAt(0, n.items[i-1])
 		n.items[i-1] = stolenItem
 		if len(stealFrom.children) > 0 {
@@ -449,7 +617,7 @@ func (n *node[T]) growChildAndRemove(i int, item T, minItems int, typ toRemove)
 		// steal from right child
 		child := n.mutableChild(i)
 		stealFrom := n.mutableChild(i + 1)
-		stolenItem := stealFrom.items.removeAt(0)
+		stolenItem := stealFrom.removeItem(0)
 		child.items = append(child.items, n.items[i])
 		n.items[i] = stolenItem
 		if len(stealFrom.children) > 0 {
@@ -461,14 +629,16 @@ func (n *node[T]) growChildAndRemove(i int, item T, minItems int, typ toRemove)
 		}
 		child := n.mutableChild(i)
 		// merge with right child
-		mergeItem := n.items.removeAt(i)
+		mergeItem := n.removeItem(i)
 		mergeChild := n.children.removeAt(i + 1)
 		child.items = append(child.items, mergeItem)
 		child.items = append(child.items, mergeChild.items...)
 		child.children = append(child.children, mergeChild.children...)
+		if h := n.cow.hooks; h != nil && h.OnMerge != nil {
+			h.OnMerge(Node[T]{child}, Node[T]{mergeChild}, mergeItem)
+		}
 		n.cow.freeNode(mergeChild)
 	}
-	return n.remove(item, minItems, typ)
 }
 
 type direction int
@@ -498,12 +668,20 @@ func empty[T any]() optionalItem[T] {
 // thus creating a "greaterOrEqual" or "lessThanEqual" rather than just a
 // "greaterThan" or "lessThan" queries.
 func (n *node[T]) iterate(dir direction, start, stop optionalItem[T], includeStart bool, hit bool, iter ItemIteratorG[T]) (bool, bool) {
+	// Scans without a start bound are the most common kind, and don't need
+	// any of the bookkeeping below, so they get their own tight loops.
+	if !start.valid {
+		if dir == ascend {
+			return hit, n.ascendUntil(stop, iter)
+		}
+		return hit, n.descendUntil(stop, iter)
+	}
 	var ok, found bool
 	var index int
 	switch dir {
 	case ascend:
 		if start.valid {
-			index, _ = n.items.find(start.item, n.cow.less)
+			index, _ = n.find(start.item)
 		}
 		for i := index; i < len(n.items); i++ {
 			if len(n.children) > 0 {
@@ -530,7 +708,7 @@ func (n *node[T]) iterate(dir direction, start, stop optionalItem[T], includeSta
 		}
 	case descend:
 		if start.valid {
-			index, found = n.items.find(start.item, n.cow.less)
+			index, found = n.find(start.item)
 			if !found {
 				index = index - 1
 			}
@@ -565,12 +743,76 @@ func (n *node[T]) iterate(dir direction, start, stop optionalItem[T], includeSta
 	return hit, true
 }
 
-// print is used for testing/debugging purposes.
-func (n *node[T]) print(w io.Writer, level int) {
-	fmt.Fprintf(w, "%sNODE:%v\n", strings.Repeat("  ", level), n.items)
-	for _, c := range n.children {
-		c.print(w, level+1)
+// ascendUntil calls iter for every item in n's subtree in ascending order,
+// stopping before the first item >= stop if stop is valid.  It returns false
+// if iteration should stop.
+func (n *node[T]) ascendUntil(stop optionalItem[T], iter ItemIteratorG[T]) bool {
+	for i, item := range n.items {
+		if len(n.children) > 0 && !n.children[i].ascendUntil(stop, iter) {
+			return false
+		}
+		if stop.valid && !n.cow.less(item, stop.item) {
+			return false
+		}
+		if !iter(item) {
+			return false
+		}
 	}
+	if len(n.children) > 0 {
+		return n.children[len(n.children)-1].ascendUntil(stop, iter)
+	}
+	return true
+}
+
+// descendUntil calls iter for every item in n's subtree in descending order,
+// stopping before the first item <= stop if stop is valid.  It returns false
+// if iteration should stop.
+func (n *node[T]) descendUntil(stop optionalItem[T], iter ItemIteratorG[T]) bool {
+	for i := len(n.items) - 1; i >= 0; i-- {
+		if len(n.children) > 0 && !n.children[i+1].descendUntil(stop, iter) {
+			return false
+		}
+		if stop.valid && !n.cow.less(stop.item, n.items[i]) {
+			return false
+		}
+		if !iter(n.items[i]) {
+			return false
+		}
+	}
+	if len(n.children) > 0 {
+		return n.children[0].descendUntil(stop, iter)
+	}
+	return true
+}
+
+// appendRange appends the items of n's subtree in the range [lo, hi) to dst.
+// An invalid lo means the range is unbounded below.  It returns false once it
+// has reached an item >= hi, since nothing after it can be in range.
+func (n *node[T]) appendRange(dst []T, lo optionalItem[T], hi T) ([]T, bool) {
+	var i int
+	var found bool
+	if lo.valid {
+		i, found = n.find(lo.item)
+	}
+	var ok bool
+	for ; i < len(n.items); i++ {
+		// If lo is in this node, everything in the child before it is < lo.
+		if len(n.children) > 0 && !found {
+			if dst, ok = n.children[i].appendRange(dst, lo, hi); !ok {
+				return dst, false
+			}
+		}
+		// Everything from here on is >= lo.
+		found, lo = false, empty[T]()
+		if !n.cow.less(n.items[i], hi) {
+			return dst, false
+		}
+		dst = append(dst, n.items[i])
+	}
+	if len(n.children) > 0 {
+		return n.children[len(n.children)-1].appendRange(dst, lo, hi)
+	}
+	return dst, true
 }
 
 // BTreeG is a generic implementation of a B-Tree.
@@ -585,6 +827,30 @@ type BTreeG[T any] struct {
 	length int
 	root   *node[T]
 	cow    *copyOnWriteContext[T]
+	limit  *limitG[T] // nil unless SetLimit has been called
+	usage  int64      // total weight of items, tracked only when limit != nil
+	// evicting is set while the eviction callback runs, so that writes it
+	// makes don't recursively trigger eviction.
+	evicting bool
+	// gen is incremented by every write, so iterators and cursors can tell
+	// if the tree changed underneath them.
+	gen uint64
+	// views are updated as items are added and removed; see AddView.
+	views []ViewG[T]
+	// bloom, if set, holds every item; see WithBloomFilter.
+	bloom *bloomFilter
+	// keys, if set, counts the hashes of every item; see WithKeyChecks.
+	keys *keyHashes
+	// minLeaf and maxLeaf are the leftmost and rightmost leaves, or nil if
+	// the tree is empty, unless edgesStale is set; see refreshEdges.
+	minLeaf, maxLeaf *node[T]
+	edgesStale       bool
+	// shared is set once the tree has been cloned, after which writes look
+	// before they copy; see noop.
+	shared bool
+	// sums, in an AugmentedG, holds the summaries of the root's subtree, a
+	// *sumNode[T, A]; see Augment.refresh.
+	sums any
 }
 
 // LessFunc[T] determines how to order a type 'T'.  It should implement a strict
@@ -608,6 +874,48 @@ type LessFunc[T any] func(a, b T) bool
 type copyOnWriteContext[T any] struct {
 	freelist *FreeListG[T]
 	less     LessFunc[T]
+	// search, if set, replaces items.find for searching within nodes.  It
+	// must order items exactly as less does.
+	search func(s items[T], item T, less LessFunc[T]) (int, bool)
+	// checkOrder enables the checks added by WithOrderingChecks.
+	checkOrder bool
+	// recoverLess is set by WithLessRecovery.
+	recoverLess bool
+	// checkMods enables the checks added by WithModificationChecks.
+	checkMods bool
+	// hooks, if set, are called as nodes are created, split, merged, and
+	// freed.
+	hooks *Hooks[T]
+	// tracer, if set, is called after each bulk operation.
+	tracer func(TraceEvent)
+	// augment, if set, brings the summaries of an AugmentedG up to date
+	// with a write, recomputing those of the nodes in touched.
+	augment func(t *BTreeG[T])
+	// touched holds the nodes changed, copied or freed since augment last
+	// ran, if augment is set.
+	touched map[*node[T]]bool
+	// counted is set if the summaries augment computes are subtree sizes,
+	// as in a CountedG.
+	counted bool
+	// comparisons, if set, counts calls to less; see
+	// WithComparisonCounting.
+	comparisons *comparisonCounter
+	// bloomHash, if set, hashes items for the tree's Bloom filter; see
+	// WithBloomFilter.
+	bloomHash func(T) uint64
+	// splitPolicy determines where full nodes are split; see
+	// WithSplitPolicy.
+	splitPolicy SplitPolicy
+	// keyHash, if set, hashes items for the checks added by WithKeyChecks.
+	keyHash func(T) uint64
+	// latency, if set, times operations; see WithLatencyHistograms.
+	latency *latencyRecorder
+	// pointerFree is set if items contain no pointers, so vacated slots
+	// needn't be zeroed; see pointerfree.go.
+	pointerFree bool
+	// equal, if set, reports whether replacing an item with another would
+	// change nothing; see WithItemEqual.
+	equal func(a, b T) bool
 }
 
 // Clone clones the btree, lazily.  Clone should not be called concurrently,
@@ -628,9 +936,17 @@ func (t *BTreeG[T]) Clone() (t2 *BTreeG[T]) {
 	//   the new b.cow nodes
 	//   the new out.cow nodes
 	cow1, cow2 := *t.cow, *t.cow
+	t.shared = true
 	out := *t
 	t.cow = &cow1
 	out.cow = &cow2
+	out.views = cloneViews(t.views)
+	if t.bloom != nil {
+		t.bloom.shared = true
+	}
+	if t.keys != nil {
+		t.keys.shared = true
+	}
 	return &out
 }
 
@@ -648,6 +964,12 @@ func (t *BTreeG[T]) minItems() int {
 func (c *copyOnWriteContext[T]) newNode() (n *node[T]) {
 	n = c.freelist.newNode()
 	n.cow = c
+	if c.augment != nil {
+		c.touch(n)
+	}
+	if c.hooks != nil && c.hooks.OnNodeAlloc != nil {
+		c.hooks.OnNodeAlloc(Node[T]{n})
+	}
 	return
 }
 
@@ -664,10 +986,16 @@ const (
 // documentation).
 func (c *copyOnWriteContext[T]) freeNode(n *node[T]) freeType {
 	if n.cow == c {
+		if c.hooks != nil && c.hooks.OnNodeFree != nil {
+			c.hooks.OnNodeFree(Node[T]{n})
+		}
 		// clear to allow GC
-		n.items.truncate(0)
+		n.truncateItems(0)
 		n.children.truncate(0)
 		n.cow = nil
+		if c.augment != nil {
+			c.touch(n)
+		}
 		if c.freelist.freeNode(n) {
 			return ftStored
 		} else {
@@ -684,26 +1012,102 @@ func (c *copyOnWriteContext[T]) freeNode(n *node[T]) freeType {
 //
 // nil cannot be added to the tree (will panic).
 func (t *BTreeG[T]) ReplaceOrInsert(item T) (_ T, _ bool) {
+	if c := t.cow.comparisons; c != nil {
+		defer c.inserts.done(c, c.start())
+	}
+	if l := t.cow.latency; l != nil {
+		defer l.inserts.done(time.Now())
+	}
+	return t.replaceOrInsert(item)
+}
+
+func (t *BTreeG[T]) replaceOrInsert(item T) (_ T, _ bool) {
+	if old, ok := t.noopInsert(item); ok {
+		return old, true
+	}
 	if t.root == nil {
 		t.root = t.cow.newNode()
 		t.root.items = append(t.root.items, item)
-		t.length++
+		t.inserted(item, item, false)
 		return
-	} else {
-		t.root = t.root.mutableFor(t.cow)
-		if len(t.root.items) >= t.maxItems() {
-			item2, second := t.root.split(t.maxItems() / 2)
-			oldroot := t.root
-			t.root = t.cow.newNode()
-			t.root.items = append(t.root.items, item2)
-			t.root.children = append(t.root.children, oldroot, second)
-		}
 	}
+	t.mutableRoot(item)
 	out, outb := t.root.insert(item, t.maxItems())
-	if !outb {
+	t.inserted(item, out, outb)
+	return out, outb
+}
+
+// AppendMax adds the given item to the tree, like ReplaceOrInsert, for callers
+// that expect it to be greater than every item already in the tree, such as
+// when loading sorted data or recording timestamps and sequence numbers.
+//
+// In that case AppendMax walks straight down the right edge of the tree and
+// makes a single comparison, against the current maximum, instead of
+// searching each node on the way down.  If item is not greater than the
+// maximum, AppendMax falls back to ReplaceOrInsert, so it is always safe to
+// call.
+func (t *BTreeG[T]) AppendMax(item T) (_ T, _ bool) {
+	if c := t.cow.comparisons; c != nil {
+		defer c.inserts.done(c, c.start())
+	}
+	if l := t.cow.latency; l != nil {
+		defer l.inserts.done(time.Now())
+	}
+	last, ok := t.Max()
+	if !ok || !t.cow.less(last, item) {
+		return t.replaceOrInsert(item)
+	}
+	t.mutableRoot(item)
+	n := t.root
+	for len(n.children) > 0 {
+		n.maybeSplitChild(len(n.children)-1, t.maxItems(), true, item)
+		n = n.mutableChild(len(n.children) - 1)
+	}
+	n.items = append(n.items, item)
+	t.inserted(item, item, false)
+	return
+}
+
+// mutableRoot makes the root writable by this tree and, if it's full, splits
+// it so that an insert of item can descend into it.
+func (t *BTreeG[T]) mutableRoot(item T) {
+	t.root = t.root.mutableFor(t.cow)
+	if len(t.root.items) >= t.maxItems() {
+		item2, second := t.root.split(t.root.splitIndex(t.maxItems(), true, item))
+		oldroot := t.root
+		t.root = t.cow.newNode()
+		t.root.items = append(t.root.items, item2)
+		t.root.children = append(t.root.children, oldroot, second)
+	}
+}
+
+// inserted updates the tree's bookkeeping after item has been added to it,
+// replacing old if replaced is true.
+func (t *BTreeG[T]) inserted(item, old T, replaced bool) {
+	t.gen++
+	t.refresh()
+	for _, v := range t.views {
+		if replaced {
+			v.Remove(old)
+		}
+		v.Add(item)
+	}
+	if !replaced {
 		t.length++
+		if t.cow.bloomHash != nil {
+			t.bloomInserted(item)
+		}
+	}
+	if t.cow.keyHash != nil {
+		t.keysInserted(item, old, replaced)
+	}
+	if t.limit != nil {
+		t.usage += t.limit.weigh(item)
+		if replaced {
+			t.usage -= t.limit.weigh(old)
+		}
+		t.enforceLimit()
 	}
-	return out, outb
 }
 
 // Delete removes an item equal to the passed in item from the tree, returning
@@ -727,9 +1131,27 @@ func (t *BTreeG[T]) DeleteMax() (T, bool) {
 }
 
 func (t *BTreeG[T]) deleteItem(item T, typ toRemove) (_ T, _ bool) {
-	if t.root == nil || len(t.root.items) == 0 {
+	if c := t.cow.comparisons; c != nil {
+		defer c.deletes.done(c, c.start())
+	}
+	if l := t.cow.latency; l != nil {
+		defer l.deletes.done(time.Now())
+	}
+	if t.root == nil || len(t.root.items) == 0 || (typ == removeItem && t.noopDelete(item)) {
 		return
 	}
+	t.gen++
+	if leaf := t.edgeLeaf(typ); leaf != nil {
+		// The cached leaves stay where they are.
+		var out T
+		if typ == removeMin {
+			out = leaf.removeItem(0)
+		} else {
+			out = leaf.popItem()
+		}
+		t.deleted(out)
+		return out, true
+	}
 	t.root = t.root.mutableFor(t.cow)
 	out, outb := t.root.remove(item, t.minItems(), typ)
 	if len(t.root.items) == 0 && len(t.root.children) > 0 {
@@ -738,100 +1160,177 @@ func (t *BTreeG[T]) deleteItem(item T, typ toRemove) (_ T, _ bool) {
 		t.cow.freeNode(oldroot)
 	}
 	if outb {
-		t.length--
+		t.deleted(out)
 	}
+	t.refresh()
 	return out, outb
 }
 
+// deleted updates the tree's bookkeeping after item has been removed from it.
+func (t *BTreeG[T]) deleted(item T) {
+	t.length--
+	t.bloomDeleted(item)
+	t.keysDeleted(item)
+	if t.limit != nil {
+		t.usage -= t.limit.weigh(item)
+	}
+	for _, v := range t.views {
+		v.Remove(item)
+	}
+}
+
 // AscendRange calls the iterator for every value in the tree within the range
 // [greaterOrEqual, lessThan), until iterator returns false.
 func (t *BTreeG[T]) AscendRange(greaterOrEqual, lessThan T, iterator ItemIteratorG[T]) {
+	if l := t.cow.latency; l != nil {
+		defer l.rangeScans.done(time.Now())
+	}
 	if t.root == nil {
 		return
 	}
-	t.root.iterate(ascend, optional[T](greaterOrEqual), optional[T](lessThan), true, false, iterator)
+	t.root.iterate(ascend, optional[T](greaterOrEqual), optional[T](lessThan), true, false, t.guard(iterator))
 }
 
 // AscendLessThan calls the iterator for every value in the tree within the range
 // [first, pivot), until iterator returns false.
 func (t *BTreeG[T]) AscendLessThan(pivot T, iterator ItemIteratorG[T]) {
+	if l := t.cow.latency; l != nil {
+		defer l.rangeScans.done(time.Now())
+	}
 	if t.root == nil {
 		return
 	}
-	t.root.iterate(ascend, empty[T](), optional(pivot), false, false, iterator)
+	t.root.iterate(ascend, empty[T](), optional(pivot), false, false, t.guard(iterator))
 }
 
 // AscendGreaterOrEqual calls the iterator for every value in the tree within
 // the range [pivot, last], until iterator returns false.
 func (t *BTreeG[T]) AscendGreaterOrEqual(pivot T, iterator ItemIteratorG[T]) {
+	if l := t.cow.latency; l != nil {
+		defer l.rangeScans.done(time.Now())
+	}
 	if t.root == nil {
 		return
 	}
-	t.root.iterate(ascend, optional[T](pivot), empty[T](), true, false, iterator)
+	t.root.iterate(ascend, optional[T](pivot), empty[T](), true, false, t.guard(iterator))
 }
 
 // Ascend calls the iterator for every value in the tree within the range
 // [first, last], until iterator returns false.
 func (t *BTreeG[T]) Ascend(iterator ItemIteratorG[T]) {
+	if l := t.cow.latency; l != nil {
+		defer l.rangeScans.done(time.Now())
+	}
+	t.ascend(iterator)
+}
+
+// ascend is Ascend without the timing, for other operations that walk the
+// whole tree.
+func (t *BTreeG[T]) ascend(iterator ItemIteratorG[T]) {
 	if t.root == nil {
 		return
 	}
-	t.root.iterate(ascend, empty[T](), empty[T](), false, false, iterator)
+	t.root.iterate(ascend, empty[T](), empty[T](), false, false, t.guard(iterator))
 }
 
 // DescendRange calls the iterator for every value in the tree within the range
 // [lessOrEqual, greaterThan), until iterator returns false.
 func (t *BTreeG[T]) DescendRange(lessOrEqual, greaterThan T, iterator ItemIteratorG[T]) {
+	if l := t.cow.latency; l != nil {
+		defer l.rangeScans.done(time.Now())
+	}
 	if t.root == nil {
 		return
 	}
-	t.root.iterate(descend, optional[T](lessOrEqual), optional[T](greaterThan), true, false, iterator)
+	t.root.iterate(descend, optional[T](lessOrEqual), optional[T](greaterThan), true, false, t.guard(iterator))
 }
 
 // DescendLessOrEqual calls the iterator for every value in the tree within the range
 // [pivot, first], until iterator returns false.
 func (t *BTreeG[T]) DescendLessOrEqual(pivot T, iterator ItemIteratorG[T]) {
+	if l := t.cow.latency; l != nil {
+		defer l.rangeScans.done(time.Now())
+	}
 	if t.root == nil {
 		return
 	}
-	t.root.iterate(descend, optional[T](pivot), empty[T](), true, false, iterator)
+	t.root.iterate(descend, optional[T](pivot), empty[T](), true, false, t.guard(iterator))
 }
 
 // DescendGreaterThan calls the iterator for every value in the tree within
 // the range [last, pivot), until iterator returns false.
 func (t *BTreeG[T]) DescendGreaterThan(pivot T, iterator ItemIteratorG[T]) {
+	if l := t.cow.latency; l != nil {
+		defer l.rangeScans.done(time.Now())
+	}
 	if t.root == nil {
 		return
 	}
-	t.root.iterate(descend, empty[T](), optional[T](pivot), false, false, iterator)
+	t.root.iterate(descend, empty[T](), optional[T](pivot), false, false, t.guard(iterator))
 }
 
 // Descend calls the iterator for every value in the tree within the range
 // [last, first], until iterator returns false.
 func (t *BTreeG[T]) Descend(iterator ItemIteratorG[T]) {
+	if l := t.cow.latency; l != nil {
+		defer l.rangeScans.done(time.Now())
+	}
 	if t.root == nil {
 		return
 	}
-	t.root.iterate(descend, empty[T](), empty[T](), false, false, iterator)
+	t.root.iterate(descend, empty[T](), empty[T](), false, false, t.guard(iterator))
+}
+
+// AppendRange appends every value in the tree within the range
+// [greaterOrEqual, lessThan) to dst, in ascending order, and returns the
+// extended slice.  Unlike AscendRange it makes no callbacks, so if dst has
+// enough capacity it doesn't allocate at all.
+func (t *BTreeG[T]) AppendRange(dst []T, greaterOrEqual, lessThan T) []T {
+	if t.root == nil {
+		return dst
+	}
+	dst, _ = t.root.appendRange(dst, optional[T](greaterOrEqual), lessThan)
+	return dst
 }
 
 // Get looks for the key item in the tree, returning it.  It returns
 // (zeroValue, false) if unable to find that item.
 func (t *BTreeG[T]) Get(key T) (_ T, _ bool) {
+	if c := t.cow.comparisons; c != nil {
+		defer c.lookups.done(c, c.start())
+	}
+	if l := t.cow.latency; l != nil {
+		defer l.gets.done(time.Now())
+	}
+	if t.bloomExcludes(key) {
+		return
+	}
 	if t.root == nil {
 		return
 	}
-	return t.root.get(key)
+	item, ok := t.root.get(key)
+	if ok && t.keys != nil {
+		t.checkKey(item)
+	}
+	return item, ok
 }
 
 // Min returns the smallest item in the tree, or (zeroValue, false) if the tree is empty.
 func (t *BTreeG[T]) Min() (_ T, _ bool) {
-	return min(t.root)
+	leaf := t.edge(true)
+	if leaf == nil {
+		return
+	}
+	return leaf.items[0], true
 }
 
 // Max returns the largest item in the tree, or (zeroValue, false) if the tree is empty.
 func (t *BTreeG[T]) Max() (_ T, _ bool) {
-	return max(t.root)
+	leaf := t.edge(false)
+	if leaf == nil {
+		return
+	}
+	return leaf.items[len(leaf.items)-1], true
 }
 
 // Has returns true if the given key is in the tree.
@@ -845,6 +1344,16 @@ func (t *BTreeG[T]) Len() int {
 	return t.length
 }
 
+// Len64 returns the number of items currently in the tree, as an int64.
+//
+// A tree can never hold more items than an int can count, since every item
+// takes up memory, but on 32-bit platforms totals summed across many trees,
+// or compared with counts from elsewhere, can overflow an int.  Len64 saves
+// converting at every such call site.
+func (t *BTreeG[T]) Len64() int64 {
+	return int64(t.length)
+}
+
 // Clear removes all items from the btree.  If addNodesToFreelist is true,
 // t's nodes are added to its freelist as part of this call, until the freelist
 // is full.  Otherwise, the root node is simply dereferenced and the subtree
@@ -866,10 +1375,225 @@ func (t *BTreeG[T]) Len() int {
 //       iterated over looking for nodes to add to the freelist, and due to
 //       ownership, none are.
 func (t *BTreeG[T]) Clear(addNodesToFreelist bool) {
+	for _, v := range t.views {
+		v.Reset()
+	}
+	t.bloom, t.keys = nil, nil
+	if !addNodesToFreelist {
+		t.clear(false)
+		return
+	}
+	start, items := t.traceStart(), t.length
+	t.clear(true)
+	t.traceEnd("Clear", start, items)
+}
+
+// clear implements Clear, without tracing.
+func (t *BTreeG[T]) clear(addNodesToFreelist bool) {
 	if t.root != nil && addNodesToFreelist {
 		t.root.reset(t.cow)
 	}
-	t.root, t.length = nil, 0
+	t.root, t.length, t.usage = nil, 0, 0
+	t.minLeaf, t.maxLeaf, t.edgesStale = nil, nil, false
+	t.gen++
+}
+
+// Rebuild reconstructs the tree bottom-up from its own contents, packing
+// nodes as full as the B-Tree invariants allow.  This restores the tree's
+// fill factor after a long series of mixed inserts and deletes, making it
+// smaller and faster to scan.  It returns the fill factor before and after
+// the rebuild, so callers can decide how often it's worth running.
+//
+// Rebuild takes O(n) time and temporarily allocates a slice of all items.
+// The old nodes are returned to the freelist where possible.  Clones of the
+// tree are not affected.
+func (t *BTreeG[T]) Rebuild() (before, after float64) {
+	start := t.traceStart()
+	before = t.FillFactor()
+	all := t.all()
+	length, usage := t.length, t.usage
+	t.clear(true)
+	t.root = t.cow.buildSorted(all, t.maxItems())
+	t.length, t.usage = length, usage
+	t.refresh()
+	t.traceEnd("Rebuild", start, length)
+	return before, t.FillFactor()
+}
+
+// WithDegree returns a new tree with the given degree containing the same
+// items as t, which is left unchanged.  The new tree is built bottom-up with
+// fully packed nodes and shares t's LessFunc, freelist, and limit, so it can
+// replace t in place when a long-lived tree's degree needs retuning:
+//
+//	t = t.WithDegree(64)
+//
+// WithDegree takes O(n) time and panics if degree is less than 2.
+func (t *BTreeG[T]) WithDegree(degree int) *BTreeG[T] {
+	if degree <= 1 {
+		panic("bad degree")
+	}
+	start := t.traceStart()
+	cow := *t.cow
+	out := &BTreeG[T]{
+		degree: degree,
+		length: t.length,
+		cow:    &cow,
+		limit:  t.limit,
+		usage:  t.usage,
+		views:  cloneViews(t.views),
+	}
+	out.root = out.cow.buildSorted(t.all(), out.maxItems())
+	out.refresh()
+	t.traceEnd("WithDegree", start, t.length)
+	return out
+}
+
+// all returns a new slice containing every item in the tree, in order.
+func (t *BTreeG[T]) all() []T {
+	out := make([]T, 0, t.length)
+	t.ascend(func(item T) bool {
+		out = append(out, item)
+		return true
+	})
+	return out
+}
+
+// buildSorted returns a new subtree, owned by c, containing the given items,
+// which must be in ascending order with no duplicates.  Nodes are packed with
+// up to maxItems items each, the tree is as short as possible, and items are
+// spread evenly between siblings so that no node falls below the minimum.
+// It returns nil if there are no items.
+func (c *copyOnWriteContext[T]) buildSorted(items []T, maxItems int) *node[T] {
+	if len(items) == 0 {
+		return nil
+	}
+	// Find the capacity of a subtree one level shorter than the result; a
+	// subtree of height h holds at most (maxItems+1)^h - 1 items.
+	below := 0
+	for capacity := maxItems; capacity < len(items); capacity = capacity*(maxItems+1) + maxItems {
+		below = capacity
+	}
+	return c.buildLevel(items, below, maxItems)
+}
+
+// buildLevel builds a node whose children are subtrees with capacity below
+// (or a leaf, if below is 0).  It uses as few children as possible and
+// divides the items between them evenly, which guarantees that each child
+// is at least half full and so satisfies the minimum node size.
+func (c *copyOnWriteContext[T]) buildLevel(items []T, below, maxItems int) *node[T] {
+	n := c.newNode()
+	if below == 0 {
+		n.items = append(n.items, items...)
+		return n
+	}
+	children := (len(items) + below + 1) / (below + 1)
+	perChild, extra := (len(items)-(children-1))/children, (len(items)-(children-1))%children
+	next := (below+1)/(maxItems+1) - 1
+	for i := 0; i < children; i++ {
+		size := perChild
+		if i < extra {
+			size++
+		}
+		n.children = append(n.children, c.buildLevel(items[:size], next, maxItems))
+		items = items[size:]
+		if i < children-1 {
+			n.items = append(n.items, items[0])
+			items = items[1:]
+		}
+	}
+	return n
+}
+
+// EvictFuncG is called by a tree whose limit (see SetLimit) has been exceeded.
+// It should remove one or more items from t, typically with DeleteMin,
+// DeleteMax, or Delete.  It is called repeatedly until the tree is back within
+// its limit, the tree is empty, or a call fails to reduce the tree's usage.
+type EvictFuncG[T any] func(t *BTreeG[T])
+
+// EvictMinG is an EvictFuncG that removes the smallest item in the tree.
+func EvictMinG[T any](t *BTreeG[T]) {
+	t.DeleteMin()
+}
+
+// EvictMaxG is an EvictFuncG that removes the largest item in the tree.
+func EvictMaxG[T any](t *BTreeG[T]) {
+	t.DeleteMax()
+}
+
+// limitG holds the configuration set by SetLimit.  It is never modified once
+// created, so it can be shared between a tree and its clones.
+type limitG[T any] struct {
+	max    int64
+	weight func(T) int64
+	evict  EvictFuncG[T]
+}
+
+func (l *limitG[T]) weigh(item T) int64 {
+	if l.weight == nil {
+		return 1
+	}
+	return l.weight(item)
+}
+
+// newLimit returns the limit set by SetLimit or WithLimit, or nil if max is
+// negative.
+func newLimit[T any](max int64, weight func(T) int64, evict EvictFuncG[T]) *limitG[T] {
+	if max < 0 {
+		return nil
+	}
+	if evict == nil {
+		evict = EvictMinG[T]
+	}
+	return &limitG[T]{max: max, weight: weight, evict: evict}
+}
+
+// SetLimit places a soft limit on the total weight of the items in the tree.
+// weight returns the weight of a single item (for example its approximate
+// size in bytes); if weight is nil, every item weighs 1 and max is simply a
+// maximum item count.  Whenever an insert leaves the tree's usage above max,
+// evict is called until the tree is back within the limit.  If evict is nil,
+// EvictMinG is used.
+//
+// Calling SetLimit computes the usage of the items already in the tree, which
+// takes O(n), and evicts immediately if the limit is already exceeded.  A max
+// less than zero removes any existing limit.  Clones inherit the limit of
+// the tree they were cloned from, but track their usage independently.
+func (t *BTreeG[T]) SetLimit(max int64, weight func(T) int64, evict EvictFuncG[T]) {
+	t.limit, t.usage = newLimit(max, weight, evict), 0
+	if t.limit == nil {
+		return
+	}
+	start := t.traceStart()
+	t.ascend(func(item T) bool {
+		t.usage += t.limit.weigh(item)
+		return true
+	})
+	t.traceEnd("SetLimit", start, t.length)
+	t.enforceLimit()
+}
+
+// Usage returns the total weight of the items in the tree, as determined by
+// the weight function passed to SetLimit.  It returns 0 if no limit is set.
+func (t *BTreeG[T]) Usage() int64 {
+	return t.usage
+}
+
+// enforceLimit calls the eviction callback until the tree is back within its
+// limit.  It stops early if the callback doesn't make progress, since calling
+// it again would loop forever.
+func (t *BTreeG[T]) enforceLimit() {
+	if t.evicting {
+		return
+	}
+	t.evicting = true
+	defer func() { t.evicting = false }()
+	for t.limit != nil && t.usage > t.limit.max && t.length > 0 {
+		before := t.usage
+		t.limit.evict(t)
+		if t.usage >= before {
+			return
+		}
+	}
 }
 
 // reset returns a subtree to the freelist.  It breaks out immediately if the
@@ -881,7 +1605,8 @@ func (n *node[T]) reset(c *copyOnWriteContext[T]) bool {
 			return false
 		}
 	}
-	return c.freeNode(n) != ftFreelistFull
+	// With an OnNodeFree hook, keep going so every node gets its call.
+	return c.freeNode(n) != ftFreelistFull || (c.hooks != nil && c.hooks.OnNodeFree != nil)
 }
 
 // Int implements the Item interface for integers.
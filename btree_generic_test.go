@@ -762,3 +762,264 @@ func BenchmarkDeleteAndRestoreG(b *testing.B) {
 		}
 	})
 }
+
+func TestSetLimitG(t *testing.T) {
+	tr := NewOrderedG[int](*btreeDegree)
+	for _, v := range rand.Perm(100) {
+		tr.ReplaceOrInsert(v)
+	}
+	tr.SetLimit(50, nil, nil)
+	if tr.Len() != 50 || tr.Usage() != 50 {
+		t.Fatalf("after SetLimit: len %v usage %v, want 50", tr.Len(), tr.Usage())
+	}
+	if got, want := intAll(tr), intRange(100, false)[50:]; !reflect.DeepEqual(got, want) {
+		t.Fatalf("EvictMinG kept wrong items:\n got: %v\nwant: %v", got, want)
+	}
+	tr.ReplaceOrInsert(1000)
+	if min, _ := tr.Min(); tr.Len() != 50 || min != 51 {
+		t.Fatalf("insert over limit: len %v min %v", tr.Len(), min)
+	}
+
+	// Weighted limit, evicting the largest items.
+	tr = NewOrderedG[int](*btreeDegree)
+	tr.SetLimit(100, func(i int) int64 { return int64(i) }, EvictMaxG[int])
+	for i := 1; i <= 20; i++ {
+		tr.ReplaceOrInsert(i)
+		if tr.Usage() > 100 {
+			t.Fatalf("usage %v exceeds limit", tr.Usage())
+		}
+	}
+	if got, want := intAll(tr), []int{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("EvictMaxG kept wrong items:\n got: %v\nwant: %v", got, want)
+	}
+
+	// An evictor that makes no progress must not loop forever.
+	tr = NewOrderedG[int](*btreeDegree)
+	tr.SetLimit(1, nil, func(*BTreeG[int]) {})
+	tr.ReplaceOrInsert(1)
+	tr.ReplaceOrInsert(2)
+	if tr.Len() != 2 {
+		t.Fatalf("len %v, want 2", tr.Len())
+	}
+	tr.SetLimit(-1, nil, nil)
+	if tr.Usage() != 0 {
+		t.Fatalf("usage %v after removing limit", tr.Usage())
+	}
+}
+
+func TestInconsistentLessTerminatesG(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	tr := NewG[int](2, func(a, b int) bool { return r.Intn(2) == 0 })
+	for i := 0; i < 1000; i++ {
+		tr.ReplaceOrInsert(i)
+	}
+	for i := 0; i < 1000; i++ {
+		func() {
+			// Panicking is fine, but every call must return.
+			defer func() { recover() }()
+			tr.Delete(i)
+		}()
+	}
+}
+
+func BenchmarkFindG(b *testing.B) {
+	less := Less[int]()
+	for _, size := range []int{3, 7, 15, 31, 63} {
+		s := make(items[int], size)
+		for i := range s {
+			s[i] = i * 2
+		}
+		keys := rand.Perm(size * 2)
+		for _, find := range []struct {
+			name string
+			f    func(items[int], int, func(int, int) bool) (int, bool)
+		}{
+			{"binary", items[int].findBinary},
+			{"linear", items[int].findLinear},
+		} {
+			b.Run(fmt.Sprintf("%v/%v", size, find.name), func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					find.f(s, keys[i%len(keys)], less)
+				}
+			})
+		}
+	}
+}
+
+func TestAppendMaxG(t *testing.T) {
+	tr := NewOrderedG[int](2)
+	for i := 0; i < 1000; i += 2 {
+		if _, ok := tr.AppendMax(i); ok {
+			t.Fatalf("AppendMax(%v) replaced an item", i)
+		}
+	}
+	// Items that aren't a new maximum fall back to ReplaceOrInsert.
+	if old, ok := tr.AppendMax(500); !ok || old != 500 {
+		t.Fatalf("AppendMax(500) = %v, %v", old, ok)
+	}
+	for i := 1; i < 1000; i += 2 {
+		tr.AppendMax(i)
+	}
+	if got, want := intAll(tr), intRange(1000, false); !reflect.DeepEqual(got, want) {
+		t.Fatalf("mismatch:\n got: %v\nwant: %v", got, want)
+	}
+	if tr.Len() != 1000 {
+		t.Fatalf("len %v, want 1000", tr.Len())
+	}
+}
+
+func BenchmarkAppendMaxG(b *testing.B) {
+	b.StopTimer()
+	insertP := intRange(benchmarkTreeSize, false)
+	b.StartTimer()
+	i := 0
+	for i < b.N {
+		tr := NewOrderedG[int](*btreeDegree)
+		for _, item := range insertP {
+			tr.AppendMax(item)
+			i++
+			if i >= b.N {
+				return
+			}
+		}
+	}
+}
+
+func TestAppendRangeG(t *testing.T) {
+	tr := NewOrderedG[int](*btreeDegree)
+	for _, v := range rand.Perm(100) {
+		tr.ReplaceOrInsert(v)
+	}
+	buf := make([]int, 0, 100)
+	for _, r := range [][2]int{{40, 60}, {-10, 10}, {90, 200}, {50, 50}, {60, 40}, {-5, 500}} {
+		var want []int
+		tr.AscendRange(r[0], r[1], func(a int) bool {
+			want = append(want, a)
+			return true
+		})
+		got := tr.AppendRange(buf[:0], r[0], r[1])
+		if len(got) != len(want) || (len(got) > 0 && !reflect.DeepEqual(got, want)) {
+			t.Errorf("AppendRange(%v, %v):\n got: %v\nwant: %v", r[0], r[1], got, want)
+		}
+	}
+	if allocs := testing.AllocsPerRun(10, func() { tr.AppendRange(buf[:0], 10, 90) }); allocs != 0 {
+		t.Errorf("AppendRange allocated %v times", allocs)
+	}
+}
+
+func TestRebuildG(t *testing.T) {
+	for _, degree := range []int{2, 3, 5, *btreeDegree} {
+		for _, size := range []int{0, 1, 2, 3, 10, 100, 1000, 4321} {
+			tr := NewOrderedG[int](degree)
+			for _, v := range rand.Perm(size * 2) {
+				tr.ReplaceOrInsert(v)
+			}
+			for _, v := range rand.Perm(size * 2)[:size] {
+				tr.Delete(v)
+			}
+			want := intAll(tr)
+			before, after := tr.Rebuild()
+			if err := tr.Verify(); err != nil {
+				t.Fatal(err)
+			}
+			if got := intAll(tr); !reflect.DeepEqual(got, want) || tr.Len() != size {
+				t.Fatalf("degree %v size %v: contents changed by Rebuild", degree, size)
+			}
+			if size > 100 && after <= before {
+				t.Errorf("degree %v size %v: fill factor went from %v to %v", degree, size, before, after)
+			}
+			// The rebuilt tree must still support writes.
+			for _, v := range rand.Perm(size * 2) {
+				tr.ReplaceOrInsert(v)
+			}
+			if err := tr.Verify(); err != nil {
+				t.Fatal(err)
+			}
+			if got, want := intAll(tr), intRange(size*2, false); size > 0 && !reflect.DeepEqual(got, want) {
+				t.Fatalf("degree %v size %v: inserts after Rebuild failed", degree, size)
+			}
+		}
+	}
+}
+
+func TestWithDegreeG(t *testing.T) {
+	tr := NewOrderedG[int](*btreeDegree)
+	for _, v := range rand.Perm(1000) {
+		tr.ReplaceOrInsert(v)
+	}
+	for _, degree := range []int{2, 7, 100} {
+		tr2 := tr.WithDegree(degree)
+		if err := tr2.Verify(); err != nil {
+			t.Fatal(err)
+		}
+		if tr2.degree != degree || tr2.Len() != 1000 || !reflect.DeepEqual(intAll(tr2), intAll(tr)) {
+			t.Fatalf("WithDegree(%v) changed contents", degree)
+		}
+		tr2.Delete(5)
+		if !tr.Has(5) {
+			t.Fatalf("WithDegree(%v) shares nodes with the original", degree)
+		}
+	}
+}
+
+func TestFindFromG(t *testing.T) {
+	less := Less[int]()
+	for size := 0; size < 40; size++ {
+		s := make(items[int], size)
+		for i := range s {
+			s[i] = i * 2
+		}
+		for item := -1; item <= size*2; item++ {
+			wantI, wantFound := s.find(item, less)
+			for hint := 0; hint <= size; hint++ {
+				if i, found := s.findFrom(hint, item, less); i != wantI || found != wantFound {
+					t.Fatalf("size %v: findFrom(%v, %v) = %v, %v; want %v, %v", size, hint, item, i, found, wantI, wantFound)
+				}
+			}
+			if i, found := interpolationFind(s, item, less); i != wantI || found != wantFound {
+				t.Fatalf("size %v: interpolationFind(%v) = %v, %v; want %v, %v", size, item, i, found, wantI, wantFound)
+			}
+		}
+	}
+}
+
+func TestInterpolatedG(t *testing.T) {
+	tr := NewInterpolatedG[float64](*btreeDegree)
+	for _, v := range rand.Perm(10000) {
+		// Skew the distribution so interpolation guesses are often wrong.
+		tr.ReplaceOrInsert(float64(v * v))
+	}
+	for v := 0; v < 10000; v++ {
+		if !tr.Has(float64(v*v)) || tr.Has(float64(v*v)+0.5) {
+			t.Fatalf("lookup of %v failed", v*v)
+		}
+	}
+	for v := 0; v < 10000; v += 2 {
+		if _, ok := tr.Delete(float64(v * v)); !ok {
+			t.Fatalf("delete of %v failed", v*v)
+		}
+	}
+	if tr.Len() != 5000 {
+		t.Fatalf("len %v, want 5000", tr.Len())
+	}
+}
+
+func BenchmarkGetInterpolatedG(b *testing.B) {
+	insertP := rand.Perm(benchmarkTreeSize)
+	for _, c := range []struct {
+		name string
+		tr   *BTreeG[int]
+	}{
+		{"binary", NewOrderedG[int](*btreeDegree)},
+		{"interpolated", NewInterpolatedG[int](*btreeDegree)},
+	} {
+		for _, v := range insertP {
+			c.tr.ReplaceOrInsert(v)
+		}
+		b.Run(c.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				c.tr.Get(insertP[i%benchmarkTreeSize])
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package inttree
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/google/btree"
+)
+
+func collect(f func(TreeIterator)) []int {
+	var out []int
+	f(func(i int) bool {
+		out = append(out, i)
+		return true
+	})
+	return out
+}
+
+func collectG(f func(btree.ItemIteratorG[int])) []int {
+	var out []int
+	f(func(i int) bool {
+		out = append(out, i)
+		return true
+	})
+	return out
+}
+
+// TestMatchesBTreeG checks the generated tree against btree.BTreeG.
+func TestMatchesBTreeG(t *testing.T) {
+	for _, degree := range []int{2, 3, 8} {
+		tr, want := NewTree(degree), btree.NewOrderedG[int](degree)
+		var clone *Tree
+		for i := 0; i < 5000; i++ {
+			k := rand.Intn(500)
+			var got, exp int
+			var gotOK, expOK bool
+			switch rand.Intn(5) {
+			case 0, 1:
+				got, gotOK = tr.ReplaceOrInsert(k)
+				exp, expOK = want.ReplaceOrInsert(k)
+			case 2:
+				got, gotOK = tr.Delete(k)
+				exp, expOK = want.Delete(k)
+			case 3:
+				got, gotOK = tr.DeleteMin()
+				exp, expOK = want.DeleteMin()
+			case 4:
+				got, gotOK = tr.Get(k)
+				exp, expOK = want.Get(k)
+			}
+			if got != exp || gotOK != expOK || tr.Len() != want.Len() {
+				t.Fatalf("degree %d, op %d on %d: got %d, %v, Len %d; want %d, %v, Len %d", degree, i, k, got, gotOK, tr.Len(), exp, expOK, want.Len())
+			}
+			if i == 2500 {
+				clone = tr.Clone()
+			}
+		}
+		if got, exp := collect(tr.Ascend), collectG(want.Ascend); !reflect.DeepEqual(got, exp) {
+			t.Fatalf("degree %d: Ascend = %v, want %v", degree, got, exp)
+		}
+		lo, hi := 100, 300
+		checks := []struct {
+			name     string
+			got, exp []int
+		}{
+			{"AscendRange", collect(func(f TreeIterator) { tr.AscendRange(lo, hi, f) }), collectG(func(f btree.ItemIteratorG[int]) { want.AscendRange(lo, hi, f) })},
+			{"AscendLessThan", collect(func(f TreeIterator) { tr.AscendLessThan(hi, f) }), collectG(func(f btree.ItemIteratorG[int]) { want.AscendLessThan(hi, f) })},
+			{"AscendGreaterOrEqual", collect(func(f TreeIterator) { tr.AscendGreaterOrEqual(lo, f) }), collectG(func(f btree.ItemIteratorG[int]) { want.AscendGreaterOrEqual(lo, f) })},
+			{"Descend", collect(tr.Descend), collectG(want.Descend)},
+			{"DescendRange", collect(func(f TreeIterator) { tr.DescendRange(hi, lo, f) }), collectG(func(f btree.ItemIteratorG[int]) { want.DescendRange(hi, lo, f) })},
+			{"DescendLessOrEqual", collect(func(f TreeIterator) { tr.DescendLessOrEqual(hi, f) }), collectG(func(f btree.ItemIteratorG[int]) { want.DescendLessOrEqual(hi, f) })},
+		}
+		for _, c := range checks {
+			if !reflect.DeepEqual(c.got, c.exp) {
+				t.Errorf("degree %d: %s = %v, want %v", degree, c.name, c.got, c.exp)
+			}
+		}
+		if min, _ := tr.Min(); len(checks[0].got) > 0 && min != collect(tr.Ascend)[0] {
+			t.Errorf("degree %d: Min = %d", degree, min)
+		}
+		// The clone was unaffected by the writes after it was made.
+		n := 0
+		clone.Ascend(func(int) bool { n++; return true })
+		if n != clone.Len() {
+			t.Errorf("degree %d: clone has %d items but Len %d", degree, n, clone.Len())
+		}
+	}
+}
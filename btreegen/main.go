@@ -0,0 +1,135 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+// Command btreegen generates a B-Tree specialized to a single item type.
+//
+// The generated tree has the core API of btree.BTreeG (ReplaceOrInsert, Get,
+// Delete, Min, Max, the Ascend and Descend families, and Clone), but stores
+// the named type directly and compares items with an inline expression, so
+// it needs neither generics nor interfaces.  Use it for projects that must
+// build with Go releases older than 1.18, or to get a tree with no indirect
+// calls at all.  For example:
+//
+//	//go:generate go run github.com/google/btree/btreegen -type int64 -name Int64Tree
+//	//go:generate go run github.com/google/btree/btreegen -type *Record -name RecordTree -less "a.ID < b.ID"
+//
+// The flags are:
+//
+//	-type     the item type (required)
+//	-name     the name of the tree type (default: the type's name, capitalized,
+//	          followed by "Tree")
+//	-less     a boolean expression comparing items a and b (default: "a < b")
+//	-package  the package of the generated file (default: $GOPACKAGE)
+//	-import   comma-separated import paths needed by -type or -less
+//	-o        the output file (default: the tree's name, lower-cased, followed
+//	          by "_btree.go")
+//
+// The generated code has no dependencies beyond those given by -import.
+package main
+
+import (
+	"bytes"
+	_ "embed"
+	"flag"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+//go:embed tree.go.tmpl
+var treeTemplate string
+
+var tmpl = template.Must(template.New("tree").Parse(treeTemplate))
+
+// config describes the tree to generate.
+type config struct {
+	Args    string // the flags, for the generated file's header
+	Package string
+	Imports []string
+	Type    string
+	Name    string
+	Node    string // the name of the tree's node type
+	Less    string
+}
+
+// generate returns the formatted source of the tree described by c.
+func generate(c config) ([]byte, error) {
+	if c.Node == "" {
+		r := []rune(c.Name)
+		c.Node = string(unicode.ToLower(r[0])) + string(r[1:]) + "Node"
+	}
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, c); err != nil {
+		return nil, err
+	}
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("generated code doesn't parse (check -type and -less): %v", err)
+	}
+	return src, nil
+}
+
+// defaultName returns the default tree name for items of type typ.
+func defaultName(typ string) string {
+	typ = strings.TrimLeft(typ, "*[]")
+	if i := strings.LastIndex(typ, "."); i >= 0 {
+		typ = typ[i+1:]
+	}
+	r := []rune(typ)
+	if len(r) == 0 {
+		return "Tree"
+	}
+	return string(unicode.ToUpper(r[0])) + string(r[1:]) + "Tree"
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("btreegen: ")
+	c := config{Args: strings.Join(os.Args[1:], " ")}
+	var imports, out string
+	flag.StringVar(&c.Type, "type", "", "the item type")
+	flag.StringVar(&c.Name, "name", "", "the name of the tree type")
+	flag.StringVar(&c.Less, "less", "a < b", "a boolean expression comparing items a and b")
+	flag.StringVar(&c.Package, "package", os.Getenv("GOPACKAGE"), "the package of the generated file")
+	flag.StringVar(&imports, "import", "", "comma-separated import paths")
+	flag.StringVar(&out, "o", "", "the output file")
+	flag.Parse()
+	if c.Type == "" || c.Package == "" || flag.NArg() > 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if c.Name == "" {
+		c.Name = defaultName(c.Type)
+	}
+	if imports != "" {
+		c.Imports = strings.Split(imports, ",")
+	}
+	if out == "" {
+		out = strings.ToLower(c.Name) + "_btree.go"
+	}
+	src, err := generate(c)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(out, src, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,57 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package main
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+func TestGeneratedUpToDate(t *testing.T) {
+	want, err := os.ReadFile("internal/inttree/tree.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := generate(config{Args: "-type int -name Tree -o tree.go", Package: "inttree", Type: "int", Name: "Tree", Less: "a < b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Error("internal/inttree/tree.go is stale; run go generate ./...")
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	if _, err := generate(config{Package: "p", Type: "int", Name: "T", Less: "a <"}); err == nil {
+		t.Error("bad -less expression generated code")
+	}
+}
+
+func TestDefaultName(t *testing.T) {
+	for typ, want := range map[string]string{
+		"int64":         "Int64Tree",
+		"*Record":       "RecordTree",
+		"time.Duration": "DurationTree",
+		"[]byte":        "ByteTree",
+	} {
+		if got := defaultName(typ); got != want {
+			t.Errorf("defaultName(%q) = %q, want %q", typ, got, want)
+		}
+	}
+}
@@ -0,0 +1,334 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+// Package btreetest helps test code that uses btree.BTreeG.
+//
+// It provides a simple reference implementation of the tree's behavior
+// (Model, a sorted slice), generators of random operations, and Run, which
+// applies a sequence of operations to both a real tree and the model and
+// reports the first place they disagree.  Together these make it easy to
+// fuzz a LessFunc and usage pattern:
+//
+//	func FuzzTree(f *testing.F) {
+//		f.Fuzz(func(t *testing.T, data []byte) {
+//			less := func(a, b Key) bool { ... }
+//			ops := btreetest.OpsFromBytes(data, func(b byte) Key { ... })
+//			if err := btreetest.Run(btree.NewG(2, less), less, ops); err != nil {
+//				t.Fatal(err)
+//			}
+//		})
+//	}
+//
+// A LessFunc that isn't a strict weak ordering is the most common cause of
+// disagreement.
+package btreetest
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+
+	"github.com/google/btree"
+)
+
+// Model is a reference implementation of btree.BTreeG's core operations,
+// built on a sorted slice.  It is slow, but simple enough to be obviously
+// correct given a valid LessFunc.
+type Model[T any] struct {
+	items []T
+	less  btree.LessFunc[T]
+}
+
+// NewModel returns an empty model ordered by less.
+func NewModel[T any](less btree.LessFunc[T]) *Model[T] {
+	return &Model[T]{less: less}
+}
+
+// search returns the index of the first item >= key, and whether it equals key.
+func (m *Model[T]) search(key T) (int, bool) {
+	i := sort.Search(len(m.items), func(i int) bool { return !m.less(m.items[i], key) })
+	return i, i < len(m.items) && !m.less(key, m.items[i])
+}
+
+// ReplaceOrInsert behaves like btree.BTreeG.ReplaceOrInsert.
+func (m *Model[T]) ReplaceOrInsert(item T) (_ T, _ bool) {
+	i, found := m.search(item)
+	if found {
+		old := m.items[i]
+		m.items[i] = item
+		return old, true
+	}
+	var zero T
+	m.items = append(m.items, zero)
+	copy(m.items[i+1:], m.items[i:])
+	m.items[i] = item
+	return
+}
+
+// Delete behaves like btree.BTreeG.Delete.
+func (m *Model[T]) Delete(item T) (_ T, _ bool) {
+	i, found := m.search(item)
+	if !found {
+		return
+	}
+	return m.removeAt(i), true
+}
+
+// DeleteMin behaves like btree.BTreeG.DeleteMin.
+func (m *Model[T]) DeleteMin() (_ T, _ bool) {
+	if len(m.items) == 0 {
+		return
+	}
+	return m.removeAt(0), true
+}
+
+// DeleteMax behaves like btree.BTreeG.DeleteMax.
+func (m *Model[T]) DeleteMax() (_ T, _ bool) {
+	if len(m.items) == 0 {
+		return
+	}
+	return m.removeAt(len(m.items) - 1), true
+}
+
+func (m *Model[T]) removeAt(i int) T {
+	out := m.items[i]
+	m.items = append(m.items[:i], m.items[i+1:]...)
+	return out
+}
+
+// Get behaves like btree.BTreeG.Get.
+func (m *Model[T]) Get(key T) (_ T, _ bool) {
+	i, found := m.search(key)
+	if !found {
+		return
+	}
+	return m.items[i], true
+}
+
+// Min behaves like btree.BTreeG.Min.
+func (m *Model[T]) Min() (_ T, _ bool) {
+	if len(m.items) == 0 {
+		return
+	}
+	return m.items[0], true
+}
+
+// Max behaves like btree.BTreeG.Max.
+func (m *Model[T]) Max() (_ T, _ bool) {
+	if len(m.items) == 0 {
+		return
+	}
+	return m.items[len(m.items)-1], true
+}
+
+// Len behaves like btree.BTreeG.Len.
+func (m *Model[T]) Len() int {
+	return len(m.items)
+}
+
+// Range returns the items in [greaterOrEqual, lessThan), like
+// btree.BTreeG.AscendRange.
+func (m *Model[T]) Range(greaterOrEqual, lessThan T) []T {
+	i, _ := m.search(greaterOrEqual)
+	j, _ := m.search(lessThan)
+	if i >= j {
+		return nil
+	}
+	return append([]T(nil), m.items[i:j]...)
+}
+
+// Items returns all items in the model, in order.
+func (m *Model[T]) Items() []T {
+	return append([]T(nil), m.items...)
+}
+
+// OpKind is the kind of an Op.
+type OpKind int
+
+const (
+	OpReplaceOrInsert OpKind = iota
+	OpDelete
+	OpDeleteMin
+	OpDeleteMax
+	OpGet
+	OpAscendRange
+	OpClone // replaces the tree with a clone, and writes to the original
+	numOpKinds
+)
+
+var opNames = [...]string{"ReplaceOrInsert", "Delete", "DeleteMin", "DeleteMax", "Get", "AscendRange", "Clone"}
+
+func (k OpKind) String() string {
+	if k < 0 || k >= numOpKinds {
+		return fmt.Sprintf("OpKind(%d)", int(k))
+	}
+	return opNames[k]
+}
+
+// Op is a single operation on a tree.  Item is the argument of
+// ReplaceOrInsert, Delete, and Get, and the lower bound of AscendRange; Hi
+// is the upper bound of AscendRange.
+type Op[T any] struct {
+	Kind     OpKind
+	Item, Hi T
+}
+
+func (o Op[T]) String() string {
+	switch o.Kind {
+	case OpReplaceOrInsert, OpDelete, OpGet:
+		return fmt.Sprintf("%v(%v)", o.Kind, o.Item)
+	case OpAscendRange:
+		return fmt.Sprintf("%v(%v, %v)", o.Kind, o.Item, o.Hi)
+	}
+	return fmt.Sprintf("%v()", o.Kind)
+}
+
+// RandomOps returns n random operations, with arguments drawn from item.
+// Inserts are generated more often than deletes, so the tree tends to grow.
+func RandomOps[T any](r *rand.Rand, n int, item func(*rand.Rand) T) []Op[T] {
+	ops := make([]Op[T], n)
+	for i := range ops {
+		var kind OpKind
+		switch x := r.Intn(100); {
+		case x < 45:
+			kind = OpReplaceOrInsert
+		case x < 70:
+			kind = OpDelete
+		case x < 75:
+			kind = OpDeleteMin
+		case x < 80:
+			kind = OpDeleteMax
+		case x < 90:
+			kind = OpGet
+		case x < 99:
+			kind = OpAscendRange
+		default:
+			kind = OpClone
+		}
+		ops[i] = Op[T]{Kind: kind, Item: item(r), Hi: item(r)}
+	}
+	return ops
+}
+
+// OpsFromBytes decodes fuzzer-provided data into operations, two bytes per
+// operation: one selecting the kind, and one converted to the argument with
+// item.  AscendRange's upper bound is the next operation's argument.
+func OpsFromBytes[T any](data []byte, item func(byte) T) []Op[T] {
+	var ops []Op[T]
+	for i := 0; i+1 < len(data); i += 2 {
+		op := Op[T]{Kind: OpKind(data[i]) % numOpKinds, Item: item(data[i+1])}
+		if i+3 < len(data) {
+			op.Hi = item(data[i+3])
+		}
+		ops = append(ops, op)
+	}
+	return ops
+}
+
+// Run applies ops to both tr and a Model ordered by less, which should be
+// the LessFunc tr was created with and tr should start out empty.  It
+// returns an error describing the first operation whose result differs
+// between the two, or after which the tree fails btree.BTreeG.Verify or its
+// contents differ from the model's.  Since the whole tree is checked after
+// every operation, Run is meant for sequences of at most a few thousand
+// operations.
+func Run[T any](tr *btree.BTreeG[T], less btree.LessFunc[T], ops []Op[T]) error {
+	m := NewModel(less)
+	for i, op := range ops {
+		var got, want []T
+		var gotOK, wantOK bool
+		one := func(item T) []T { return []T{item} }
+		switch op.Kind {
+		case OpReplaceOrInsert:
+			g, gok := tr.ReplaceOrInsert(op.Item)
+			w, wok := m.ReplaceOrInsert(op.Item)
+			got, gotOK, want, wantOK = one(g), gok, one(w), wok
+		case OpDelete:
+			g, gok := tr.Delete(op.Item)
+			w, wok := m.Delete(op.Item)
+			got, gotOK, want, wantOK = one(g), gok, one(w), wok
+		case OpDeleteMin:
+			g, gok := tr.DeleteMin()
+			w, wok := m.DeleteMin()
+			got, gotOK, want, wantOK = one(g), gok, one(w), wok
+		case OpDeleteMax:
+			g, gok := tr.DeleteMax()
+			w, wok := m.DeleteMax()
+			got, gotOK, want, wantOK = one(g), gok, one(w), wok
+		case OpGet:
+			g, gok := tr.Get(op.Item)
+			w, wok := m.Get(op.Item)
+			got, gotOK, want, wantOK = one(g), gok, one(w), wok
+		case OpAscendRange:
+			tr.AscendRange(op.Item, op.Hi, func(item T) bool {
+				got = append(got, item)
+				return true
+			})
+			want = m.Range(op.Item, op.Hi)
+		case OpClone:
+			// Keep using the clone, and make sure writes to the original
+			// don't leak into it.
+			orig := tr
+			tr = tr.Clone()
+			orig.Clear(true)
+		default:
+			return fmt.Errorf("op %d: unknown kind %v", i, op.Kind)
+		}
+		if gotOK != wantOK || !equal(less, got, want) {
+			return fmt.Errorf("op %d, %v: tree returned %v, %v; model returned %v, %v", i, op, got, gotOK, want, wantOK)
+		}
+		if err := Compare(tr, m); err != nil {
+			return fmt.Errorf("op %d, %v: %v", i, op, err)
+		}
+	}
+	return nil
+}
+
+// Compare returns an error if tr is not internally consistent or its
+// contents differ from m's.
+func Compare[T any](tr *btree.BTreeG[T], m *Model[T]) error {
+	if err := tr.Verify(); err != nil {
+		return err
+	}
+	if tr.Len() != m.Len() {
+		return fmt.Errorf("tree has %d items, model has %d", tr.Len(), m.Len())
+	}
+	i := 0
+	var err error
+	tr.Ascend(func(item T) bool {
+		if m.less(item, m.items[i]) || m.less(m.items[i], item) {
+			err = fmt.Errorf("item %d is %v in the tree but %v in the model", i, item, m.items[i])
+			return false
+		}
+		i++
+		return true
+	})
+	return err
+}
+
+// equal reports whether a and b hold equivalent items.
+func equal[T any](less btree.LessFunc[T], a, b []T) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if less(a[i], b[i]) || less(b[i], a[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,58 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btreetest
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/google/btree"
+)
+
+func TestRunRandom(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	less := btree.Less[int]()
+	for _, degree := range []int{2, 3, 8} {
+		ops := RandomOps(r, 2000, func(r *rand.Rand) int { return r.Intn(500) })
+		if err := Run(btree.NewG(degree, less), less, ops); err != nil {
+			t.Errorf("degree %d: %v", degree, err)
+		}
+	}
+}
+
+func TestRunFindsBadLess(t *testing.T) {
+	// '<=' isn't a strict ordering, so the tree can't find items equal to
+	// ones it holds, and stores duplicates.
+	less := func(a, b int) bool { return a <= b }
+	r := rand.New(rand.NewSource(1))
+	ops := RandomOps(r, 1000, func(r *rand.Rand) int { return r.Intn(50) })
+	if err := Run(btree.NewG(2, less), less, ops); err == nil {
+		t.Error("Run didn't notice a non-strict LessFunc")
+	}
+}
+
+func FuzzRun(f *testing.F) {
+	f.Add([]byte{0, 1, 0, 2, 0, 3, 1, 2, 5, 0, 6, 0})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		less := btree.Less[int]()
+		ops := OpsFromBytes(data, func(b byte) int { return int(b) })
+		if err := Run(btree.NewG(2, less), less, ops); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
@@ -0,0 +1,81 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+import "sort"
+
+// ByKeyG is a BTreeG of items ordered by a key extracted from each item, so
+// that callers with struct items needn't write a LessFunc, and can look
+// items up by key without building a dummy item.
+type ByKeyG[T any, K Ordered] struct {
+	*BTreeG[T]
+	key func(T) K
+}
+
+// NewByKeyG creates a new B-Tree with the given degree, ordering items by
+// the key that key extracts from them.  Items with equal keys are equal, so
+// the tree holds at most one item per key.
+func NewByKeyG[T any, K Ordered](degree int, key func(T) K) *ByKeyG[T, K] {
+	less := func(a, b T) bool { return key(a) < key(b) }
+	return &ByKeyG[T, K]{BTreeG: NewG(degree, less), key: key}
+}
+
+// Clone clones the tree lazily, like BTreeG.Clone.
+func (t *ByKeyG[T, K]) Clone() *ByKeyG[T, K] {
+	return &ByKeyG[T, K]{BTreeG: t.BTreeG.Clone(), key: t.key}
+}
+
+// findKey returns the index of the first item in n whose key is not less
+// than k, and whether its key is k.
+func (t *ByKeyG[T, K]) findKey(n *node[T], k K) (int, bool) {
+	i := sort.Search(len(n.items), func(i int) bool {
+		return !(t.key(n.items[i]) < k)
+	})
+	return i, i < len(n.items) && t.key(n.items[i]) == k
+}
+
+// GetByKey returns the item with key k, or (zeroValue, false) if there is
+// none.
+func (t *ByKeyG[T, K]) GetByKey(k K) (_ T, _ bool) {
+	for n := t.root; n != nil; {
+		i, found := t.findKey(n, k)
+		if found {
+			return n.items[i], true
+		}
+		if len(n.children) == 0 {
+			break
+		}
+		n = n.children[i]
+	}
+	return
+}
+
+// HasByKey returns true if the tree holds an item with key k.
+func (t *ByKeyG[T, K]) HasByKey(k K) bool {
+	_, ok := t.GetByKey(k)
+	return ok
+}
+
+// DeleteByKey removes the item with key k, returning it.
+func (t *ByKeyG[T, K]) DeleteByKey(k K) (_ T, _ bool) {
+	item, ok := t.GetByKey(k)
+	if !ok {
+		return
+	}
+	return t.Delete(item)
+}
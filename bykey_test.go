@@ -0,0 +1,49 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestByKey(t *testing.T) {
+	type user struct {
+		id   int
+		name string
+	}
+	tr := NewByKeyG(*btreeDegree, func(u user) int { return u.id })
+	for _, id := range rand.Perm(500) {
+		tr.ReplaceOrInsert(user{id * 2, "x"})
+	}
+	for id := -1; id < 1001; id++ {
+		u, ok := tr.GetByKey(id)
+		if want := id%2 == 0 && id >= 0 && id < 1000; ok != want || (ok && u.id != id) {
+			t.Fatalf("GetByKey(%d) = %v, %v", id, u, ok)
+		}
+	}
+	if u, ok := tr.DeleteByKey(10); !ok || u.id != 10 || tr.HasByKey(10) || tr.Len() != 499 {
+		t.Errorf("DeleteByKey(10) = %v, %v", u, ok)
+	}
+	if _, ok := tr.DeleteByKey(11); ok {
+		t.Errorf("deleted a missing key")
+	}
+	if old, ok := tr.ReplaceOrInsert(user{12, "y"}); !ok || old.name != "x" {
+		t.Errorf("items with equal keys aren't equal")
+	}
+}
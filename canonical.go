@@ -0,0 +1,70 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+// Canonicalize gives the tree its canonical shape: the shape Rebuild
+// produces, which depends only on the tree's degree and number of items.
+// Two trees with the same degree and items then have identical structure,
+// node for node, whatever sequence of writes produced them, so hashing
+// their nodes (through Hooks or Golden, say) gives equal results.
+//
+// If the tree already has its canonical shape, Canonicalize leaves it alone,
+// so its nodes stay shared with any clones, and returns false.  This check
+// takes time proportional to the number of nodes.  Otherwise it rebuilds the
+// tree, as Rebuild does, and returns true.
+func (t *BTreeG[T]) Canonicalize() bool {
+	if t.root == nil || isCanonical(t.root, t.length, t.maxItems()) {
+		return false
+	}
+	t.Rebuild()
+	return true
+}
+
+// isCanonical reports whether the subtree n, holding count items, has the
+// shape buildSorted would give it.
+func isCanonical[T any](n *node[T], count, maxItems int) bool {
+	below := 0
+	for capacity := maxItems; capacity < count; capacity = capacity*(maxItems+1) + maxItems {
+		below = capacity
+	}
+	return isCanonicalLevel(n, count, below, maxItems)
+}
+
+// isCanonicalLevel mirrors buildLevel, reporting whether n has the shape it
+// would build for count items.
+func isCanonicalLevel[T any](n *node[T], count, below, maxItems int) bool {
+	if below == 0 {
+		return len(n.children) == 0 && len(n.items) == count
+	}
+	children := (count + below + 1) / (below + 1)
+	if len(n.children) != children || len(n.items) != children-1 {
+		return false
+	}
+	perChild, extra := (count-(children-1))/children, (count-(children-1))%children
+	next := (below+1)/(maxItems+1) - 1
+	for i, c := range n.children {
+		size := perChild
+		if i < extra {
+			size++
+		}
+		if !isCanonicalLevel(c, size, next, maxItems) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,60 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+import (
+	"math/rand"
+	"strconv"
+	"testing"
+)
+
+func TestCanonicalize(t *testing.T) {
+	for _, degree := range []int{2, 3, 8} {
+		// Build the same set of items in two different ways.
+		a, b := NewOrderedG[int](degree), NewOrderedG[int](degree)
+		for _, i := range rand.Perm(1000) {
+			a.ReplaceOrInsert(i)
+		}
+		for i := 0; i < 2000; i++ {
+			b.ReplaceOrInsert(i)
+		}
+		for i := 1000; i < 2000; i++ {
+			b.Delete(i)
+		}
+		if !a.Canonicalize() || !b.Canonicalize() {
+			t.Fatalf("degree %d: Canonicalize didn't rebuild irregular trees", degree)
+		}
+		ga, gb := a.Golden(strconv.Itoa), b.Golden(strconv.Itoa)
+		if ga != gb {
+			t.Errorf("degree %d: canonical trees differ:\n%s\n%s", degree, ga, gb)
+		}
+		if err := a.Verify(); err != nil {
+			t.Error(err)
+		}
+
+		// A canonical tree is left alone, keeping the nodes it shares.
+		c := a.Clone()
+		root := c.root
+		if c.Canonicalize() || c.root != root {
+			t.Errorf("degree %d: Canonicalize rebuilt a canonical tree", degree)
+		}
+	}
+	if NewOrderedG[int](2).Canonicalize() {
+		t.Error("Canonicalize rebuilt an empty tree")
+	}
+}
@@ -0,0 +1,156 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+// Package cmpfuncs builds btree.LessFuncs from smaller pieces.
+//
+// A tree is only as correct as its LessFunc, which must be a strict weak
+// ordering, and hand-written comparisons of several fields are the most
+// common way to get that wrong: the classic mistake is
+//
+//	return a.X < b.X || a.Y < b.Y
+//
+// which says both (1,2) < (2,1) and (2,1) < (1,2).  Chain builds the correct
+// version, and Float makes floating-point keys safe in the presence of NaN.
+// Time, Addr, Prefix and UUID order common key types that have pitfalls of
+// their own.
+package cmpfuncs
+
+import (
+	"bytes"
+	"net/netip"
+	"time"
+
+	"github.com/google/btree"
+)
+
+// Chain returns a LessFunc ordering by each of less in turn: by the first,
+// then, among items it considers equal, by the second, and so on.
+func Chain[T any](less ...btree.LessFunc[T]) btree.LessFunc[T] {
+	return func(a, b T) bool {
+		for _, l := range less {
+			if l(a, b) {
+				return true
+			}
+			if l(b, a) {
+				return false
+			}
+		}
+		return false
+	}
+}
+
+// Reversed returns a LessFunc giving the opposite order to less.
+func Reversed[T any](less btree.LessFunc[T]) btree.LessFunc[T] {
+	return func(a, b T) bool { return less(b, a) }
+}
+
+// ByField returns a LessFunc ordering items by the field, or other key, that
+// field extracts from them.  Use it with Chain for multi-field orderings:
+//
+//	cmpfuncs.Chain(
+//		cmpfuncs.ByField(func(e Event) string { return e.User }),
+//		cmpfuncs.Reversed(cmpfuncs.ByField(func(e Event) int64 { return e.Time })),
+//	)
+//
+// For floating-point fields, use ByFloatField instead.
+func ByField[T any, K btree.Ordered](field func(T) K) btree.LessFunc[T] {
+	return func(a, b T) bool { return field(a) < field(b) }
+}
+
+// Float is a LessFunc for floating-point numbers that is a strict weak
+// ordering even when NaNs are present: NaNs are equal to each other and
+// less than every other value, including negative infinity.  (With <, NaN is
+// neither less than nor greater than anything, which makes it "equal" to
+// every number, and corrupts trees.)  -0 and +0 are equal.
+func Float[F ~float32 | ~float64](a, b F) bool {
+	if a != a { // a is NaN
+		return b == b
+	}
+	return a < b
+}
+
+// ByFloatField is like ByField for floating-point fields, ordered by Float.
+func ByFloatField[T any, F ~float32 | ~float64](field func(T) F) btree.LessFunc[T] {
+	return func(a, b T) bool { return Float(field(a), field(b)) }
+}
+
+// Bool is a LessFunc ordering false before true.
+func Bool(a, b bool) bool {
+	return !a && b
+}
+
+// Slice returns a LessFunc ordering slices lexicographically by less, with a
+// slice that is a prefix of another ordering first.
+func Slice[E any](less btree.LessFunc[E]) btree.LessFunc[[]E] {
+	return func(a, b []E) bool {
+		for i := 0; i < len(a) && i < len(b); i++ {
+			if less(a[i], b[i]) {
+				return true
+			}
+			if less(b[i], a[i]) {
+				return false
+			}
+		}
+		return len(a) < len(b)
+	}
+}
+
+// Time is a LessFunc ordering times by the instant they represent.  It
+// ignores monotonic clock readings: Before compares those when both times
+// have one and wall clock readings otherwise, so a mix of times from
+// time.Now and from elsewhere may not be consistently ordered by Before if
+// the wall clock has been stepped.  Time compares wall clock readings only.
+// Times in different locations are equal if they are the same instant, as
+// with Equal (and unlike ==).
+func Time(a, b time.Time) bool {
+	return a.Round(0).Before(b.Round(0))
+}
+
+// Addr is a LessFunc for IP addresses, ordering the zero Addr first, then
+// IPv4 addresses, then IPv6 addresses, each numerically, and then by zone.
+// An IPv4 address and the IPv4-mapped IPv6 address with the same value are
+// distinct; use Unmap on keys first to treat them as the same.
+func Addr(a, b netip.Addr) bool {
+	return a.Less(b)
+}
+
+// Prefix is a LessFunc for IP prefixes, ordering them by Addr on their
+// masked addresses, then shorter prefixes first, so a prefix comes right
+// before the more specific prefixes it contains.  Prefixes whose addresses
+// have host bits set (such as 10.0.0.1/8) come after the same prefix
+// without, ordered by Addr; use Masked on keys first to treat them as the
+// same.  Invalid prefixes come first.
+func Prefix(a, b netip.Prefix) bool {
+	am, bm := a.Masked().Addr(), b.Masked().Addr()
+	switch {
+	case am.Less(bm):
+		return true
+	case bm.Less(am):
+		return false
+	case a.Bits() != b.Bits():
+		return a.Bits() < b.Bits()
+	}
+	return a.Addr().Less(b.Addr())
+}
+
+// UUID is a LessFunc for UUIDs, or any other 16-byte identifiers, ordering
+// them bytewise, as their string forms sort.  This matches creation order
+// for version 7 UUIDs but not for version 1 UUIDs, whose timestamp's low
+// bits come first, nor for random version 4 UUIDs.
+func UUID[U ~[16]byte](a, b U) bool {
+	return bytes.Compare(a[:], b[:]) < 0
+}
@@ -0,0 +1,129 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package cmpfuncs
+
+import (
+	"math"
+	"math/rand"
+	"net/netip"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/btree"
+	"github.com/google/btree/btreetest"
+)
+
+type point struct {
+	x, y int
+}
+
+func TestChain(t *testing.T) {
+	less := Chain(
+		ByField(func(p point) int { return p.x }),
+		Reversed(ByField(func(p point) int { return p.y })),
+	)
+	tr := btree.NewG(2, less)
+	for _, p := range []point{{2, 1}, {1, 2}, {1, 3}, {2, 2}, {1, 2}} {
+		tr.ReplaceOrInsert(p)
+	}
+	var got []point
+	tr.Ascend(func(p point) bool {
+		got = append(got, p)
+		return true
+	})
+	if want := []point{{1, 3}, {1, 2}, {2, 2}, {2, 1}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestFloatWithNaN(t *testing.T) {
+	values := []float64{math.NaN(), math.Inf(-1), -1, 0, math.Copysign(0, -1), 1, math.Inf(1), math.NaN()}
+	r := rand.New(rand.NewSource(1))
+	ops := btreetest.RandomOps(r, 2000, func(r *rand.Rand) float64 { return values[r.Intn(len(values))] })
+	if err := btreetest.Run(btree.NewG(2, Float[float64]), Float[float64], ops); err != nil {
+		t.Error(err)
+	}
+	if !Float(math.NaN(), math.Inf(-1)) || Float(math.NaN(), math.NaN()) || Float(1, math.NaN()) {
+		t.Error("NaN misordered")
+	}
+}
+
+func TestSlice(t *testing.T) {
+	less := Slice(btree.Less[int]())
+	for _, c := range []struct {
+		a, b []int
+		want bool
+	}{
+		{[]int{1, 2}, []int{1, 3}, true},
+		{[]int{1}, []int{1, 0}, true},
+		{[]int{1, 0}, []int{1}, false},
+		{nil, nil, false},
+		{[]int{2}, []int{1, 9}, false},
+	} {
+		if got := less(c.a, c.b); got != c.want {
+			t.Errorf("less(%v, %v) = %v", c.a, c.b, got)
+		}
+	}
+	if !Bool(false, true) || Bool(true, false) || Bool(true, true) {
+		t.Error("Bool misordered")
+	}
+}
+
+// checkSorted checks that less orders values, which are in strictly
+// ascending order.
+func checkSorted[T any](t *testing.T, less btree.LessFunc[T], values ...T) {
+	t.Helper()
+	for i := range values {
+		for j := range values {
+			if got := less(values[i], values[j]); got != (i < j) {
+				t.Errorf("less(%v, %v) = %v", values[i], values[j], got)
+			}
+		}
+	}
+}
+
+func TestTime(t *testing.T) {
+	now := time.Now()
+	wall := now.Round(0)
+	if Time(now, wall) || Time(wall, now) {
+		t.Error("monotonic reading changed the order")
+	}
+	if Time(now, now.In(time.FixedZone("X", 3600))) {
+		t.Error("location changed the order")
+	}
+	checkSorted(t, Time, time.Time{}, now.Add(-time.Nanosecond), now, wall.Add(time.Nanosecond))
+}
+
+func TestNetip(t *testing.T) {
+	checkSorted(t, Addr, netip.Addr{}, netip.MustParseAddr("10.0.0.1"), netip.MustParseAddr("10.0.0.2"), netip.MustParseAddr("::ffff:10.0.0.1"), netip.MustParseAddr("fe80::1"), netip.MustParseAddr("fe80::1%eth0"))
+	checkSorted(t, Prefix,
+		netip.Prefix{},
+		netip.MustParsePrefix("10.0.0.0/8"),
+		netip.MustParsePrefix("10.0.0.1/8"),
+		netip.MustParsePrefix("10.0.0.0/16"),
+		netip.MustParsePrefix("10.0.0.0/24"),
+		netip.MustParsePrefix("10.1.0.0/16"),
+		netip.MustParsePrefix("::/0"),
+		netip.MustParsePrefix("2001:db8::/32"))
+}
+
+func TestUUID(t *testing.T) {
+	type uuid [16]byte
+	checkSorted(t, UUID[uuid], uuid{}, uuid{0, 1}, uuid{1}, uuid{1, 0xff}, uuid{0xff})
+}
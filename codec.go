@@ -0,0 +1,217 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+import (
+	"encoding"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+)
+
+// ItemCodec converts items to and from bytes, for saving trees and logging
+// their changes.  Encodings must be self-delimiting: Decode must be able to
+// tell where an item's encoding ends when it's followed by other data.
+type ItemCodec[T any] interface {
+	// Append appends the encoding of item to dst and returns the extended
+	// buffer.
+	Append(dst []byte, item T) ([]byte, error)
+	// Decode decodes the item at the start of src, returning it and the
+	// length of its encoding.
+	Decode(src []byte) (item T, n int, err error)
+}
+
+// ErrTruncated is returned when decoding data that ends in the middle of an
+// item.
+var ErrTruncated = errors.New("btree: truncated encoding")
+
+// OrderedCodec returns an ItemCodec for integers, floats and strings, and
+// types based on them.  Integers are encoded as varints, floats in the 4 or
+// 8 bytes of their IEEE 754 representation, and strings as their length,
+// as a varint, followed by their bytes.
+func OrderedCodec[T Ordered]() ItemCodec[T] {
+	var zero T
+	return orderedCodec[T]{kind: reflect.ValueOf(zero).Kind()}
+}
+
+type orderedCodec[T Ordered] struct {
+	kind reflect.Kind
+}
+
+func (c orderedCodec[T]) Append(dst []byte, item T) ([]byte, error) {
+	var buf [binary.MaxVarintLen64]byte
+	v := reflect.ValueOf(item)
+	switch c.kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return append(dst, buf[:binary.PutVarint(buf[:], v.Int())]...), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return append(dst, buf[:binary.PutUvarint(buf[:], v.Uint())]...), nil
+	case reflect.Float32:
+		binary.LittleEndian.PutUint32(buf[:], math.Float32bits(float32(v.Float())))
+		return append(dst, buf[:4]...), nil
+	case reflect.Float64:
+		binary.LittleEndian.PutUint64(buf[:], math.Float64bits(v.Float()))
+		return append(dst, buf[:8]...), nil
+	}
+	s := v.String()
+	dst = append(dst, buf[:binary.PutUvarint(buf[:], uint64(len(s)))]...)
+	return append(dst, s...), nil
+}
+
+func (c orderedCodec[T]) Decode(src []byte) (item T, n int, err error) {
+	v := reflect.ValueOf(&item).Elem()
+	switch c.kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		x, n := binary.Varint(src)
+		if n <= 0 {
+			return item, 0, varintError(n)
+		}
+		if v.OverflowInt(x) {
+			return item, 0, fmt.Errorf("btree: %v overflows %v", x, v.Type())
+		}
+		v.SetInt(x)
+		return item, n, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		x, n := binary.Uvarint(src)
+		if n <= 0 {
+			return item, 0, varintError(n)
+		}
+		if v.OverflowUint(x) {
+			return item, 0, fmt.Errorf("btree: %v overflows %v", x, v.Type())
+		}
+		v.SetUint(x)
+		return item, n, nil
+	case reflect.Float32:
+		if len(src) < 4 {
+			return item, 0, ErrTruncated
+		}
+		v.SetFloat(float64(math.Float32frombits(binary.LittleEndian.Uint32(src))))
+		return item, 4, nil
+	case reflect.Float64:
+		if len(src) < 8 {
+			return item, 0, ErrTruncated
+		}
+		v.SetFloat(math.Float64frombits(binary.LittleEndian.Uint64(src)))
+		return item, 8, nil
+	}
+	b, n, err := decodeBytes(src)
+	if err != nil {
+		return item, 0, err
+	}
+	v.SetString(string(b))
+	return item, n, nil
+}
+
+// varintError returns the error for a failed varint decode that returned n.
+func varintError(n int) error {
+	if n == 0 {
+		return ErrTruncated
+	}
+	return errors.New("btree: varint overflows 64 bits")
+}
+
+// decodeBytes decodes a length-prefixed byte string from the start of src,
+// returning it (without copying) and the length of its encoding.
+func decodeBytes(src []byte) ([]byte, int, error) {
+	size, n := binary.Uvarint(src)
+	if n <= 0 {
+		return nil, 0, varintError(n)
+	}
+	if size > uint64(len(src)-n) {
+		return nil, 0, ErrTruncated
+	}
+	end := n + int(size)
+	return src[n:end], end, nil
+}
+
+// MarshalerCodec returns an ItemCodec for items of type *T whose methods
+// implement encoding.BinaryMarshaler and encoding.BinaryUnmarshaler.  Each
+// item is encoded as the length of its marshaled form, as a varint,
+// followed by the marshaled form.  For example:
+//
+//	codec := btree.MarshalerCodec[Record]() // an ItemCodec[*Record]
+func MarshalerCodec[T any, PT interface {
+	*T
+	encoding.BinaryMarshaler
+	encoding.BinaryUnmarshaler
+}]() ItemCodec[PT] {
+	return marshalerCodec[T, PT]{}
+}
+
+type marshalerCodec[T any, PT interface {
+	*T
+	encoding.BinaryMarshaler
+	encoding.BinaryUnmarshaler
+}] struct{}
+
+func (marshalerCodec[T, PT]) Append(dst []byte, item PT) ([]byte, error) {
+	b, err := item.MarshalBinary()
+	if err != nil {
+		return dst, err
+	}
+	var buf [binary.MaxVarintLen64]byte
+	dst = append(dst, buf[:binary.PutUvarint(buf[:], uint64(len(b)))]...)
+	return append(dst, b...), nil
+}
+
+func (marshalerCodec[T, PT]) Decode(src []byte) (PT, int, error) {
+	b, n, err := decodeBytes(src)
+	if err != nil {
+		return nil, 0, err
+	}
+	item := PT(new(T))
+	if err := item.UnmarshalBinary(b); err != nil {
+		return nil, 0, err
+	}
+	return item, n, nil
+}
+
+// AppendItems appends the tree's item count, as a varint, and the encoding
+// of each of its items, in order, to dst, returning the extended buffer.
+// ReadItems reverses it.
+func (t *BTreeG[T]) AppendItems(dst []byte, codec ItemCodec[T]) (_ []byte, err error) {
+	var buf [binary.MaxVarintLen64]byte
+	dst = append(dst, buf[:binary.PutUvarint(buf[:], uint64(t.length))]...)
+	t.ascend(func(item T) bool {
+		dst, err = codec.Append(dst, item)
+		return err == nil
+	})
+	return dst, err
+}
+
+// ReadItems decodes items written by AppendItems from the start of src and
+// adds them to the tree, returning the length of their encoding.  If it
+// returns an error, the items decoded before the error have been added.
+func (t *BTreeG[T]) ReadItems(src []byte, codec ItemCodec[T]) (int, error) {
+	count, n := binary.Uvarint(src)
+	if n <= 0 {
+		return 0, varintError(n)
+	}
+	for ; count > 0; count-- {
+		item, size, err := codec.Decode(src[n:])
+		if err != nil {
+			return n, err
+		}
+		n += size
+		// The items are usually in order, so AppendMax avoids searching.
+		t.AppendMax(item)
+	}
+	return n, nil
+}
@@ -0,0 +1,121 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func roundTrip[T Ordered](t *testing.T, items ...T) {
+	t.Helper()
+	codec := OrderedCodec[T]()
+	var buf []byte
+	for _, item := range items {
+		var err error
+		if buf, err = codec.Append(buf, item); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var got []T
+	for len(buf) > 0 {
+		item, n, err := codec.Decode(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, buf = append(got, item), buf[n:]
+	}
+	if !reflect.DeepEqual(got, items) {
+		t.Errorf("decoded %v, want %v", got, items)
+	}
+}
+
+type codecID int16
+
+func TestOrderedCodec(t *testing.T) {
+	roundTrip(t, 0, -1, 1, math.MaxInt64, math.MinInt64)
+	roundTrip[uint8](t, 0, 1, 255)
+	roundTrip[codecID](t, -300, 7)
+	roundTrip(t, 1.5, math.Inf(-1), -0.25)
+	roundTrip[float32](t, 3.25, -1)
+	roundTrip(t, "", "a", "hello, world")
+
+	if _, _, err := OrderedCodec[string]().Decode([]byte{5, 'a'}); err != ErrTruncated {
+		t.Errorf("decoding a truncated string: got %v, want ErrTruncated", err)
+	}
+	if _, _, err := OrderedCodec[int8]().Decode([]byte{0x80, 0x04}); err == nil {
+		t.Error("decoding 256 as an int8 succeeded")
+	}
+}
+
+type codecRecord struct {
+	key  string
+	fail bool
+}
+
+func (r *codecRecord) MarshalBinary() ([]byte, error) {
+	if r.fail {
+		return nil, errors.New("can't marshal")
+	}
+	return []byte(r.key), nil
+}
+
+func (r *codecRecord) UnmarshalBinary(b []byte) error {
+	r.key = string(b)
+	return nil
+}
+
+func TestItemsRoundTrip(t *testing.T) {
+	less := func(a, b *codecRecord) bool { return a.key < b.key }
+	tr := NewG(*btreeDegree, less)
+	for _, k := range []string{"pear", "apple", "fig", ""} {
+		tr.ReplaceOrInsert(&codecRecord{key: k})
+	}
+	codec := MarshalerCodec[codecRecord]()
+	buf, err := tr.AppendItems([]byte("header"), codec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf = append(buf, "trailer"...)
+	got := NewG(*btreeDegree, less)
+	n, err := got.ReadItems(buf[len("header"):], codec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rest := string(buf[len("header")+n:]); rest != "trailer" {
+		t.Errorf("ReadItems left %q, want %q", rest, "trailer")
+	}
+	var keys []string
+	got.Ascend(func(r *codecRecord) bool {
+		keys = append(keys, r.key)
+		return true
+	})
+	if want := []string{"", "apple", "fig", "pear"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("read %q, want %q", keys, want)
+	}
+	if _, err := got.ReadItems(buf[len("header"):len(buf)-len("trailer")-2], codec); err != ErrTruncated {
+		t.Errorf("reading truncated items: got %v, want ErrTruncated", err)
+	}
+
+	tr.ReplaceOrInsert(&codecRecord{key: "bad", fail: true})
+	if _, err := tr.AppendItems(nil, codec); err == nil {
+		t.Error("AppendItems ignored a marshaling error")
+	}
+}
@@ -0,0 +1,45 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+// EqualG reports whether a and b contain equal items in the same order.
+// Items are compared with eq; if eq is nil, two items are equal if neither
+// is less than the other according to a's LessFunc.
+//
+// EqualG walks both trees in step with cursors, so it takes O(n) time,
+// stops at the first difference, and doesn't copy the trees' contents.
+// Trees that still share their root through Clone compare equal in O(1).
+func EqualG[T any](a, b *BTreeG[T], eq func(x, y T) bool) bool {
+	if a.Len() != b.Len() {
+		return false
+	}
+	if a.root == b.root {
+		return true
+	}
+	if eq == nil {
+		less := a.cow.less
+		eq = func(x, y T) bool { return !less(x, y) && !less(y, x) }
+	}
+	ca, cb := a.Cursor(), b.Cursor()
+	for ok := ca.First() && cb.First(); ok; ok = ca.Next() && cb.Next() {
+		if !eq(ca.Item(), cb.Item()) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,52 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestEqualG(t *testing.T) {
+	a, b := NewOrderedG[int](2), NewOrderedG[int](*btreeDegree)
+	if !EqualG(a, b, nil) {
+		t.Error("empty trees differ")
+	}
+	for _, v := range rand.Perm(1000) {
+		a.ReplaceOrInsert(v)
+	}
+	for _, v := range rand.Perm(1000) {
+		b.ReplaceOrInsert(v)
+	}
+	// Different degrees give different shapes, but the same contents.
+	if !EqualG(a, b, nil) || !EqualG(a, b, func(x, y int) bool { return x == y }) {
+		t.Error("equal trees differ")
+	}
+	c := a.Clone()
+	if !EqualG(a, c, nil) {
+		t.Error("clone differs")
+	}
+	c.Delete(500)
+	c.ReplaceOrInsert(1000)
+	if EqualG(a, c, nil) || EqualG(c, a, nil) {
+		t.Error("trees with one different item are equal")
+	}
+	if EqualG(a, b, func(x, y int) bool { return x == y && x != 999 }) {
+		t.Error("eq ignored")
+	}
+}
@@ -0,0 +1,110 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+import "sync/atomic"
+
+// ComparisonStats counts the calls a tree created with
+// WithComparisonCounting has made to its LessFunc, as reported by Stats.
+type ComparisonStats struct {
+	// Total is the number of comparisons made by all operations.
+	Total uint64
+	// Inserts counts the comparisons made by ReplaceOrInsert and AppendMax,
+	// Deletes those made by Delete, DeleteMin and DeleteMax, and Lookups
+	// those made by Get and Has.  Iteration and bulk operations are only
+	// included in Total.
+	Inserts, Deletes, Lookups OpComparisons
+}
+
+// OpComparisons counts the comparisons made by one kind of operation.
+type OpComparisons struct {
+	// Ops is the number of operations, and Comparisons the number of
+	// comparisons they made.
+	Ops, Comparisons uint64
+}
+
+// PerOp returns the average number of comparisons per operation, or 0 if
+// there have been no operations.
+func (c OpComparisons) PerOp() float64 {
+	if c.Ops == 0 {
+		return 0
+	}
+	return float64(c.Comparisons) / float64(c.Ops)
+}
+
+// WithComparisonCounting makes the tree count the calls it makes to its
+// LessFunc, reporting them in the Comparisons field of Stats.  Use it to
+// measure what comparisons really cost on your own items, when tuning the
+// degree or choosing between orderings.  The counts are shared with clones
+// of the tree.
+//
+// Comparisons are attributed to an operation by reading the total before
+// and after it, so the breakdown by operation is only approximate while
+// several goroutines read the tree at once; Total is always exact.
+// Interpolation search (see WithInterpolationSearch) also compares items
+// with the LessFunc, so its comparisons are counted too.
+func WithComparisonCounting[T any]() Option[T] {
+	return func(o *options[T]) { o.countComparisons = true }
+}
+
+// comparisonCounter holds the counts behind ComparisonStats.  Its fields
+// are only accessed atomically.
+type comparisonCounter struct {
+	total                     uint64
+	inserts, deletes, lookups opCounter
+}
+
+type opCounter struct {
+	ops, comparisons uint64
+}
+
+// countingLess wraps less so that it counts its calls in c.
+func countingLess[T any](less LessFunc[T], c *comparisonCounter) LessFunc[T] {
+	return func(a, b T) bool {
+		atomic.AddUint64(&c.total, 1)
+		return less(a, b)
+	}
+}
+
+// start returns the current total, to pass to op.done when the operation
+// finishes.
+func (c *comparisonCounter) start() uint64 {
+	return atomic.LoadUint64(&c.total)
+}
+
+// done records an operation that began when the total was start.
+func (op *opCounter) done(c *comparisonCounter, start uint64) {
+	atomic.AddUint64(&op.ops, 1)
+	atomic.AddUint64(&op.comparisons, c.start()-start)
+}
+
+func (op *opCounter) load() OpComparisons {
+	return OpComparisons{
+		Ops:         atomic.LoadUint64(&op.ops),
+		Comparisons: atomic.LoadUint64(&op.comparisons),
+	}
+}
+
+func (c *comparisonCounter) stats() ComparisonStats {
+	return ComparisonStats{
+		Total:   c.start(),
+		Inserts: c.inserts.load(),
+		Deletes: c.deletes.load(),
+		Lookups: c.lookups.load(),
+	}
+}
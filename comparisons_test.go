@@ -0,0 +1,86 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestComparisonCounting(t *testing.T) {
+	var calls uint64
+	less := func(a, b int) bool {
+		calls++
+		return a < b
+	}
+	tr := NewWithOptions(WithLess(less), WithDegree[int](*btreeDegree), WithComparisonCounting[int]())
+	for _, v := range rand.Perm(1000) {
+		tr.ReplaceOrInsert(v)
+	}
+	tr.AppendMax(1000)
+	insertCalls := calls
+	for i := 0; i < 100; i++ {
+		tr.Get(i * 7)
+	}
+	lookupCalls := calls - insertCalls
+	for i := 0; i < 50; i++ {
+		tr.Delete(i)
+	}
+	tr.Ascend(func(int) bool { return true })
+
+	c := tr.Stats().Comparisons
+	if c.Total != calls {
+		t.Errorf("Total = %d, want %d", c.Total, calls)
+	}
+	if want := (OpComparisons{1001, insertCalls}); c.Inserts != want {
+		t.Errorf("Inserts = %+v, want %+v", c.Inserts, want)
+	}
+	if want := (OpComparisons{100, lookupCalls}); c.Lookups != want {
+		t.Errorf("Lookups = %+v, want %+v", c.Lookups, want)
+	}
+	if c.Deletes.Ops != 50 || c.Deletes.Comparisons == 0 {
+		t.Errorf("Deletes = %+v", c.Deletes)
+	}
+	if got := c.Lookups.PerOp(); got <= 0 || got > 100 {
+		t.Errorf("Lookups.PerOp() = %v", got)
+	}
+
+	// Counts are shared with clones, and absent without the option.
+	tr.Clone().Get(3)
+	if got := tr.Stats().Comparisons.Lookups.Ops; got != 101 {
+		t.Errorf("after clone lookup, Lookups.Ops = %d, want 101", got)
+	}
+	if got := NewOrderedG[int](*btreeDegree).Stats().Comparisons; got != (ComparisonStats{}) {
+		t.Errorf("uncounted tree reports %+v", got)
+	}
+}
+
+func TestComparisonCountingInterpolated(t *testing.T) {
+	tr := NewWithOptions(WithInterpolationSearch[int](), WithDegree[int](*btreeDegree), WithComparisonCounting[int]())
+	for _, v := range rand.Perm(1000) {
+		tr.ReplaceOrInsert(v)
+	}
+	for i := 0; i < 100; i++ {
+		if !tr.Has(i * 7) {
+			t.Fatalf("Has(%d) = false", i*7)
+		}
+	}
+	if c := tr.Stats().Comparisons.Lookups; c.Ops != 100 || c.Comparisons == 0 {
+		t.Errorf("Lookups = %+v, want comparisons counted", c)
+	}
+}
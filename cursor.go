@@ -0,0 +1,265 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+// cursorFrame is one level of a cursor's path from the root.  For the
+// deepest frame, index is the position of the current item within n.items.
+// For every other frame, index is the child of n that the path descends into.
+type cursorFrame[T any] struct {
+	n     *node[T]
+	index int
+}
+
+// CursorG is a position within a BTreeG that can be moved forwards and
+// backwards one item at a time.
+//
+// A cursor keeps the full path from the root to its current item, so moving
+// to a neighbouring item takes amortized O(1) time and never re-descends from
+// the root.  This makes it cheap to pause a scan and resume it later, or to
+// walk several trees in lockstep.  (Nodes can't simply link to their
+// siblings, because copy-on-write lets a node be shared by several trees
+// whose siblings differ.)
+//
+// A cursor is invalidated by any write to the tree it was created from;
+// after a write, reposition it with First, Last, or Seek before using it
+// again.  Writes to clones of the tree do not affect it.  Trees created with
+// WithModificationChecks detect use of an invalidated cursor and panic.
+type CursorG[T any] struct {
+	t     *BTreeG[T]
+	stack []cursorFrame[T]
+	gen   uint64 // t.gen when the cursor was positioned
+}
+
+// Cursor returns a new cursor over t.  The cursor is not positioned at any
+// item; call First, Last, or Seek before using it.
+func (t *BTreeG[T]) Cursor() *CursorG[T] {
+	return &CursorG[T]{t: t}
+}
+
+// Valid returns true if the cursor is positioned at an item.
+func (c *CursorG[T]) Valid() bool {
+	return len(c.stack) > 0
+}
+
+// Item returns the item at the cursor's position.  It panics if the cursor is
+// not valid.
+func (c *CursorG[T]) Item() T {
+	c.check()
+	top := c.stack[len(c.stack)-1]
+	return top.n.items[top.index]
+}
+
+// First moves the cursor to the smallest item in the tree, returning false if
+// the tree is empty.
+func (c *CursorG[T]) First() bool {
+	c.stack, c.gen = c.stack[:0], c.t.gen
+	if c.t.root == nil || len(c.t.root.items) == 0 {
+		return false
+	}
+	c.pushLeftmost(c.t.root)
+	return true
+}
+
+// Last moves the cursor to the largest item in the tree, returning false if
+// the tree is empty.
+func (c *CursorG[T]) Last() bool {
+	c.stack, c.gen = c.stack[:0], c.t.gen
+	if c.t.root == nil || len(c.t.root.items) == 0 {
+		return false
+	}
+	c.pushRightmost(c.t.root)
+	return true
+}
+
+// Seek moves the cursor to the smallest item greater than or equal to key,
+// returning false if there is no such item.
+func (c *CursorG[T]) Seek(key T) bool {
+	c.stack, c.gen = c.stack[:0], c.t.gen
+	if c.t.root == nil {
+		return false
+	}
+	return c.seekFrom(c.t.root, -1, key)
+}
+
+// SeekNear is like Seek, but starts from the cursor's current position: it
+// climbs only as far up the path as it must to reach a subtree that can hold
+// key, and searches each node from the position the path already passes
+// through by galloping outwards from it, rather than binary searching the
+// whole node.  When successive seeks go to nearby keys, as in merges and
+// joins, this takes O(log distance) comparisons rather than O(log n).  If
+// the cursor isn't positioned, or the tree has changed since it was,
+// SeekNear is the same as Seek.
+func (c *CursorG[T]) SeekNear(key T) bool {
+	if len(c.stack) == 0 || c.gen != c.t.gen {
+		return c.Seek(key)
+	}
+	// Find the deepest node on the path whose subtree holds the items
+	// strictly between the nearest items of its ancestors either side of
+	// key.  Each ancestor contributes the items either side of the child
+	// the path descends into; nearer ancestors give tighter bounds, so
+	// once the nearest bound on one side is known to admit key, the
+	// bounds further up on that side needn't be checked.
+	less := c.t.cow.less
+	k := len(c.stack) - 1
+	lowerOK, upperOK := false, false
+	for j := k - 1; j >= 0 && !(lowerOK && upperOK); j-- {
+		p := c.stack[j]
+		if !lowerOK && p.index > 0 {
+			if less(p.n.items[p.index-1], key) {
+				lowerOK = true
+			} else {
+				k = j
+			}
+		}
+		if !upperOK && p.index < len(p.n.items) {
+			if !less(p.n.items[p.index], key) {
+				upperOK = true
+			} else {
+				k = j
+			}
+		}
+	}
+	f := c.stack[k]
+	c.stack = c.stack[:k]
+	return c.seekFrom(f.n, f.index, key)
+}
+
+// seekFrom extends the path down from n to the smallest item greater than or
+// equal to key, which must lie within n's subtree or be the next item after
+// it.  If hint isn't negative, n is searched starting from index hint.
+func (c *CursorG[T]) seekFrom(n *node[T], hint int, key T) bool {
+	for {
+		var i int
+		var found bool
+		if hint >= 0 && n.cow.search == nil {
+			i, found = n.items.findFrom(hint, key, n.cow.less)
+		} else {
+			i, found = n.find(key)
+		}
+		hint = -1
+		c.stack = append(c.stack, cursorFrame[T]{n, i})
+		if found {
+			return true
+		}
+		if len(n.children) == 0 {
+			if i < len(n.items) {
+				return true
+			}
+			return c.ascendToNext()
+		}
+		n = n.children[i]
+	}
+}
+
+// Next moves the cursor to the next larger item, returning false (and
+// leaving the cursor invalid) if there is none.
+func (c *CursorG[T]) Next() bool {
+	if len(c.stack) == 0 {
+		return false
+	}
+	c.check()
+	top := &c.stack[len(c.stack)-1]
+	if len(top.n.children) > 0 {
+		top.index++
+		c.pushLeftmost(top.n.children[top.index])
+		return true
+	}
+	top.index++
+	if top.index < len(top.n.items) {
+		return true
+	}
+	return c.ascendToNext()
+}
+
+// Prev moves the cursor to the next smaller item, returning false (and
+// leaving the cursor invalid) if there is none.
+func (c *CursorG[T]) Prev() bool {
+	if len(c.stack) == 0 {
+		return false
+	}
+	c.check()
+	top := &c.stack[len(c.stack)-1]
+	if len(top.n.children) > 0 {
+		c.pushRightmost(top.n.children[top.index])
+		return true
+	}
+	top.index--
+	if top.index >= 0 {
+		return true
+	}
+	return c.ascendToPrev()
+}
+
+// check panics if the tree has been written to since the cursor was
+// positioned, if modification checks are on.
+func (c *CursorG[T]) check() {
+	if c.t.cow.checkMods && c.gen != c.t.gen {
+		panic(ErrModifiedDuringIteration)
+	}
+}
+
+// pushLeftmost extends the path down the leftmost edge of n's subtree,
+// leaving the cursor at its smallest item.
+func (c *CursorG[T]) pushLeftmost(n *node[T]) {
+	for {
+		c.stack = append(c.stack, cursorFrame[T]{n, 0})
+		if len(n.children) == 0 {
+			return
+		}
+		n = n.children[0]
+	}
+}
+
+// pushRightmost extends the path down the rightmost edge of n's subtree,
+// leaving the cursor at its largest item.
+func (c *CursorG[T]) pushRightmost(n *node[T]) {
+	for len(n.children) > 0 {
+		c.stack = append(c.stack, cursorFrame[T]{n, len(n.children) - 1})
+		n = n.children[len(n.children)-1]
+	}
+	c.stack = append(c.stack, cursorFrame[T]{n, len(n.items) - 1})
+}
+
+// ascendToNext pops exhausted frames until it reaches an ancestor with an
+// item after the child we came from, which is the next item.
+func (c *CursorG[T]) ascendToNext() bool {
+	c.stack = c.stack[:len(c.stack)-1]
+	for len(c.stack) > 0 {
+		top := c.stack[len(c.stack)-1]
+		if top.index < len(top.n.items) {
+			return true
+		}
+		c.stack = c.stack[:len(c.stack)-1]
+	}
+	return false
+}
+
+// ascendToPrev pops exhausted frames until it reaches an ancestor with an
+// item before the child we came from, which is the previous item.
+func (c *CursorG[T]) ascendToPrev() bool {
+	c.stack = c.stack[:len(c.stack)-1]
+	for len(c.stack) > 0 {
+		top := &c.stack[len(c.stack)-1]
+		if top.index > 0 {
+			top.index--
+			return true
+		}
+		c.stack = c.stack[:len(c.stack)-1]
+	}
+	return false
+}
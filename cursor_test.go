@@ -0,0 +1,116 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestCursorG(t *testing.T) {
+	for _, degree := range []int{2, 3, *btreeDegree} {
+		tr := NewOrderedG[int](degree)
+		c := tr.Cursor()
+		if c.First() || c.Last() || c.Seek(0) || c.Valid() {
+			t.Fatal("cursor valid on empty tree")
+		}
+		for _, v := range rand.Perm(1000) {
+			tr.ReplaceOrInsert(v * 2)
+		}
+		var got []int
+		for ok := c.First(); ok; ok = c.Next() {
+			got = append(got, c.Item())
+		}
+		if want := intAll(tr); !reflect.DeepEqual(got, want) {
+			t.Fatalf("degree %v: ascending mismatch", degree)
+		}
+		got = got[:0]
+		for ok := c.Last(); ok; ok = c.Prev() {
+			got = append(got, c.Item())
+		}
+		if want := intAllRev(tr); !reflect.DeepEqual(got, want) {
+			t.Fatalf("degree %v: descending mismatch", degree)
+		}
+		for i := -1; i < 2001; i++ {
+			ok := c.Seek(i)
+			want := i + i%2
+			if i < 0 {
+				want = 0
+			}
+			if want >= 2000 {
+				if ok {
+					t.Fatalf("degree %v: Seek(%v) found %v", degree, i, c.Item())
+				}
+				continue
+			}
+			if !ok || c.Item() != want {
+				t.Fatalf("degree %v: Seek(%v) = %v, want %v", degree, i, c.Item(), want)
+			}
+			// Changing direction must revisit neighbouring items.
+			if c.Prev() {
+				if c.Item() != want-2 || !c.Next() || c.Item() != want {
+					t.Fatalf("degree %v: Prev/Next around %v broken", degree, want)
+				}
+			} else if want != 0 {
+				t.Fatalf("degree %v: no item before %v", degree, want)
+			}
+		}
+	}
+}
+
+func TestCursorSeekNear(t *testing.T) {
+	tr := NewWithOptions(WithOrdered[int](), WithDegree[int](*btreeDegree), WithComparisonCounting[int]())
+	for _, v := range rand.Perm(10000) {
+		tr.ReplaceOrInsert(v * 2)
+	}
+	near, far := tr.Cursor(), tr.Cursor()
+	for i := 0; i < 2000; i++ {
+		key := rand.Intn(20100) - 50
+		if i%2 == 0 && near.Valid() {
+			key = near.Item() + rand.Intn(21) - 10 // stay close
+		}
+		okNear, okFar := near.SeekNear(key), far.Seek(key)
+		if okNear != okFar || (okNear && near.Item() != far.Item()) {
+			t.Fatalf("SeekNear(%d) disagrees with Seek", key)
+		}
+		if okNear && near.Next() != far.Next() {
+			t.Fatalf("Next after SeekNear(%d) disagrees with Seek", key)
+		}
+		if !okNear {
+			near.First()
+			far.First()
+		}
+	}
+
+	// Stepping through the tree by small jumps costs fewer comparisons.
+	cost := func(seek func(c *CursorG[int], key int) bool) uint64 {
+		before := tr.Stats().Comparisons.Total
+		c := tr.Cursor()
+		c.First()
+		for key := 0; key < 20000; key += 7 {
+			seek(c, key)
+		}
+		return tr.Stats().Comparisons.Total - before
+	}
+	seekCost := cost((*CursorG[int]).Seek)
+	nearCost := cost((*CursorG[int]).SeekNear)
+	if nearCost >= seekCost {
+		t.Errorf("SeekNear made %d comparisons, Seek %d", nearCost, seekCost)
+	}
+}
@@ -0,0 +1,171 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+import (
+	"errors"
+	"fmt"
+)
+
+// This file holds the opt-in consistency checks used to debug misbehaving
+// trees.  None of them cost anything when they're turned off.
+
+// checkedLess wraps less so that it panics if it's ever asymmetric, that is,
+// if both less(a, b) and less(b, a) are true.
+func checkedLess[T any](less LessFunc[T]) LessFunc[T] {
+	return func(a, b T) bool {
+		if !less(a, b) {
+			return false
+		}
+		if less(b, a) {
+			panic(fmt.Sprintf("btree: inconsistent LessFunc: both less(%v, %v) and less(%v, %v) are true", a, b, b, a))
+		}
+		return true
+	}
+}
+
+// checkNeighbors spot-checks the ordering around s[i], which was just
+// changed: s[i-1] < s[i] < s[i+1] must hold, and by transitivity so must
+// s[j-1] < s[j+1] for every j next to or at i.
+func (s items[T]) checkNeighbors(i int, less LessFunc[T]) {
+	for j := i; j <= i+1; j++ {
+		if j > 0 && j < len(s) && !less(s[j-1], s[j]) {
+			panic(fmt.Sprintf("btree: inconsistent LessFunc: %v is stored before %v, but is not less than it", s[j-1], s[j]))
+		}
+	}
+	for j := i - 1; j <= i+1; j++ {
+		if j > 0 && j+1 < len(s) && !less(s[j-1], s[j+1]) {
+			panic(fmt.Sprintf("btree: LessFunc is not transitive: %v < %v < %v, but not %v < %v", s[j-1], s[j], s[j+1], s[j-1], s[j+1]))
+		}
+	}
+}
+
+// ErrModifiedDuringIteration is the panic value used by trees created with
+// WithModificationChecks when they are written to during an iteration.
+var ErrModifiedDuringIteration = errors.New("btree: tree modified during iteration")
+
+// guard wraps iter so that it panics if the tree is modified while it runs,
+// if modification checks are on.
+func (t *BTreeG[T]) guard(iter ItemIteratorG[T]) ItemIteratorG[T] {
+	if !t.cow.checkMods {
+		return iter
+	}
+	gen := t.gen
+	return func(item T) bool {
+		ok := iter(item)
+		if t.gen != gen {
+			panic(ErrModifiedDuringIteration)
+		}
+		return ok
+	}
+}
+
+// WithKeyChecks makes the tree catch items whose ordering is changed while
+// they are in the tree, a common bug when items are pointers to structs that
+// are updated in place.  hash must hash the fields the LessFunc compares:
+// items that are equal must hash equally, and an item whose ordering fields
+// change should hash differently.  The tree records the hash of every item
+// it holds, and Get, Delete and ReplaceOrInsert panic, naming the item, when
+// an item they find in the tree no longer has a recorded hash.  Verify
+// checks every item.
+//
+// The hashes are kept in a map, costing memory and time on every insert
+// and delete, so the checks are meant for tests and debugging.  Trees
+// derived from this one record the hashes of their items on their first
+// insert; until then their items aren't checked.
+func WithKeyChecks[T any](hash func(T) uint64) Option[T] {
+	return func(o *options[T]) { o.keyHash = hash }
+}
+
+// keyHashes counts the hashes of a tree's items, for WithKeyChecks.  Like a
+// Bloom filter, it is shared by clones of a tree until one of them writes
+// to it.
+type keyHashes struct {
+	counts map[uint64]int
+	shared bool
+}
+
+// mutableKeys returns t's hashes, copying them first if they're shared.
+func (t *BTreeG[T]) mutableKeys() *keyHashes {
+	if t.keys.shared {
+		counts := make(map[uint64]int, len(t.keys.counts))
+		for h, n := range t.keys.counts {
+			counts[h] = n
+		}
+		t.keys = &keyHashes{counts: counts}
+	}
+	return t.keys
+}
+
+// keysInserted records the hash of item after it was added to the tree,
+// checking old if item replaced it.  If no hashes have been recorded yet,
+// they're recorded for every item.
+func (t *BTreeG[T]) keysInserted(item, old T, replaced bool) {
+	if t.keys == nil {
+		t.keys = &keyHashes{counts: make(map[uint64]int, t.length)}
+		t.ascend(func(item T) bool {
+			t.keys.counts[t.cow.keyHash(item)]++
+			return true
+		})
+		return
+	}
+	if replaced {
+		t.keysDeleted(old)
+	}
+	t.mutableKeys().counts[t.cow.keyHash(item)]++
+}
+
+// keysDeleted checks item, which was just removed from the tree, and
+// forgets its hash.
+func (t *BTreeG[T]) keysDeleted(item T) {
+	if t.keys == nil {
+		return
+	}
+	t.checkKey(item)
+	k, h := t.mutableKeys(), t.cow.keyHash(item)
+	if k.counts[h]--; k.counts[h] == 0 {
+		delete(k.counts, h)
+	}
+}
+
+// checkKey panics if item, which was found in the tree, doesn't have a
+// recorded hash.
+func (t *BTreeG[T]) checkKey(item T) {
+	if t.keys != nil && t.keys.counts[t.cow.keyHash(item)] == 0 {
+		panic(fmt.Sprintf("btree: item %v was modified while in the tree", item))
+	}
+}
+
+// verifyKeys returns an error naming an item without a recorded hash, if
+// there is one.
+func (t *BTreeG[T]) verifyKeys() (err error) {
+	counts := make(map[uint64]int, len(t.keys.counts))
+	for h, n := range t.keys.counts {
+		counts[h] = n
+	}
+	t.ascend(func(item T) bool {
+		h := t.cow.keyHash(item)
+		if counts[h] == 0 {
+			err = fmt.Errorf("btree: item %v was modified while in the tree", item)
+			return false
+		}
+		counts[h]--
+		return true
+	})
+	return err
+}
@@ -0,0 +1,135 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// expectPanic calls f and fails the test unless it panics with a message
+// containing want.
+func expectPanic(t *testing.T, want string, f func()) {
+	t.Helper()
+	defer func() {
+		t.Helper()
+		r := recover()
+		if r == nil || !strings.Contains(fmt.Sprint(r), want) {
+			t.Errorf("got panic %v, want one containing %q", r, want)
+		}
+	}()
+	f()
+}
+
+func TestOrderingChecks(t *testing.T) {
+	tr := NewWithOptions(WithOrdered[int](), WithOrderingChecks[int]())
+	for i := 0; i < 1000; i++ {
+		tr.ReplaceOrInsert(i)
+	}
+	for i := 0; i < 1000; i += 2 {
+		tr.Delete(i)
+	}
+
+	// '<=' is not a strict ordering: less(a, a) is true both ways round.
+	tr = NewWithOptions(WithLess(func(a, b int) bool { return a <= b }), WithOrderingChecks[int]())
+	expectPanic(t, "both less(1, 1)", func() {
+		tr.ReplaceOrInsert(1)
+		tr.ReplaceOrInsert(1)
+	})
+
+	// Rock-paper-scissors is asymmetric but not transitive.
+	beats := func(a, b int) bool { return (b+1)%3 == a }
+	tr = NewWithOptions(WithLess(beats), WithOrderingChecks[int]())
+	expectPanic(t, "not transitive", func() {
+		for i := 0; i < 3; i++ {
+			tr.ReplaceOrInsert(i)
+		}
+	})
+}
+
+func TestModificationChecks(t *testing.T) {
+	tr := NewWithOptions(WithOrdered[int](), WithModificationChecks[int]())
+	for i := 0; i < 100; i++ {
+		tr.ReplaceOrInsert(i)
+	}
+	// Read-only iteration is fine.
+	tr.Ascend(func(int) bool { return true })
+	tr.DescendRange(50, 10, func(int) bool { return true })
+
+	expectPanic(t, ErrModifiedDuringIteration.Error(), func() {
+		tr.Ascend(func(i int) bool {
+			tr.Delete(i + 1)
+			return true
+		})
+	})
+	expectPanic(t, ErrModifiedDuringIteration.Error(), func() {
+		c := tr.Cursor()
+		c.First()
+		tr.ReplaceOrInsert(1000)
+		c.Next()
+	})
+	// Repositioning a cursor makes it valid again.
+	c := tr.Cursor()
+	c.First()
+	tr.Delete(1000)
+	if !c.Seek(50) || c.Item() != 50 || !c.Next() {
+		t.Error("repositioned cursor failed")
+	}
+}
+
+func TestKeyChecks(t *testing.T) {
+	type rec struct{ key int }
+	newTree := func() *BTreeG[*rec] {
+		return NewWithOptions(
+			WithLess(func(a, b *rec) bool { return a.key < b.key }),
+			WithKeyChecks(func(r *rec) uint64 { return uint64(r.key) }),
+			WithDegree[*rec](2),
+		)
+	}
+	tr := newTree()
+	recs := make([]*rec, 20)
+	for i := range recs {
+		recs[i] = &rec{i * 2}
+		tr.ReplaceOrInsert(recs[i])
+	}
+	tr.ReplaceOrInsert(&rec{4})
+	tr.Delete(&rec{6})
+	if err := tr.Verify(); err != nil {
+		t.Fatal(err)
+	}
+	c := tr.Clone()
+	recs[5].key = 11 // still between 8 and 12, so lookups find it
+	if err := tr.Verify(); err == nil || !strings.Contains(err.Error(), "modified") {
+		t.Errorf("Verify: got %v, want an error about the modified item", err)
+	}
+	expectPanic(t, "modified while in the tree", func() { tr.Get(&rec{11}) })
+	expectPanic(t, "modified while in the tree", func() { c.Delete(&rec{11}) })
+	recs[5].key = 10
+	if _, ok := tr.Get(&rec{10}); !ok {
+		t.Error("Get(10) failed after restoring the item")
+	}
+	recs[5].key = 11
+	expectPanic(t, "modified while in the tree", func() { tr.ReplaceOrInsert(&rec{11}) })
+
+	tr.Clear(false)
+	tr.ReplaceOrInsert(&rec{1})
+	if err := tr.Verify(); err != nil {
+		t.Errorf("after Clear: %v", err)
+	}
+}
@@ -0,0 +1,70 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+// DeepCopy returns a copy of t that shares no nodes with it, with each item
+// replaced by clone(item), or copied as is if clone is nil.  clone must not
+// change how items order.
+//
+// Unlike Clone, which shares nodes until either tree writes to them,
+// DeepCopy copies every node up front, allocating from t's freelist, so it
+// takes O(n) time.  Use it when the copy must be fully independent: when
+// items are pointers or contain slices that the copy will modify in place,
+// or when t will be written to heavily and the copy-on-write costs would be
+// paid anyway.  The copy shares t's configuration, and its views are clones
+// of t's.
+func (t *BTreeG[T]) DeepCopy(clone func(T) T) *BTreeG[T] {
+	cow := *t.cow
+	out := &BTreeG[T]{
+		degree: t.degree,
+		length: t.length,
+		cow:    &cow,
+		limit:  t.limit,
+		usage:  t.usage,
+		views:  cloneViews(t.views),
+	}
+	if t.root != nil {
+		out.root = t.root.deepCopy(out.cow, clone)
+	}
+	if clone != nil && out.limit != nil {
+		out.usage = 0
+		out.ascend(func(item T) bool {
+			out.usage += out.limit.weigh(item)
+			return true
+		})
+	}
+	out.refresh()
+	return out
+}
+
+// deepCopy returns a copy of n's subtree owned by c, copying children before
+// their parents.
+func (n *node[T]) deepCopy(c *copyOnWriteContext[T], clone func(T) T) *node[T] {
+	out := c.newNode()
+	for _, child := range n.children {
+		out.children = append(out.children, child.deepCopy(c, clone))
+	}
+	if clone == nil {
+		out.items = append(out.items, n.items...)
+	} else {
+		for _, item := range n.items {
+			out.items = append(out.items, clone(item))
+		}
+	}
+	return out
+}
@@ -0,0 +1,66 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+type deepItem struct {
+	key  int
+	tags []string
+}
+
+func TestDeepCopy(t *testing.T) {
+	tr := NewG(*btreeDegree, func(a, b *deepItem) bool { return a.key < b.key })
+	for _, v := range rand.Perm(200) {
+		tr.ReplaceOrInsert(&deepItem{key: v, tags: []string{"orig"}})
+	}
+	cp := tr.DeepCopy(func(it *deepItem) *deepItem {
+		return &deepItem{key: it.key, tags: append([]string(nil), it.tags...)}
+	})
+	if err := cp.Verify(); err != nil {
+		t.Fatal(err)
+	}
+	cp.Ascend(func(it *deepItem) bool {
+		it.tags[0] = "copy"
+		return true
+	})
+	for i := 0; i < 100; i++ {
+		cp.Delete(&deepItem{key: i})
+	}
+	if tr.Len() != 200 || cp.Len() != 100 {
+		t.Fatalf("lengths %d, %d; want 200, 100", tr.Len(), cp.Len())
+	}
+	tr.Ascend(func(it *deepItem) bool {
+		if it.tags[0] != "orig" {
+			t.Fatalf("item %d modified through copy", it.key)
+		}
+		return true
+	})
+
+	ints := NewOrderedG[int](*btreeDegree)
+	for _, v := range rand.Perm(100) {
+		ints.ReplaceOrInsert(v)
+	}
+	if got := ints.DeepCopy(nil).all(); !reflect.DeepEqual(got, intRange(100, false)) {
+		t.Errorf("got %v", got)
+	}
+}
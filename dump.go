@@ -0,0 +1,161 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// errWriter wraps an io.Writer, remembering the first error so that a
+// sequence of writes can be checked once at the end.
+type errWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (e *errWriter) printf(format string, args ...interface{}) {
+	if e.err == nil {
+		_, e.err = fmt.Fprintf(e.w, format, args...)
+	}
+}
+
+// formatter returns format, or fmt.Sprint if format is nil.
+func formatter[T any](format func(T) string) func(T) string {
+	if format != nil {
+		return format
+	}
+	return func(item T) string { return fmt.Sprint(item) }
+}
+
+// dotEscaper escapes the characters that are special in Graphviz record
+// labels.
+var dotEscaper = strings.NewReplacer(
+	`\`, `\\`, `"`, `\"`, `{`, `\{`, `}`, `\}`, `|`, `\|`, `<`, `\<`, `>`, `\>`, "\n", `\n`,
+)
+
+// WriteDot writes the structure of the tree to w in the Graphviz DOT
+// language, for visualizing how items are laid out in nodes.  Each node is
+// drawn as a record of its items, formatted with format (or fmt.Sprint if
+// format is nil).  Nodes that are shared with another tree through Clone,
+// and so will be copied on the next write that touches them, are drawn
+// dashed and grey; nodes owned by this tree are drawn solid.
+//
+// Render the output with, for example, "dot -Tsvg".
+func (t *BTreeG[T]) WriteDot(w io.Writer, format func(T) string) error {
+	ew := &errWriter{w: w}
+	format = formatter(format)
+	ew.printf("digraph btree {\n\tnode [shape=record];\n")
+	id := 0
+	var walk func(n *node[T]) int
+	walk = func(n *node[T]) int {
+		me := id
+		id++
+		labels := make([]string, len(n.items))
+		for i, item := range n.items {
+			labels[i] = dotEscaper.Replace(format(item))
+		}
+		style := ""
+		if n.cow != t.cow {
+			style = ", style=dashed, color=grey"
+		}
+		ew.printf("\tn%d [label=\"%s\"%s];\n", me, strings.Join(labels, "|"), style)
+		for _, c := range n.children {
+			ew.printf("\tn%d -> n%d;\n", me, walk(c))
+		}
+		return me
+	}
+	if t.root != nil {
+		walk(t.root)
+	}
+	ew.printf("}\n")
+	return ew.err
+}
+
+// Dump writes the structure of the tree to w as text, one node per line.
+// Each line lists a node's items in brackets, formatted with format (or
+// fmt.Sprint if format is nil), and is indented by two spaces per level
+// below the root, with each node's children following it in order.  For
+// example, a tree of degree 2 holding 0 through 5 dumps as:
+//
+//	[1 3]
+//	  [0]
+//	  [2]
+//	  [4 5]
+//
+// The format is stable, so dumps can be compared in tests and included in
+// bug reports.
+func (t *BTreeG[T]) Dump(w io.Writer, format func(T) string) error {
+	ew := &errWriter{w: w}
+	format = formatter(format)
+	var walk func(n *node[T], level int)
+	walk = func(n *node[T], level int) {
+		labels := make([]string, len(n.items))
+		for i, item := range n.items {
+			labels[i] = format(item)
+		}
+		ew.printf("%s[%s]\n", strings.Repeat("  ", level), strings.Join(labels, " "))
+		for _, c := range n.children {
+			walk(c, level+1)
+		}
+	}
+	if t.root != nil {
+		walk(t.root, 0)
+	}
+	return ew.err
+}
+
+// Golden returns the structural shape of the tree as a canonical string,
+// for golden-file tests that should notice when a change to this package
+// or to the caller alters how items are laid out in nodes.  Unlike Dump, it
+// leaves out the contents of leaves: internal nodes list their items, which
+// are the key boundaries between their children, formatted with format (or
+// fmt.Sprint if format is nil), and leaves list only their number of items.
+// For example, a tree of degree 2 holding 0 through 5 gives:
+//
+//	degree 2, 6 items
+//	[1 3]
+//	  (1)
+//	  (1)
+//	  (2)
+func (t *BTreeG[T]) Golden(format func(T) string) string {
+	var b strings.Builder
+	format = formatter(format)
+	fmt.Fprintf(&b, "degree %d, %d items\n", t.degree, t.length)
+	var walk func(n *node[T], level int)
+	walk = func(n *node[T], level int) {
+		b.WriteString(strings.Repeat("  ", level))
+		if len(n.children) == 0 {
+			fmt.Fprintf(&b, "(%d)\n", len(n.items))
+			return
+		}
+		labels := make([]string, len(n.items))
+		for i, item := range n.items {
+			labels[i] = format(item)
+		}
+		fmt.Fprintf(&b, "[%s]\n", strings.Join(labels, " "))
+		for _, c := range n.children {
+			walk(c, level+1)
+		}
+	}
+	if t.root != nil {
+		walk(t.root, 0)
+	}
+	return b.String()
+}
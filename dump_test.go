@@ -0,0 +1,105 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestWriteDot(t *testing.T) {
+	tr := NewOrderedG[int](2)
+	for i := 0; i < 5; i++ {
+		tr.ReplaceOrInsert(i)
+	}
+	clone := tr.Clone()
+	clone.ReplaceOrInsert(5)
+	var b strings.Builder
+	if err := clone.WriteDot(&b, nil); err != nil {
+		t.Fatal(err)
+	}
+	// The insert copied the root and the leaves it split; the leftmost leaf
+	// is still shared with tr.
+	want := `digraph btree {
+	node [shape=record];
+	n0 [label="1|3"];
+	n1 [label="0", style=dashed, color=grey];
+	n0 -> n1;
+	n2 [label="2"];
+	n0 -> n2;
+	n3 [label="4|5"];
+	n0 -> n3;
+}
+`
+	if got := b.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestWriteDotEscaping(t *testing.T) {
+	tr := NewOrderedG[string](2)
+	tr.ReplaceOrInsert(`a|"b"`)
+	var b strings.Builder
+	if err := tr.WriteDot(&b, func(s string) string { return "<" + s + ">" }); err != nil {
+		t.Fatal(err)
+	}
+	if want := `n0 [label="\<a\|\"b\"\>"];`; !strings.Contains(b.String(), want) {
+		t.Errorf("got:\n%s\nwant it to contain %s", b.String(), want)
+	}
+}
+
+func TestDump(t *testing.T) {
+	tr := NewOrderedG[int](2)
+	var b strings.Builder
+	if err := tr.Dump(&b, nil); err != nil || b.Len() != 0 {
+		t.Fatalf("empty tree dumped %q, %v", b.String(), err)
+	}
+	for i := 0; i < 6; i++ {
+		tr.ReplaceOrInsert(i)
+	}
+	if err := tr.Dump(&b, func(i int) string { return fmt.Sprintf("%02d", i) }); err != nil {
+		t.Fatal(err)
+	}
+	want := "[01 03]\n  [00]\n  [02]\n  [04 05]\n"
+	if got := b.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestGolden(t *testing.T) {
+	tr := NewOrderedG[int](2)
+	if got, want := tr.Golden(nil), "degree 2, 0 items\n"; got != want {
+		t.Errorf("empty tree: got %q, want %q", got, want)
+	}
+	for i := 0; i < 6; i++ {
+		tr.ReplaceOrInsert(i)
+	}
+	want := "degree 2, 6 items\n[1 3]\n  (1)\n  (1)\n  (2)\n"
+	if got := tr.Golden(nil); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+	// Trees with the same items laid out differently differ.
+	other := NewOrderedG[int](2)
+	for i := 5; i >= 0; i-- {
+		other.ReplaceOrInsert(i)
+	}
+	if other.Golden(nil) == want {
+		t.Errorf("descending inserts gave the same shape:\n%s", want)
+	}
+}
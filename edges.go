@@ -0,0 +1,92 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+// The tree caches its leftmost and rightmost leaves, so that DeleteMin and
+// DeleteMax can usually remove an item straight from the leaf without
+// descending the tree, and so that Min and Max take O(1) time while the
+// cache is fresh.
+//
+// Most writes don't touch the edges of the tree, so rather than walking
+// both edges after every write, writes just mark the cache stale.  Like
+// summaries, the cache is only updated by writes, never by reads, so that
+// reads stay safe to run concurrently: DeleteMin and DeleteMax refill a
+// stale cache, which takes one walk down an edge each, and then keep it
+// fresh while they take the fast path; Min and Max walk the edge of a tree
+// whose cache is stale, as they would without a cache.
+
+// refresh brings the tree's caches up to date after a write.
+func (t *BTreeG[T]) refresh() {
+	t.refreshAug()
+	t.edgesStale = true
+}
+
+// refreshEdges finds the leftmost and rightmost leaves, or clears them if
+// the tree is empty.
+func (t *BTreeG[T]) refreshEdges() {
+	t.minLeaf, t.maxLeaf = t.edge(true), t.edge(false)
+	t.edgesStale = false
+}
+
+// edge returns the leftmost leaf if left is true, and the rightmost leaf
+// otherwise, or nil if the tree is empty.  It uses the cache if it's fresh.
+func (t *BTreeG[T]) edge(left bool) *node[T] {
+	if !t.edgesStale {
+		if left {
+			return t.minLeaf
+		}
+		return t.maxLeaf
+	}
+	n := t.root
+	if n == nil || len(n.items) == 0 {
+		return nil
+	}
+	for len(n.children) > 0 {
+		if left {
+			n = n.children[0]
+		} else {
+			n = n.children[len(n.children)-1]
+		}
+	}
+	return n
+}
+
+// edgeLeaf returns the leaf holding the item that a removal of type typ
+// would remove, if the item can be removed straight from the leaf: the tree
+// must own the leaf, so no clone shares it, the leaf must keep at least the
+// minimum number of items, and there must be no summaries, which would need
+// recomputing up the whole path.  Otherwise it returns nil.
+func (t *BTreeG[T]) edgeLeaf(typ toRemove) *node[T] {
+	if typ == removeItem {
+		return nil
+	}
+	if t.edgesStale {
+		t.refreshEdges()
+	}
+	var leaf *node[T]
+	switch typ {
+	case removeMin:
+		leaf = t.minLeaf
+	case removeMax:
+		leaf = t.maxLeaf
+	}
+	if leaf == nil || leaf == t.root || leaf.cow != t.cow || len(leaf.items) <= t.minItems() || t.cow.augment != nil {
+		return nil
+	}
+	return leaf
+}
@@ -0,0 +1,119 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestCachedEdges(t *testing.T) {
+	tr := NewOrderedG[int](*btreeDegree)
+	var clones []*BTreeG[int]
+	var snapshots [][]int
+	check := func(tr *BTreeG[int]) {
+		t.Helper()
+		all := tr.all()
+		min, minOK := tr.Min()
+		max, maxOK := tr.Max()
+		if len(all) == 0 {
+			if minOK || maxOK {
+				t.Fatalf("empty tree has Min %d, Max %d", min, max)
+			}
+			return
+		}
+		if min != all[0] || max != all[len(all)-1] {
+			t.Fatalf("Min, Max = %d, %d; want %d, %d", min, max, all[0], all[len(all)-1])
+		}
+	}
+	for i := 0; i < 20000; i++ {
+		switch r := rand.Intn(10); {
+		case r < 4:
+			tr.ReplaceOrInsert(rand.Intn(5000))
+		case r < 6:
+			tr.DeleteMin()
+		case r < 8:
+			tr.DeleteMax()
+		case r < 9:
+			tr.Delete(rand.Intn(5000))
+		default:
+			if len(clones) < 20 {
+				c := tr.Clone()
+				clones = append(clones, c)
+				snapshots = append(snapshots, c.all())
+			}
+		}
+		check(tr)
+	}
+	if err := tr.Verify(); err != nil {
+		t.Fatal(err)
+	}
+	for i, c := range clones {
+		if got := c.all(); !reflect.DeepEqual(got, snapshots[i]) {
+			t.Fatalf("clone %d changed", i)
+		}
+		check(c)
+		for c.Len() > 0 {
+			c.DeleteMin()
+			check(c)
+		}
+	}
+}
+
+func TestEdgesRefreshedLazily(t *testing.T) {
+	tr := NewOrderedG[int](2)
+	for i := 0; i < 1000; i++ {
+		tr.ReplaceOrInsert(i)
+	}
+	// Reads use, but never refill, a stale cache.
+	if min, _ := tr.Min(); min != 0 || !tr.edgesStale {
+		t.Fatalf("Min() = %d, edgesStale = %v", min, tr.edgesStale)
+	}
+	// DeleteMin refills it, and then keeps it fresh.
+	tr.DeleteMin()
+	tr.DeleteMin()
+	if tr.edgesStale || tr.minLeaf.items[0] != 2 {
+		t.Fatal("DeleteMin didn't refill the cache")
+	}
+	// A write elsewhere in the tree just marks it stale.
+	tr.Delete(500)
+	if !tr.edgesStale {
+		t.Fatal("Delete didn't mark the cache stale")
+	}
+	if min, _ := tr.Min(); min != 2 {
+		t.Errorf("Min() = %d, want 2", min)
+	}
+	if max, _ := tr.Max(); max != 999 {
+		t.Errorf("Max() = %d, want 999", max)
+	}
+}
+
+func BenchmarkDeleteMinReinsert(b *testing.B) {
+	tr := NewOrderedG[int](*btreeDegree)
+	for i := 0; i < 100000; i++ {
+		tr.ReplaceOrInsert(i)
+	}
+	next := 100000
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tr.DeleteMin()
+		tr.ReplaceOrInsert(next)
+		next++
+	}
+}
@@ -0,0 +1,181 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+import (
+	"errors"
+	"fmt"
+)
+
+// The functions in this file are variants of the constructors and core
+// operations that return errors instead of panicking, for programs that
+// can't allow bad configuration or a misbehaving LessFunc to crash them.
+
+var (
+	// ErrBadDegree is returned when creating a tree with a degree less than 2.
+	ErrBadDegree = errors.New("btree: bad degree")
+	// ErrNilLess is returned when creating a tree without a LessFunc.
+	ErrNilLess = errors.New("btree: nil LessFunc")
+)
+
+// PanicError is returned by the error-returning operations, such as
+// ReplaceOrInsertE, when the operation panics.  This most often happens
+// because the tree's LessFunc panicked, for example when comparing a nil
+// item.
+type PanicError struct {
+	// Op is the name of the operation that panicked.
+	Op string
+	// Value is the value passed to panic.
+	Value interface{}
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("btree: panic in %v: %v", e.Op, e.Value)
+}
+
+// Unwrap returns the panic value if it is an error, so that errors.Is and
+// errors.As can see through a PanicError.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// LessPanicError is the panic value used by trees created with
+// WithLessRecovery when their LessFunc panics, recording the items it was
+// comparing.
+type LessPanicError struct {
+	// A and B are the items passed to the LessFunc, in order.
+	A, B interface{}
+	// Value is the value the LessFunc passed to panic.
+	Value interface{}
+}
+
+func (e *LessPanicError) Error() string {
+	return fmt.Sprintf("btree: LessFunc panicked comparing %v and %v: %v", e.A, e.B, e.Value)
+}
+
+// Unwrap returns the panic value if it is an error.
+func (e *LessPanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// WithLessRecovery makes the tree recover panics in its LessFunc and panic
+// again with a *LessPanicError naming the two items being compared, so that
+// a crash points at the items involved rather than at a line deep inside
+// the tree's internals.  Combined with the error-returning operations, such
+// as ReplaceOrInsertE, the *LessPanicError is returned as an error:
+//
+//	_, _, err := tr.ReplaceOrInsertE(item)
+//	var lerr *btree.LessPanicError
+//	if errors.As(err, &lerr) {
+//		log.Printf("bad item %v or %v", lerr.A, lerr.B)
+//	}
+//
+// The deferred recover adds a little to the cost of every comparison.
+func WithLessRecovery[T any]() Option[T] {
+	return func(o *options[T]) { o.recoverLess = true }
+}
+
+// recoveringLess wraps less so that a panic in it is replaced by a
+// *LessPanicError.
+func recoveringLess[T any](less LessFunc[T]) LessFunc[T] {
+	return func(a, b T) bool {
+		defer func() {
+			if r := recover(); r != nil {
+				panic(&LessPanicError{A: a, B: b, Value: r})
+			}
+		}()
+		return less(a, b)
+	}
+}
+
+// recoverAs is deferred by the error-returning operations to turn a panic
+// into a *PanicError stored in *err.
+func recoverAs(op string, err *error) {
+	if r := recover(); r != nil {
+		*err = &PanicError{Op: op, Value: r}
+	}
+}
+
+// checkConfig returns an error if degree and less can't make a valid tree.
+func checkConfig[T any](degree int, less LessFunc[T]) error {
+	if degree <= 1 {
+		return ErrBadDegree
+	}
+	if less == nil {
+		return ErrNilLess
+	}
+	return nil
+}
+
+// NewGE is like NewG, but returns an error instead of panicking if degree is
+// less than 2 or less is nil.
+func NewGE[T any](degree int, less LessFunc[T]) (*BTreeG[T], error) {
+	return NewWithFreeListGE(degree, less, NewFreeListG[T](DefaultFreeListSize))
+}
+
+// NewOrderedGE is like NewOrderedG, but returns an error instead of
+// panicking if degree is less than 2.
+func NewOrderedGE[T Ordered](degree int) (*BTreeG[T], error) {
+	return NewGE(degree, Less[T]())
+}
+
+// NewWithFreeListGE is like NewWithFreeListG, but returns an error instead of
+// panicking if degree is less than 2 or less is nil.
+func NewWithFreeListGE[T any](degree int, less LessFunc[T], f *FreeListG[T]) (*BTreeG[T], error) {
+	if err := checkConfig(degree, less); err != nil {
+		return nil, err
+	}
+	return NewWithFreeListG(degree, less, f), nil
+}
+
+// NewWithOptionsE is like NewWithOptions, but returns an error instead of
+// panicking if the options are invalid.
+func NewWithOptionsE[T any](opts ...Option[T]) (*BTreeG[T], error) {
+	o := applyOptions(opts)
+	if err := checkConfig(o.degree, o.less); err != nil {
+		return nil, err
+	}
+	return newFromOptions(o), nil
+}
+
+// ReplaceOrInsertE is like ReplaceOrInsert, but returns a *PanicError instead
+// of panicking.  If it returns an error the tree may have been left partially
+// modified, and should be checked with Verify or discarded.
+func (t *BTreeG[T]) ReplaceOrInsertE(item T) (old T, replaced bool, err error) {
+	defer recoverAs("ReplaceOrInsert", &err)
+	old, replaced = t.ReplaceOrInsert(item)
+	return
+}
+
+// DeleteE is like Delete, but returns a *PanicError instead of panicking.  If
+// it returns an error the tree may have been left partially modified, and
+// should be checked with Verify or discarded.
+func (t *BTreeG[T]) DeleteE(item T) (old T, deleted bool, err error) {
+	defer recoverAs("Delete", &err)
+	old, deleted = t.Delete(item)
+	return
+}
+
+// GetE is like Get, but returns a *PanicError instead of panicking.
+func (t *BTreeG[T]) GetE(key T) (item T, found bool, err error) {
+	defer recoverAs("Get", &err)
+	item, found = t.Get(key)
+	return
+}
@@ -0,0 +1,106 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestConstructorErrors(t *testing.T) {
+	if _, err := NewOrderedGE[int](1); err != ErrBadDegree {
+		t.Errorf("NewOrderedGE(1): got %v, want ErrBadDegree", err)
+	}
+	if _, err := NewGE[int](2, nil); err != ErrNilLess {
+		t.Errorf("NewGE(2, nil): got %v, want ErrNilLess", err)
+	}
+	if _, err := NewWithOptionsE[int](); err != ErrNilLess {
+		t.Errorf("NewWithOptionsE(): got %v, want ErrNilLess", err)
+	}
+	if tr, err := NewWithOptionsE(WithOrdered[int]()); err != nil || tr == nil {
+		t.Errorf("NewWithOptionsE(WithOrdered): got %v, %v", tr, err)
+	}
+	calls := 0
+	counting := func(o *options[int]) { calls++ }
+	if _, err := NewWithOptionsE(WithOrdered[int](), counting); err != nil || calls != 1 {
+		t.Errorf("NewWithOptionsE applied an option %v times, want 1", calls)
+	}
+}
+
+func TestOperationErrors(t *testing.T) {
+	type rec struct{ key int }
+	tr, err := NewGE(2, func(a, b *rec) bool { return a.key < b.key })
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 10; i++ {
+		if _, _, err := tr.ReplaceOrInsertE(&rec{i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Comparing a nil *rec dereferences nil inside the LessFunc.
+	_, _, err = tr.ReplaceOrInsertE(nil)
+	var perr *PanicError
+	if !errors.As(err, &perr) || perr.Op != "ReplaceOrInsert" {
+		t.Fatalf("ReplaceOrInsertE(nil): got %v", err)
+	}
+	if _, _, err := tr.GetE(nil); err == nil {
+		t.Error("GetE(nil) succeeded")
+	}
+	if _, _, err := tr.DeleteE(nil); err == nil {
+		t.Error("DeleteE(nil) succeeded")
+	}
+	if item, ok, err := tr.GetE(&rec{3}); err != nil || !ok || item.key != 3 {
+		t.Errorf("GetE(3) = %v, %v, %v", item, ok, err)
+	}
+	if err := tr.Verify(); err != nil {
+		t.Errorf("failed reads and writes corrupted the tree: %v", err)
+	}
+}
+
+func TestLessRecovery(t *testing.T) {
+	type rec struct{ key int }
+	tr := NewWithOptions(WithLess(func(a, b *rec) bool { return a.key < b.key }), WithLessRecovery[*rec]())
+	for i := 0; i < 10; i++ {
+		tr.ReplaceOrInsert(&rec{i})
+	}
+	_, _, err := tr.ReplaceOrInsertE(nil)
+	var lerr *LessPanicError
+	if !errors.As(err, &lerr) {
+		t.Fatalf("ReplaceOrInsertE(nil): got %v, want a *LessPanicError", err)
+	}
+	if lerr.A != (*rec)(nil) && lerr.B != (*rec)(nil) {
+		t.Errorf("operands %v and %v don't include the nil item", lerr.A, lerr.B)
+	}
+	// The runtime error for the nil dereference is still reachable.
+	var rerr interface{ RuntimeError() }
+	if !errors.As(err, &rerr) {
+		t.Errorf("%v doesn't wrap the runtime error", err)
+	}
+	expectPanic(t, "LessFunc panicked comparing", func() { tr.Get(nil) })
+}
+
+func TestLessRecoveryAfterResort(t *testing.T) {
+	type rec struct{ key, rank int }
+	tr := NewWithOptions(WithLess(func(a, b *rec) bool { return a.key < b.key }), WithLessRecovery[*rec]())
+	for i := 0; i < 10; i++ {
+		tr.ReplaceOrInsert(&rec{i, -i})
+	}
+	byRank := tr.Resort(func(a, b *rec) bool { return a.rank < b.rank })
+	expectPanic(t, "LessFunc panicked comparing", func() { byRank.Get(nil) })
+}
@@ -0,0 +1,129 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+// Extent is the half-open range [Start, End).
+type Extent struct {
+	Start, End int64
+}
+
+// extentSummary summarizes a run of extents: where it starts and ends, and
+// the largest gap between consecutive extents within it.
+type extentSummary struct {
+	start, end int64
+	gap        int64
+}
+
+var extentAugment = Augment[Extent, extentSummary]{
+	Of: func(e Extent) extentSummary {
+		return extentSummary{start: e.Start, end: e.End}
+	},
+	Combine: func(a, b extentSummary) extentSummary {
+		out := extentSummary{start: a.start, end: b.end, gap: a.gap}
+		if b.gap > out.gap {
+			out.gap = b.gap
+		}
+		if gap := b.start - a.end; gap > out.gap {
+			out.gap = gap
+		}
+		return out
+	},
+}
+
+// Extents tracks the allocated extents of a space, such as the used regions
+// of a file, or the IDs or ports in use, and finds free gaps between them in
+// O(log n) time rather than by scanning.
+//
+// Extents must not overlap.  Write operations are not safe for concurrent
+// mutation by multiple goroutines, but Read operations are.
+type Extents struct {
+	t      *AugmentedG[Extent, extentSummary]
+	lo, hi int64
+}
+
+// NewExtents returns an empty set of extents within the space [lo, hi).
+func NewExtents(degree int, lo, hi int64) *Extents {
+	less := func(a, b Extent) bool { return a.Start < b.Start }
+	return &Extents{t: NewAugmentedG(degree, less, extentAugment), lo: lo, hi: hi}
+}
+
+// Len returns the number of extents.
+func (x *Extents) Len() int {
+	return x.t.Len()
+}
+
+// Insert adds e, replacing any extent with the same start.  It panics if e is
+// empty or outside the space.
+func (x *Extents) Insert(e Extent) {
+	if e.End <= e.Start || e.Start < x.lo || e.End > x.hi {
+		panic("bad extent")
+	}
+	x.t.ReplaceOrInsert(e)
+}
+
+// Remove removes the extent starting at start, returning it.
+func (x *Extents) Remove(start int64) (Extent, bool) {
+	return x.t.Delete(Extent{Start: start})
+}
+
+// Get returns the extent starting at start.
+func (x *Extents) Get(start int64) (Extent, bool) {
+	return x.t.Get(Extent{Start: start})
+}
+
+// Ascend calls iter for each extent in order, until iter returns false.
+func (x *Extents) Ascend(iter ItemIteratorG[Extent]) {
+	x.t.Ascend(iter)
+}
+
+// FirstGap returns the start of the lowest gap between extents, or between
+// an extent and the edge of the space, of at least minSize.
+func (x *Extents) FirstGap(minSize int64) (start int64, ok bool) {
+	first, ok := x.t.Min()
+	if !ok {
+		return x.lo, x.hi-x.lo >= minSize
+	}
+	if first.Start-x.lo >= minSize {
+		return x.lo, true
+	}
+	// The first extent whose preceding gap is large enough is the first at
+	// which the largest gap so far is.
+	next, ok := x.t.Search(func(s extentSummary) bool { return s.gap >= minSize })
+	if ok {
+		x.t.DescendLessOrEqual(Extent{Start: next.Start - 1}, func(prev Extent) bool {
+			start = prev.End
+			return false
+		})
+		return start, true
+	}
+	last, _ := x.t.Max()
+	return last.End, x.hi-last.End >= minSize
+}
+
+// Allocate inserts an extent of the given size in the first gap large
+// enough to hold it, returning its start.
+func (x *Extents) Allocate(size int64) (start int64, ok bool) {
+	if size <= 0 {
+		panic("bad extent")
+	}
+	start, ok = x.FirstGap(size)
+	if ok {
+		x.t.ReplaceOrInsert(Extent{start, start + size})
+	}
+	return start, ok
+}
@@ -0,0 +1,87 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// firstGapSlow is FirstGap by linear scan.
+func firstGapSlow(x *Extents, minSize int64) (int64, bool) {
+	pos := x.lo
+	var start int64
+	found := false
+	x.Ascend(func(e Extent) bool {
+		if e.Start-pos >= minSize {
+			start, found = pos, true
+			return false
+		}
+		pos = e.End
+		return true
+	})
+	if found {
+		return start, true
+	}
+	return pos, x.hi-pos >= minSize
+}
+
+func TestExtentsFirstGap(t *testing.T) {
+	x := NewExtents(2, 0, 1000)
+	if start, ok := x.FirstGap(1000); !ok || start != 0 {
+		t.Errorf("empty: FirstGap(1000) = %d, %v", start, ok)
+	}
+	if _, ok := x.FirstGap(1001); ok {
+		t.Errorf("empty: FirstGap(1001) succeeded")
+	}
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 2000; i++ {
+		switch r.Intn(3) {
+		case 0, 1:
+			x.Allocate(int64(r.Intn(5) + 1))
+		case 2:
+			x.Remove(int64(r.Intn(1000)))
+		}
+		size := int64(r.Intn(20) + 1)
+		gotStart, gotOK := x.FirstGap(size)
+		wantStart, wantOK := firstGapSlow(x, size)
+		if gotOK != wantOK || (gotOK && gotStart != wantStart) {
+			t.Fatalf("op %d: FirstGap(%d) = %d, %v; want %d, %v", i, size, gotStart, gotOK, wantStart, wantOK)
+		}
+	}
+}
+
+func TestExtentsAllocate(t *testing.T) {
+	x := NewExtents(3, 10, 20)
+	for want := int64(10); want < 20; want += 2 {
+		if got, ok := x.Allocate(2); !ok || got != want {
+			t.Fatalf("Allocate(2) = %d, %v; want %d", got, ok, want)
+		}
+	}
+	if _, ok := x.Allocate(1); ok {
+		t.Fatal("allocated in a full space")
+	}
+	x.Remove(14)
+	if got, ok := x.Allocate(1); !ok || got != 14 {
+		t.Errorf("Allocate(1) = %d, %v; want 14", got, ok)
+	}
+	if got, ok := x.FirstGap(1); !ok || got != 15 {
+		t.Errorf("FirstGap(1) = %d, %v; want 15", got, ok)
+	}
+	expectPanic(t, "bad extent", func() { x.Insert(Extent{5, 12}) })
+}
@@ -0,0 +1,237 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+// ExtractRange returns a new tree containing the items of t within the range
+// [greaterOrEqual, lessThan).  t is unchanged.
+//
+// The new tree shares every node that lies wholly within the range with t,
+// copy-on-write as with Clone, and builds new nodes only along the two
+// boundaries of the range.  Building it takes O(log n) time, however large
+// the range, plus a walk over the shared nodes (but not their items) to
+// count them.  This makes it cheap to hand out views of part of a large
+// tree, such as one tenant's keys.  The new tree shares t's configuration,
+// except that it has no views.
+//
+// Like Clone, ExtractRange should not be called concurrently with other
+// operations on t.
+func (t *BTreeG[T]) ExtractRange(greaterOrEqual, lessThan T) *BTreeG[T] {
+	// As in Clone, give t a new context, so that the nodes it shares with
+	// the new tree become read-only to both.
+	cow1, cow2 := *t.cow, *t.cow
+	t.cow = &cow1
+	t.shared = true
+	out := &BTreeG[T]{degree: t.degree, cow: &cow2, limit: t.limit, shared: true}
+	j := joiner[T]{t: out}
+	if t.root != nil && t.cow.less(greaterOrEqual, lessThan) {
+		s := subtree[T]{t.root, height(t.root)}.normalize()
+		if s.n != nil {
+			s = j.fromKey(s, greaterOrEqual)
+		}
+		if s.n != nil {
+			s = j.beforeKey(s, lessThan)
+		}
+		out.root = s.n
+	}
+	if out.root != nil {
+		out.length = countItems(out.root)
+		if out.limit != nil {
+			out.ascend(func(item T) bool {
+				out.usage += out.limit.weigh(item)
+				return true
+			})
+		}
+	}
+	out.refresh()
+	return out
+}
+
+// subtree is a node and its height (0 for a leaf).  The node is the root of
+// a valid tree, except that it may have fewer than the minimum number of
+// items.  A nil node is an empty subtree.
+type subtree[T any] struct {
+	n *node[T]
+	h int
+}
+
+func height[T any](n *node[T]) (h int) {
+	for ; len(n.children) > 0; n = n.children[0] {
+		h++
+	}
+	return h
+}
+
+func countItems[T any](n *node[T]) int {
+	count := len(n.items)
+	for _, c := range n.children {
+		count += countItems(c)
+	}
+	return count
+}
+
+// normalize removes empty nodes from the top of s.
+func (s subtree[T]) normalize() subtree[T] {
+	for s.n != nil && len(s.n.items) == 0 {
+		if len(s.n.children) == 0 {
+			return subtree[T]{}
+		}
+		s = subtree[T]{s.n.children[0], s.h - 1}
+	}
+	return s
+}
+
+// joiner splits and joins subtrees.  Nodes it builds or modifies belong to
+// t, which is otherwise only used for its configuration.
+type joiner[T any] struct {
+	t *BTreeG[T]
+}
+
+// newNode returns a new node holding copies of items and children.
+func (j joiner[T]) newNode(items []T, children []*node[T]) *node[T] {
+	n := j.t.cow.newNode()
+	n.items = append(n.items, items...)
+	n.children = append(n.children, children...)
+	return n
+}
+
+// fromKey returns the subtree of the items of s not less than key.
+func (j joiner[T]) fromKey(s subtree[T], key T) subtree[T] {
+	i, _ := s.n.find(key)
+	if len(s.n.children) == 0 {
+		return subtree[T]{j.newNode(s.n.items[i:], nil), 0}.normalize()
+	}
+	sub := j.fromKey(subtree[T]{s.n.children[i], s.h - 1}, key).normalize()
+	if i == len(s.n.items) {
+		return sub
+	}
+	rest := subtree[T]{j.newNode(s.n.items[i+1:], s.n.children[i+1:]), s.h}.normalize()
+	return j.join(sub, s.n.items[i], rest)
+}
+
+// beforeKey returns the subtree of the items of s less than key.
+func (j joiner[T]) beforeKey(s subtree[T], key T) subtree[T] {
+	i, _ := s.n.find(key)
+	if len(s.n.children) == 0 {
+		return subtree[T]{j.newNode(s.n.items[:i], nil), 0}.normalize()
+	}
+	sub := j.beforeKey(subtree[T]{s.n.children[i], s.h - 1}, key).normalize()
+	if i == 0 {
+		return sub
+	}
+	rest := subtree[T]{j.newNode(s.n.items[:i-1], s.n.children[:i]), s.h}.normalize()
+	return j.join(rest, s.n.items[i-1], sub)
+}
+
+// join returns a subtree holding the items of a, then sep, then the items of
+// b.  Every item of a must be less than sep, which must be less than every
+// item of b.
+func (j joiner[T]) join(a subtree[T], sep T, b subtree[T]) subtree[T] {
+	var n1, n2 *node[T]
+	var split bool
+	h := a.h
+	switch {
+	case a.n == nil || b.n == nil:
+		// Insert sep into the other subtree, as its minimum or maximum.
+		tmp := &BTreeG[T]{degree: j.t.degree, cow: j.t.cow}
+		if a.n != nil {
+			tmp.root = a.n
+		} else if b.n != nil {
+			tmp.root = b.n
+		}
+		tmp.ReplaceOrInsert(sep)
+		return subtree[T]{tmp.root, height(tmp.root)}
+	case a.h == b.h:
+		n1, sep, n2, split = j.joinLevel(a.n, sep, b.n)
+	case a.h > b.h:
+		n1, sep, n2, split = j.joinRight(a, sep, b)
+	default:
+		n1, sep, n2, split = j.joinLeft(a, sep, b)
+		h = b.h
+	}
+	if !split {
+		return subtree[T]{n1, h}
+	}
+	return subtree[T]{j.newNode([]T{sep}, []*node[T]{n1, n2}), h + 1}
+}
+
+// joinLevel joins two nodes of the same height into one node or, if their
+// items don't fit in one, two evenly filled ones separated by sep.  Either
+// way, the resulting nodes have at least the minimum number of items if a or
+// b did.
+func (j joiner[T]) joinLevel(a *node[T], sep T, b *node[T]) (*node[T], T, *node[T], bool) {
+	n := j.newNode(a.items, a.children)
+	n.items = append(n.items, sep)
+	n.items = append(n.items, b.items...)
+	n.children = append(n.children, b.children...)
+	if len(n.items) <= j.t.maxItems() {
+		return n, sep, nil, false
+	}
+	sep, n2 := n.split(len(n.items) / 2)
+	return n, sep, n2, true
+}
+
+// joinRight joins b, which is shorter than a, onto a's right edge.  The
+// result is a's (modified) root or, if that had to split, two nodes and the
+// item between them.
+func (j joiner[T]) joinRight(a subtree[T], sep T, b subtree[T]) (*node[T], T, *node[T], bool) {
+	n := a.n.mutableFor(j.t.cow)
+	last := len(n.children) - 1
+	var n1, n2 *node[T]
+	var split bool
+	if a.h-1 == b.h {
+		n1, sep, n2, split = j.joinLevel(n.children[last], sep, b.n)
+	} else {
+		n1, sep, n2, split = j.joinRight(subtree[T]{n.children[last], a.h - 1}, sep, b)
+	}
+	n.children[last] = n1
+	if split {
+		n.items = append(n.items, sep)
+		n.children = append(n.children, n2)
+	}
+	return j.splitIfFull(n)
+}
+
+// joinLeft joins a, which is shorter than b, onto b's left edge, like
+// joinRight.
+func (j joiner[T]) joinLeft(a subtree[T], sep T, b subtree[T]) (*node[T], T, *node[T], bool) {
+	n := b.n.mutableFor(j.t.cow)
+	var n1, n2 *node[T]
+	var split bool
+	if b.h-1 == a.h {
+		n1, sep, n2, split = j.joinLevel(a.n, sep, n.children[0])
+	} else {
+		n1, sep, n2, split = j.joinLeft(a, sep, subtree[T]{n.children[0], b.h - 1})
+	}
+	n.children[0] = n1
+	if split {
+		n.items.insertAt(0, sep)
+		n.children.insertAt(1, n2)
+	}
+	return j.splitIfFull(n)
+}
+
+// splitIfFull splits n in two if it has more than the maximum number of
+// items, which can only be by one.
+func (j joiner[T]) splitIfFull(n *node[T]) (*node[T], T, *node[T], bool) {
+	if len(n.items) <= j.t.maxItems() {
+		var zero T
+		return n, zero, nil, false
+	}
+	sep, n2 := n.split(len(n.items) / 2)
+	return n, sep, n2, true
+}
@@ -0,0 +1,74 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestExtractRange(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for _, degree := range []int{2, 3, 5} {
+		for _, size := range []int{0, 1, 10, 100, 2000} {
+			tr := NewOrderedG[int](degree)
+			for _, v := range r.Perm(size) {
+				tr.ReplaceOrInsert(v * 2)
+			}
+			before := tr.all()
+			for trial := 0; trial < 30; trial++ {
+				lo, hi := r.Intn(2*size+10)-5, r.Intn(2*size+10)-5
+				out := tr.ExtractRange(lo, hi)
+				if err := out.Verify(); err != nil {
+					t.Fatalf("degree %d, size %d, [%d, %d): %v", degree, size, lo, hi, err)
+				}
+				var want []int
+				tr.AscendRange(lo, hi, func(v int) bool {
+					want = append(want, v)
+					return true
+				})
+				if got := out.all(); len(got)+len(want) > 0 && !reflect.DeepEqual(got, want) {
+					t.Fatalf("degree %d, size %d, [%d, %d): got %v, want %v", degree, size, lo, hi, got, want)
+				}
+				// Writes to either tree must not affect the other.
+				out.ReplaceOrInsert(-1)
+				out.DeleteMax()
+				tr.ReplaceOrInsert(lo | 1)
+				tr.Delete(lo | 1)
+				if err := out.Verify(); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got := tr.all(); !reflect.DeepEqual(got, before) {
+				t.Fatalf("source changed")
+			}
+		}
+	}
+}
+
+func TestExtractRangeAugmented(t *testing.T) {
+	tr := NewAugmentedG(2, Less[int](), sumCountAug)
+	for _, v := range rand.Perm(500) {
+		tr.ReplaceOrInsert(v)
+	}
+	out := &AugmentedG[int, sumCount]{BTreeG: tr.ExtractRange(100, 200), aug: tr.aug}
+	if got, _ := out.Summary(); got.n != 100 || got.sum != (100+199)*50 {
+		t.Errorf("extracted summary %v", got)
+	}
+}
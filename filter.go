@@ -0,0 +1,35 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+// CloneFiltered returns a new tree containing the items of t for which pred
+// returns true, leaving t unchanged.  It makes a single ascending pass over t
+// and builds the new tree bottom-up with packed nodes, so it takes O(n) time
+// and never compares items.  The new tree shares t's configuration; its
+// views are rebuilt from its items.
+func (t *BTreeG[T]) CloneFiltered(pred func(T) bool) *BTreeG[T] {
+	var items []T
+	t.ascend(func(item T) bool {
+		if pred(item) {
+			items = append(items, item)
+		}
+		return true
+	})
+	cow := *t.cow
+	return t.built(&cow, items)
+}
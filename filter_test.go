@@ -0,0 +1,52 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestCloneFiltered(t *testing.T) {
+	tr := NewOrderedG[int](*btreeDegree)
+	for _, v := range rand.Perm(500) {
+		tr.ReplaceOrInsert(v)
+	}
+	even := tr.CloneFiltered(func(v int) bool { return v%2 == 0 })
+	if err := even.Verify(); err != nil {
+		t.Fatal(err)
+	}
+	if even.Len() != 250 || tr.Len() != 500 {
+		t.Fatalf("lengths %d, %d; want 250, 500", even.Len(), tr.Len())
+	}
+	i := 0
+	even.Ascend(func(v int) bool {
+		if v != i*2 {
+			t.Fatalf("item %d is %d", i, v)
+		}
+		i++
+		return true
+	})
+	even.ReplaceOrInsert(1)
+	if tr.Len() != 500 || !even.Has(1) {
+		t.Error("filtered tree not independent")
+	}
+	if none := tr.CloneFiltered(func(int) bool { return false }); none.Len() != 0 {
+		t.Errorf("empty filter kept %d items", none.Len())
+	}
+}
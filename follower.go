@@ -0,0 +1,152 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// AppendOpBatch appends the encoding of a batch of ops, as passed to Apply,
+// to dst, with items encoded by codec, and returns the extended buffer.
+// seq is the batch's sequence number, which a FollowerG uses to apply
+// batches in order and detect missing ones; a leader should number its
+// batches consecutively.
+//
+// The encoding is the sequence number and op count, as uvarints, followed
+// by each op's kind (1 byte), Item, and, for an OpDeleteRange, End.
+func AppendOpBatch[T any](dst []byte, seq uint64, ops []Op[T], codec ItemCodec[T]) (_ []byte, err error) {
+	var buf [binary.MaxVarintLen64]byte
+	dst = append(dst, buf[:binary.PutUvarint(buf[:], seq)]...)
+	dst = append(dst, buf[:binary.PutUvarint(buf[:], uint64(len(ops)))]...)
+	for _, op := range ops {
+		dst = append(dst, byte(op.Kind))
+		if dst, err = codec.Append(dst, op.Item); err != nil {
+			return dst, err
+		}
+		if op.Kind == OpDeleteRange {
+			if dst, err = codec.Append(dst, op.End); err != nil {
+				return dst, err
+			}
+		}
+	}
+	return dst, nil
+}
+
+// DecodeOpBatch decodes a batch written by AppendOpBatch from the start of
+// src, returning its sequence number, its ops, and the length of its
+// encoding.
+func DecodeOpBatch[T any](src []byte, codec ItemCodec[T]) (seq uint64, ops []Op[T], n int, err error) {
+	seq, n = binary.Uvarint(src)
+	if n <= 0 {
+		return 0, nil, 0, varintError(n)
+	}
+	count, size := binary.Uvarint(src[n:])
+	if size <= 0 {
+		return 0, nil, 0, varintError(size)
+	}
+	n += size
+	for ; count > 0; count-- {
+		if n == len(src) {
+			return 0, nil, 0, ErrTruncated
+		}
+		op := Op[T]{Kind: OpKind(src[n])}
+		if op.Kind < OpInsert || op.Kind > OpDeleteRange {
+			return 0, nil, 0, fmt.Errorf("btree: unknown op kind %d", op.Kind)
+		}
+		n++
+		if op.Item, size, err = codec.Decode(src[n:]); err != nil {
+			return 0, nil, 0, err
+		}
+		n += size
+		if op.Kind == OpDeleteRange {
+			if op.End, size, err = codec.Decode(src[n:]); err != nil {
+				return 0, nil, 0, err
+			}
+			n += size
+		}
+		ops = append(ops, op)
+	}
+	return seq, ops, n, nil
+}
+
+// GapError is returned by FollowerG.Consume when a batch arrives before
+// some of the batches preceding it, so the follower can't apply it.  The
+// follower must be sent the missing batches, or be resynchronized from a
+// snapshot.
+type GapError struct {
+	// Want is the sequence number of the next batch the follower needs.
+	Want uint64
+	// Got is the sequence number of the batch it was given.
+	Got uint64
+}
+
+func (e *GapError) Error() string {
+	return fmt.Sprintf("btree: change feed gap: want batch %d, got %d", e.Want, e.Got)
+}
+
+// FollowerG maintains a replica of a leader's tree by applying the batches
+// of ops the leader encoded with AppendOpBatch, in sequence order.
+//
+// A FollowerG is not safe for concurrent use, and its tree must not be
+// written other than through it.  To read the replica while it is being
+// updated, read clones of Tree.
+type FollowerG[T any] struct {
+	t     *BTreeG[T]
+	codec ItemCodec[T]
+	next  uint64
+}
+
+// NewFollowerG returns a follower that keeps t up to date, starting with
+// the batch numbered next.  t must hold the leader's items as of just
+// before that batch, such as a tree restored from a snapshot, or be empty
+// if next is the leader's first batch.
+func NewFollowerG[T any](t *BTreeG[T], codec ItemCodec[T], next uint64) *FollowerG[T] {
+	return &FollowerG[T]{t: t, codec: codec, next: next}
+}
+
+// Tree returns the replica.
+func (f *FollowerG[T]) Tree() *BTreeG[T] {
+	return f.t
+}
+
+// Next returns the sequence number of the next batch the follower needs.
+func (f *FollowerG[T]) Next() uint64 {
+	return f.next
+}
+
+// Consume decodes a batch encoded by AppendOpBatch and applies it to the
+// replica, returning the length of its encoding.  A batch that has already
+// been applied is skipped, so a feed may be safely replayed from an earlier
+// point.  A batch from after the next one needed is not applied, and
+// Consume returns a *GapError.  If Consume returns any error, the replica
+// is unchanged.
+func (f *FollowerG[T]) Consume(batch []byte) (int, error) {
+	seq, ops, n, err := DecodeOpBatch(batch, f.codec)
+	switch {
+	case err != nil:
+		return 0, err
+	case seq < f.next:
+		return n, nil
+	case seq > f.next:
+		return n, &GapError{Want: f.next, Got: seq}
+	}
+	f.t.Apply(ops)
+	f.next++
+	return n, nil
+}
@@ -0,0 +1,81 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+import (
+	"errors"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestFollower(t *testing.T) {
+	codec := OrderedCodec[int]()
+	leader := NewOrderedG[int](*btreeDegree)
+	var feed [][]byte
+	for seq := uint64(1); seq <= 200; seq++ {
+		var ops []Op[int]
+		for i := rand.Intn(10); i >= 0; i-- {
+			x := rand.Intn(1000)
+			switch rand.Intn(5) {
+			case 0:
+				ops = append(ops, Op[int]{Kind: OpDelete, Item: x})
+			case 1:
+				ops = append(ops, Op[int]{Kind: OpDeleteRange, Item: x, End: x + rand.Intn(20)})
+			default:
+				ops = append(ops, Op[int]{Kind: OpInsert, Item: x})
+			}
+		}
+		leader.Apply(ops)
+		batch, err := AppendOpBatch(nil, seq, ops, codec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		feed = append(feed, batch)
+	}
+
+	f := NewFollowerG(NewOrderedG[int](*btreeDegree), codec, 1)
+	for i, batch := range feed {
+		if i == 100 {
+			// Skip a batch, then deliver it and replay some already applied.
+			var gap *GapError
+			if _, err := f.Consume(feed[i+1]); !errors.As(err, &gap) || gap.Want != 101 || gap.Got != 102 {
+				t.Fatalf("Consume of batch 102 before 101 returned %v", err)
+			}
+			for _, old := range feed[90:100] {
+				if _, err := f.Consume(old); err != nil {
+					t.Fatalf("replaying an applied batch: %v", err)
+				}
+			}
+		}
+		n, err := f.Consume(batch)
+		if err != nil || n != len(batch) {
+			t.Fatalf("Consume(batch %d) = %d, %v", i+1, n, err)
+		}
+	}
+	if f.Next() != 201 {
+		t.Errorf("Next() = %d, want 201", f.Next())
+	}
+	if got, want := f.Tree().all(), leader.all(); !reflect.DeepEqual(got, want) {
+		t.Errorf("replica holds %v, leader %v", got, want)
+	}
+
+	if _, err := f.Consume(feed[0][:len(feed[0])-1]); err == nil {
+		t.Error("Consume accepted a truncated batch")
+	}
+}
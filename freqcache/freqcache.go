@@ -0,0 +1,188 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+// Package freqcache provides a bounded cache that evicts entries by how often
+// they have been used.
+//
+// A Cache keeps a map from key to entry for lookups, and a btree.BTreeG of
+// the same entries ordered by use count for eviction.  The tree's LessFunc
+// must be a strict weak ordering even though many entries share a count;
+// getting that wrong is the usual way hand-rolled versions of this pattern
+// corrupt their trees.  Here ties are broken by the time of last use, which
+// is unique per entry, so the ordering is total.
+package freqcache
+
+import "github.com/google/btree"
+
+// Policy chooses which entry a full Cache evicts.
+type Policy int
+
+const (
+	// LFU evicts the least frequently used entry.
+	LFU Policy = iota
+	// MFU evicts the most frequently used entry.
+	MFU
+)
+
+// Entry is a cached key and value, with the number of times it has been used.
+type Entry[K comparable, V any] struct {
+	Key   K
+	Value V
+	Freq  uint64
+}
+
+type entry[K comparable, V any] struct {
+	Entry[K, V]
+	tick uint64 // when the entry was last used; unique within a Cache
+}
+
+// Cache is a bounded cache that evicts entries by use count.  Among entries
+// with equal counts, the least recently used is evicted first.
+//
+// A Cache is not safe for concurrent use.
+type Cache[K comparable, V any] struct {
+	capacity int
+	policy   Policy
+	tick     uint64
+	entries  map[K]*entry[K, V]
+	byFreq   *btree.BTreeG[*entry[K, V]]
+}
+
+// New returns an empty cache holding at most capacity entries.  It panics if
+// capacity is not positive.
+func New[K comparable, V any](capacity int, policy Policy) *Cache[K, V] {
+	if capacity <= 0 {
+		panic("bad capacity")
+	}
+	less := func(a, b *entry[K, V]) bool {
+		if a.Freq != b.Freq {
+			return a.Freq < b.Freq
+		}
+		return a.tick < b.tick
+	}
+	if policy == MFU {
+		// Order ties by descending recency, so the maximum is the most
+		// used entry and, among those, the least recently used.
+		less = func(a, b *entry[K, V]) bool {
+			if a.Freq != b.Freq {
+				return a.Freq < b.Freq
+			}
+			return a.tick > b.tick
+		}
+	}
+	return &Cache[K, V]{
+		capacity: capacity,
+		policy:   policy,
+		entries:  make(map[K]*entry[K, V]),
+		byFreq:   btree.NewG(btree.DefaultDegree, less),
+	}
+}
+
+// Len returns the number of entries in the cache.
+func (c *Cache[K, V]) Len() int {
+	return len(c.entries)
+}
+
+// Get returns the value for key, counting it as a use.
+func (c *Cache[K, V]) Get(key K) (_ V, _ bool) {
+	e, ok := c.entries[key]
+	if !ok {
+		return
+	}
+	c.touch(e)
+	return e.Value, true
+}
+
+// Peek returns the entry for key without counting it as a use.
+func (c *Cache[K, V]) Peek(key K) (_ Entry[K, V], _ bool) {
+	e, ok := c.entries[key]
+	if !ok {
+		return
+	}
+	return e.Entry, true
+}
+
+// Put sets the value for key, counting it as a use.  If key is new and the
+// cache is full, Put first evicts an entry according to the cache's policy
+// and returns it.
+func (c *Cache[K, V]) Put(key K, value V) (evicted Entry[K, V], ok bool) {
+	if e, found := c.entries[key]; found {
+		e.Value = value
+		c.touch(e)
+		return
+	}
+	if len(c.entries) >= c.capacity {
+		evicted, ok = c.evict()
+	}
+	c.tick++
+	e := &entry[K, V]{Entry: Entry[K, V]{Key: key, Value: value, Freq: 1}, tick: c.tick}
+	c.entries[key] = e
+	c.byFreq.ReplaceOrInsert(e)
+	return evicted, ok
+}
+
+// Delete removes key from the cache, returning its entry.
+func (c *Cache[K, V]) Delete(key K) (_ Entry[K, V], _ bool) {
+	e, ok := c.entries[key]
+	if !ok {
+		return
+	}
+	delete(c.entries, key)
+	c.byFreq.Delete(e)
+	return e.Entry, true
+}
+
+// Victim returns the entry that would be evicted next, without evicting it.
+func (c *Cache[K, V]) Victim() (_ Entry[K, V], _ bool) {
+	var e *entry[K, V]
+	var ok bool
+	if c.policy == MFU {
+		e, ok = c.byFreq.Max()
+	} else {
+		e, ok = c.byFreq.Min()
+	}
+	if !ok {
+		return
+	}
+	return e.Entry, true
+}
+
+// evict removes and returns the entry chosen by the policy.
+func (c *Cache[K, V]) evict() (_ Entry[K, V], _ bool) {
+	var e *entry[K, V]
+	var ok bool
+	if c.policy == MFU {
+		e, ok = c.byFreq.DeleteMax()
+	} else {
+		e, ok = c.byFreq.DeleteMin()
+	}
+	if !ok {
+		return
+	}
+	delete(c.entries, e.Key)
+	return e.Entry, true
+}
+
+// touch records a use of e.  e's position in byFreq depends on its count and
+// tick, so it must be removed before either changes and reinserted after.
+func (c *Cache[K, V]) touch(e *entry[K, V]) {
+	c.byFreq.Delete(e)
+	c.tick++
+	e.Freq++
+	e.tick = c.tick
+	c.byFreq.ReplaceOrInsert(e)
+}
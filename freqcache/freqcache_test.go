@@ -0,0 +1,109 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package freqcache
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestLFU(t *testing.T) {
+	c := New[string, int](3, LFU)
+	c.Put("a", 1)
+	c.Put("b", 2)
+	c.Put("c", 3)
+	c.Get("a")
+	c.Get("a")
+	c.Get("c")
+	// b has the lowest count.
+	if e, ok := c.Put("d", 4); !ok || e.Key != "b" || e.Value != 2 || e.Freq != 1 {
+		t.Fatalf("evicted %+v, %v; want b", e, ok)
+	}
+	// d is new, with count 1; a and c have been used more.
+	if e, ok := c.Put("e", 5); !ok || e.Key != "d" {
+		t.Fatalf("evicted %+v, %v; want d", e, ok)
+	}
+	// Raise e to tie with c, and the less recently used of the two, c, goes
+	// first.
+	c.Get("e")
+	if e, ok := c.Put("f", 6); !ok || e.Key != "c" {
+		t.Fatalf("evicted %+v, %v; want c", e, ok)
+	}
+	if c.Len() != 3 {
+		t.Errorf("Len() = %d, want 3", c.Len())
+	}
+}
+
+func TestMFU(t *testing.T) {
+	c := New[int, int](2, MFU)
+	c.Put(1, 1)
+	c.Put(2, 2)
+	c.Get(1)
+	if v, ok := c.Victim(); !ok || v.Key != 1 || v.Freq != 2 {
+		t.Fatalf("Victim() = %+v, %v; want 1", v, ok)
+	}
+	if e, ok := c.Put(3, 3); !ok || e.Key != 1 {
+		t.Fatalf("evicted %+v, %v; want 1", e, ok)
+	}
+	// 2 and 3 tie; 2 was used less recently.
+	if e, ok := c.Put(4, 4); !ok || e.Key != 2 {
+		t.Fatalf("evicted %+v, %v; want 2", e, ok)
+	}
+}
+
+func TestPeekDelete(t *testing.T) {
+	c := New[int, string](2, LFU)
+	c.Put(1, "x")
+	c.Put(1, "y")
+	if e, ok := c.Peek(1); !ok || e.Value != "y" || e.Freq != 2 {
+		t.Fatalf("Peek(1) = %+v, %v", e, ok)
+	}
+	if e, _ := c.Peek(1); e.Freq != 2 {
+		t.Errorf("Peek counted as a use")
+	}
+	if _, ok := c.Delete(1); !ok {
+		t.Fatal("Delete(1) failed")
+	}
+	if _, ok := c.Get(1); ok || c.Len() != 0 {
+		t.Errorf("entry survived Delete")
+	}
+}
+
+func TestRandomConsistent(t *testing.T) {
+	for _, policy := range []Policy{LFU, MFU} {
+		c := New[int, int](50, policy)
+		r := rand.New(rand.NewSource(1))
+		for i := 0; i < 10000; i++ {
+			k := r.Intn(200)
+			switch r.Intn(3) {
+			case 0:
+				c.Put(k, i)
+			case 1:
+				c.Get(k)
+			case 2:
+				c.Delete(k)
+			}
+			if c.Len() != c.byFreq.Len() || c.Len() > 50 {
+				t.Fatalf("map has %d entries, tree %d", c.Len(), c.byFreq.Len())
+			}
+		}
+		if err := c.byFreq.Verify(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+import "sort"
+
+// FrozenTreeG is an immutable, read-optimized copy of a BTreeG.
+//
+// All of its items are stored in a single sorted slice, so the search
+// structure is implicit: there are no nodes, child pointers, or partially
+// filled slots, and scans read memory sequentially.  A FrozenTreeG typically
+// uses half the memory of the tree it was created from, and additionally
+// supports finding items by rank in O(1).
+//
+// A FrozenTreeG can't be modified, so it is safe for concurrent use by
+// multiple goroutines.
+type FrozenTreeG[T any] struct {
+	items []T
+	less  LessFunc[T]
+}
+
+// Freeze returns a FrozenTreeG containing the items currently in t.  Later
+// changes to t do not affect it.  Freeze takes O(n) time.
+func (t *BTreeG[T]) Freeze() *FrozenTreeG[T] {
+	start := t.traceStart()
+	f := &FrozenTreeG[T]{items: t.all(), less: t.cow.less}
+	t.traceEnd("Freeze", start, len(f.items))
+	return f
+}
+
+// search returns the index of the first item >= key.
+func (f *FrozenTreeG[T]) search(key T) int {
+	return sort.Search(len(f.items), func(i int) bool {
+		return !f.less(f.items[i], key)
+	})
+}
+
+// Len returns the number of items in the tree.
+func (f *FrozenTreeG[T]) Len() int {
+	return len(f.items)
+}
+
+// Get looks for the key item in the tree, returning it.  It returns
+// (zeroValue, false) if unable to find that item.
+func (f *FrozenTreeG[T]) Get(key T) (_ T, _ bool) {
+	i := f.search(key)
+	if i < len(f.items) && !f.less(key, f.items[i]) {
+		return f.items[i], true
+	}
+	return
+}
+
+// Has returns true if the given key is in the tree.
+func (f *FrozenTreeG[T]) Has(key T) bool {
+	_, ok := f.Get(key)
+	return ok
+}
+
+// Min returns the smallest item in the tree, or (zeroValue, false) if the tree is empty.
+func (f *FrozenTreeG[T]) Min() (_ T, _ bool) {
+	if len(f.items) == 0 {
+		return
+	}
+	return f.items[0], true
+}
+
+// Max returns the largest item in the tree, or (zeroValue, false) if the tree is empty.
+func (f *FrozenTreeG[T]) Max() (_ T, _ bool) {
+	if len(f.items) == 0 {
+		return
+	}
+	return f.items[len(f.items)-1], true
+}
+
+// Rank returns the number of items in the tree that are less than key.  If
+// key is in the tree, this is its index in ascending order.
+func (f *FrozenTreeG[T]) Rank(key T) int {
+	return f.search(key)
+}
+
+// At returns the item with the given rank, that is, the item at index i in
+// ascending order.  It panics if i is out of range.
+func (f *FrozenTreeG[T]) At(i int) T {
+	return f.items[i]
+}
+
+// ascend calls iterator for items[from:to] in ascending order.
+func (f *FrozenTreeG[T]) ascend(from, to int, iterator ItemIteratorG[T]) {
+	for _, item := range f.items[from:to] {
+		if !iterator(item) {
+			return
+		}
+	}
+}
+
+// descend calls iterator for items[from:to] in descending order.
+func (f *FrozenTreeG[T]) descend(from, to int, iterator ItemIteratorG[T]) {
+	for i := to - 1; i >= from; i-- {
+		if !iterator(f.items[i]) {
+			return
+		}
+	}
+}
+
+// AscendRange calls the iterator for every value in the tree within the range
+// [greaterOrEqual, lessThan), until iterator returns false.
+func (f *FrozenTreeG[T]) AscendRange(greaterOrEqual, lessThan T, iterator ItemIteratorG[T]) {
+	from, to := f.search(greaterOrEqual), f.search(lessThan)
+	if from < to {
+		f.ascend(from, to, iterator)
+	}
+}
+
+// AscendLessThan calls the iterator for every value in the tree within the range
+// [first, pivot), until iterator returns false.
+func (f *FrozenTreeG[T]) AscendLessThan(pivot T, iterator ItemIteratorG[T]) {
+	f.ascend(0, f.search(pivot), iterator)
+}
+
+// AscendGreaterOrEqual calls the iterator for every value in the tree within
+// the range [pivot, last], until iterator returns false.
+func (f *FrozenTreeG[T]) AscendGreaterOrEqual(pivot T, iterator ItemIteratorG[T]) {
+	f.ascend(f.search(pivot), len(f.items), iterator)
+}
+
+// Ascend calls the iterator for every value in the tree within the range
+// [first, last], until iterator returns false.
+func (f *FrozenTreeG[T]) Ascend(iterator ItemIteratorG[T]) {
+	f.ascend(0, len(f.items), iterator)
+}
+
+// upper returns the index of the first item > key.
+func (f *FrozenTreeG[T]) upper(key T) int {
+	return sort.Search(len(f.items), func(i int) bool {
+		return f.less(key, f.items[i])
+	})
+}
+
+// DescendRange calls the iterator for every value in the tree within the range
+// [lessOrEqual, greaterThan), until iterator returns false.
+func (f *FrozenTreeG[T]) DescendRange(lessOrEqual, greaterThan T, iterator ItemIteratorG[T]) {
+	from, to := f.upper(greaterThan), f.upper(lessOrEqual)
+	if from < to {
+		f.descend(from, to, iterator)
+	}
+}
+
+// DescendLessOrEqual calls the iterator for every value in the tree within the range
+// [pivot, first], until iterator returns false.
+func (f *FrozenTreeG[T]) DescendLessOrEqual(pivot T, iterator ItemIteratorG[T]) {
+	f.descend(0, f.upper(pivot), iterator)
+}
+
+// DescendGreaterThan calls the iterator for every value in the tree within
+// the range [last, pivot), until iterator returns false.
+func (f *FrozenTreeG[T]) DescendGreaterThan(pivot T, iterator ItemIteratorG[T]) {
+	f.descend(f.upper(pivot), len(f.items), iterator)
+}
+
+// Descend calls the iterator for every value in the tree within the range
+// [last, first], until iterator returns false.
+func (f *FrozenTreeG[T]) Descend(iterator ItemIteratorG[T]) {
+	f.descend(0, len(f.items), iterator)
+}
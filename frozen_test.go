@@ -0,0 +1,72 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestFreezeG(t *testing.T) {
+	tr := NewOrderedG[int](*btreeDegree)
+	for _, v := range rand.Perm(100) {
+		tr.ReplaceOrInsert(v * 2)
+	}
+	f := tr.Freeze()
+	tr.Delete(10)
+	if f.Len() != 100 || !f.Has(10) || f.Has(11) {
+		t.Fatal("frozen tree changed with its source")
+	}
+	tr.ReplaceOrInsert(10)
+	collect := func(each func(ItemIteratorG[int])) (out []int) {
+		each(func(a int) bool {
+			out = append(out, a)
+			return true
+		})
+		return out
+	}
+	for _, c := range []struct {
+		name       string
+		tree, froz func(ItemIteratorG[int])
+	}{
+		{"Ascend", tr.Ascend, f.Ascend},
+		{"Descend", tr.Descend, f.Descend},
+		{"AscendRange", func(i ItemIteratorG[int]) { tr.AscendRange(21, 60, i) }, func(i ItemIteratorG[int]) { f.AscendRange(21, 60, i) }},
+		{"AscendLessThan", func(i ItemIteratorG[int]) { tr.AscendLessThan(40, i) }, func(i ItemIteratorG[int]) { f.AscendLessThan(40, i) }},
+		{"AscendGreaterOrEqual", func(i ItemIteratorG[int]) { tr.AscendGreaterOrEqual(40, i) }, func(i ItemIteratorG[int]) { f.AscendGreaterOrEqual(40, i) }},
+		{"DescendRange", func(i ItemIteratorG[int]) { tr.DescendRange(60, 21, i) }, func(i ItemIteratorG[int]) { f.DescendRange(60, 21, i) }},
+		{"DescendLessOrEqual", func(i ItemIteratorG[int]) { tr.DescendLessOrEqual(41, i) }, func(i ItemIteratorG[int]) { f.DescendLessOrEqual(41, i) }},
+		{"DescendGreaterThan", func(i ItemIteratorG[int]) { tr.DescendGreaterThan(40, i) }, func(i ItemIteratorG[int]) { f.DescendGreaterThan(40, i) }},
+	} {
+		if got, want := collect(c.froz), collect(c.tree); !reflect.DeepEqual(got, want) {
+			t.Errorf("%v:\n got: %v\nwant: %v", c.name, got, want)
+		}
+	}
+	for i := 0; i < 100; i++ {
+		if f.At(i) != i*2 || f.Rank(i*2) != i || f.Rank(i*2+1) != i+1 {
+			t.Fatalf("At/Rank mismatch at %v", i)
+		}
+	}
+	if min, _ := f.Min(); min != 0 {
+		t.Errorf("min %v", min)
+	}
+	if max, _ := f.Max(); max != 198 {
+		t.Errorf("max %v", max)
+	}
+}
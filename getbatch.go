@@ -0,0 +1,64 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+// GetBatch looks up each of keys in the tree, returning in items[i] the
+// item equal to keys[i] and in found[i] whether there was one, as Get would.
+//
+// Rather than finishing each lookup before starting the next, GetBatch
+// descends one level for every key before moving on to the next level.
+// The memory loads of the independent lookups can then overlap, instead of
+// each lookup waiting on its own chain of cache misses.  This pays off for
+// trees much larger than the CPU's caches; for smaller trees the extra
+// bookkeeping makes GetBatch a little slower than calling Get for each key,
+// so benchmark both on real data.
+func (t *BTreeG[T]) GetBatch(keys []T) (items []T, found []bool) {
+	items, found = make([]T, len(keys)), make([]bool, len(keys))
+	if t.root == nil {
+		return items, found
+	}
+	// pending holds the indexes of the unfinished lookups, and at[i] the
+	// node lookup i has reached.
+	pending := make([]int, 0, len(keys))
+	at := make([]*node[T], len(keys))
+	for i, key := range keys {
+		if !t.bloomExcludes(key) {
+			pending = append(pending, i)
+			at[i] = t.root
+		}
+	}
+	for len(pending) > 0 {
+		next := pending[:0]
+		for _, i := range pending {
+			n := at[i]
+			j, ok := n.find(keys[i])
+			switch {
+			case ok:
+				items[i], found[i] = n.items[j], true
+				if t.keys != nil {
+					t.checkKey(items[i])
+				}
+			case len(n.children) > 0:
+				at[i] = n.children[j]
+				next = append(next, i)
+			}
+		}
+		pending = next
+	}
+	return items, found
+}
@@ -0,0 +1,61 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestGetBatch(t *testing.T) {
+	tr := NewOrderedG[int](*btreeDegree)
+	if items, found := tr.GetBatch([]int{1, 2}); len(items) != 2 || found[0] || found[1] {
+		t.Errorf("empty tree: got %v, %v", items, found)
+	}
+	for _, v := range rand.Perm(1000) {
+		tr.ReplaceOrInsert(v * 2)
+	}
+	keys := rand.Perm(2100)
+	items, found := tr.GetBatch(keys)
+	for i, key := range keys {
+		want, wantOK := tr.Get(key)
+		if items[i] != want || found[i] != wantOK {
+			t.Fatalf("GetBatch found %d, %v for %d; Get found %d, %v", items[i], found[i], key, want, wantOK)
+		}
+	}
+}
+
+func BenchmarkGetBatch(b *testing.B) {
+	tr := NewOrderedG[int](*btreeDegree)
+	for _, v := range rand.Perm(benchmarkTreeSize) {
+		tr.ReplaceOrInsert(v)
+	}
+	keys := rand.Perm(benchmarkTreeSize)[:64]
+	b.Run("Get", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			for _, k := range keys {
+				tr.Get(k)
+			}
+		}
+	})
+	b.Run("GetBatch", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			tr.GetBatch(keys)
+		}
+	})
+}
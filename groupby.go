@@ -0,0 +1,44 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+// GroupBy walks t once in ascending order, calling agg with each bucket and
+// the run of consecutive items that bucket maps to it.
+//
+// bucket must be monotonic with respect to the tree's ordering, such as a
+// prefix of the key or a timestamp truncated to the hour, so that each
+// bucket's items are contiguous and no sort is needed.  If bucket isn't
+// monotonic, a bucket is reported once per run.  agg must not retain the
+// slice it is passed, which is reused for the next group.
+func GroupBy[T any, K comparable](t *BTreeG[T], bucket func(T) K, agg func(k K, items []T)) {
+	var group []T
+	var key K
+	t.ascend(func(item T) bool {
+		k := bucket(item)
+		if len(group) > 0 && k != key {
+			agg(key, group)
+			group = group[:0]
+		}
+		key = k
+		group = append(group, item)
+		return true
+	})
+	if len(group) > 0 {
+		agg(key, group)
+	}
+}
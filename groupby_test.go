@@ -0,0 +1,43 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGroupBy(t *testing.T) {
+	tr := NewOrderedG[int](*btreeDegree)
+	for _, v := range []int{1, 5, 12, 13, 19, 40, 41} {
+		tr.ReplaceOrInsert(v)
+	}
+	got := map[int][]int{}
+	var order []int
+	GroupBy(tr, func(v int) int { return v / 10 }, func(k int, items []int) {
+		order = append(order, k)
+		got[k] = append([]int(nil), items...)
+	})
+	want := map[int][]int{0: {1, 5}, 1: {12, 13, 19}, 4: {40, 41}}
+	if !reflect.DeepEqual(got, want) || !reflect.DeepEqual(order, []int{0, 1, 4}) {
+		t.Errorf("got %v in order %v, want %v", got, order, want)
+	}
+	GroupBy(NewOrderedG[int](2), func(v int) int { return v }, func(int, []int) {
+		t.Error("agg called for an empty tree")
+	})
+}
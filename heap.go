@@ -0,0 +1,69 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+// HeapG adapts a PQ to container/heap's Interface, so that code written
+// against a heap-based priority queue can switch to one that also answers
+// range queries without changing how it pushes and pops.  heap.Init,
+// heap.Push and heap.Pop work as with a min-heap, as do direct calls to the
+// Push and Pop methods.  Use the embedded PQ for the rest of the queue's
+// operations, such as PopMax or AscendRange.
+//
+// The tree keeps the items in order itself, so Less and Swap, which
+// container/heap uses to move items between positions, do nothing.  That
+// makes heap.Fix and heap.Remove, which take an item's position, unusable
+// with a HeapG; use a Handle from PushHandle with the PQ's UpdatePriority
+// and Remove instead.
+type HeapG[T any] struct {
+	// PQ is the underlying queue.
+	*PQ[T]
+}
+
+// NewHeapG returns an empty HeapG ordered by less.
+func NewHeapG[T any](less LessFunc[T]) *HeapG[T] {
+	return &HeapG[T]{NewPQ(less)}
+}
+
+// Less reports false: a HeapG's items have no positions to compare; see
+// HeapG.
+func (h *HeapG[T]) Less(i, j int) bool { return false }
+
+// Swap does nothing; see HeapG.
+func (h *HeapG[T]) Swap(i, j int) {}
+
+// Push adds x, which must be a T, to the heap.  It hides the PQ's Push,
+// which PushHandle replaces.
+func (h *HeapG[T]) Push(x any) {
+	h.PQ.Push(x.(T))
+}
+
+// PushHandle adds item to the heap, like the PQ's Push, returning a Handle
+// for later calls to UpdatePriority or Remove.
+func (h *HeapG[T]) PushHandle(item T) *Handle[T] {
+	return h.PQ.Push(item)
+}
+
+// Pop removes and returns the smallest item in the heap.  Like popping an
+// empty container/heap, it panics if the heap is empty.
+func (h *HeapG[T]) Pop() any {
+	item, ok := h.PopMin()
+	if !ok {
+		panic("btree: Pop from empty heap")
+	}
+	return item
+}
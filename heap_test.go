@@ -0,0 +1,81 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+import (
+	"container/heap"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// pushPopper is the part of heap.Interface that heap-based queues' consumers
+// typically call.
+type pushPopper interface {
+	Len() int
+	Push(x any)
+	Pop() any
+}
+
+func TestHeapG(t *testing.T) {
+	h := NewHeapG(Less[int]())
+	var q pushPopper = h
+	for _, v := range rand.Perm(50) {
+		q.Push(v % 25)
+	}
+	var inRange []int
+	h.AscendRange(10, 13, func(v int) bool {
+		inRange = append(inRange, v)
+		return true
+	})
+	if want := []int{10, 10, 11, 11, 12, 12}; !reflect.DeepEqual(inRange, want) {
+		t.Errorf("AscendRange got %v, want %v", inRange, want)
+	}
+	var got []int
+	for q.Len() > 0 {
+		got = append(got, q.Pop().(int))
+	}
+	for i, v := range got {
+		if v != i/2 {
+			t.Fatalf("popped %v", got)
+		}
+	}
+	expectPanic(t, "btree: Pop from empty heap", func() { q.Pop() })
+}
+
+func TestHeapGContainerHeap(t *testing.T) {
+	h := NewHeapG(Less[int]())
+	var _ heap.Interface = h
+	for _, v := range rand.Perm(20) {
+		h.Push(v)
+	}
+	heap.Init(h)
+	for _, v := range rand.Perm(20) {
+		heap.Push(h, v+20)
+	}
+	handle := h.PushHandle(100)
+	h.UpdatePriority(handle, -1)
+	for want := -1; want < 40; want++ {
+		if got := heap.Pop(h).(int); got != want {
+			t.Fatalf("heap.Pop() = %v, want %v", got, want)
+		}
+	}
+	if h.Len() != 0 {
+		t.Errorf("Len() = %v after popping everything", h.Len())
+	}
+}
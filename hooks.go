@@ -0,0 +1,73 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+// Node is a read-only handle to a node of a tree, passed to Hooks.
+//
+// Nodes are comparable, so a Node can be used as a map key to associate
+// external data with a node; use OnNodeFree to learn when to drop it.  A
+// Node is only meaningful during the hook call and until the node is freed:
+// the tree modifies nodes in place as it changes, and a freed node may be
+// reused for a different part of the tree.
+type Node[T any] struct {
+	n *node[T]
+}
+
+// Items returns the node's items, in order.  The returned slice must not be
+// modified, and is only valid until the tree is next modified.
+func (n Node[T]) Items() []T {
+	return n.n.items
+}
+
+// Children returns the number of children the node has; 0 for a leaf.
+func (n Node[T]) Children() int {
+	return len(n.n.children)
+}
+
+// Child returns the node's i'th child.
+func (n Node[T]) Child(i int) Node[T] {
+	return Node[T]{n.n.children[i]}
+}
+
+// Hooks holds callbacks that observe structural changes to a tree, for
+// instrumentation, for invalidating caches keyed by node, or for animating
+// how a B-Tree works.  Any of the callbacks may be nil.  Install hooks with
+// WithHooks.
+//
+// Hooks run synchronously in the middle of tree operations, while the tree
+// is in an intermediate state: they must not read or write the tree itself.
+type Hooks[T any] struct {
+	// OnSplit is called when a full node is split in two.  left is the
+	// original node, now holding the items before separator; right is a new
+	// node holding the items after it; and separator is moving up to their
+	// parent.
+	OnSplit func(left, right Node[T], separator T)
+	// OnMerge is called when two adjacent nodes are merged.  from's items
+	// and children have been appended to into, after separator, which has
+	// come down from their parent.  from is about to be freed.
+	OnMerge func(into, from Node[T], separator T)
+	// OnNodeAlloc is called when the tree creates a node, including the
+	// copies made by copy-on-write after a Clone.
+	OnNodeAlloc func(n Node[T])
+	// OnNodeFree is called when the tree releases a node it owns, just
+	// before the node's contents are cleared.  Clear(true) calls it for
+	// every node the tree owns, even once the freelist is full; nodes
+	// dropped by Clear(false) are left to the garbage collector without a
+	// call.
+	OnNodeFree func(n Node[T])
+}
@@ -0,0 +1,75 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+import (
+	"testing"
+)
+
+func TestHooks(t *testing.T) {
+	live := map[Node[int]]bool{}
+	var splits, merges int
+	tr := NewWithOptions(WithOrdered[int](), WithDegree[int](2), WithHooks(Hooks[int]{
+		OnSplit: func(left, right Node[int], sep int) {
+			splits++
+			if l, r := left.Items(), right.Items(); l[len(l)-1] >= sep || r[0] <= sep {
+				t.Errorf("split of %v, %v around %v", l, r, sep)
+			}
+		},
+		OnMerge: func(into, from Node[int], sep int) {
+			merges++
+			if !live[into] || !live[from] {
+				t.Error("merge of unknown nodes")
+			}
+		},
+		OnNodeAlloc: func(n Node[int]) {
+			if live[n] {
+				t.Error("allocated a live node")
+			}
+			live[n] = true
+		},
+		OnNodeFree: func(n Node[int]) {
+			if !live[n] {
+				t.Error("freed an unknown node")
+			}
+			delete(live, n)
+		},
+	}))
+	for i := 0; i < 100; i++ {
+		tr.ReplaceOrInsert(i)
+	}
+	if splits == 0 {
+		t.Error("no splits reported")
+	}
+	if got := tr.Stats(); len(live) != got.InternalNodes+got.LeafNodes {
+		t.Errorf("%v live nodes, tree has %v", len(live), got.InternalNodes+got.LeafNodes)
+	}
+	for i := 0; i < 100; i += 2 {
+		tr.Delete(i)
+	}
+	if merges == 0 {
+		t.Error("no merges reported")
+	}
+	if got := tr.Stats(); len(live) != got.InternalNodes+got.LeafNodes {
+		t.Errorf("%v live nodes, tree has %v", len(live), got.InternalNodes+got.LeafNodes)
+	}
+	tr.Clear(true)
+	if len(live) != 0 {
+		t.Errorf("%v nodes still live after Clear", len(live))
+	}
+}
@@ -0,0 +1,166 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+// seekTo advances c to the first item not less than key, which must not be
+// less than c's current item.  It steps forward once first, which is enough
+// when the trees being joined interleave densely, and only then seeks from
+// there, so that skipping a run of length d costs O(log d) rather than O(d).
+func seekTo[T any](c *CursorG[T], key T, less LessFunc[T]) bool {
+	if !c.Next() {
+		return false
+	}
+	if !less(c.Item(), key) {
+		return true
+	}
+	return c.SeekNear(key)
+}
+
+// IntersectAscend calls iter, in ascending order, for every item of a that
+// is equal to an item of b, until iter returns false.  Both trees must be
+// ordered the same way; a's LessFunc is used.
+//
+// The two trees leapfrog: each seeks to the other's current item, skipping
+// runs with no match.  Intersecting a small tree with a huge one therefore
+// takes O(small * log(huge)) time, rather than the O(small + huge) of a
+// merge.
+func IntersectAscend[T any](a, b *BTreeG[T], iter ItemIteratorG[T]) {
+	less := a.cow.less
+	ca, cb := a.Cursor(), b.Cursor()
+	if !ca.First() || !cb.First() {
+		return
+	}
+	for {
+		x, y := ca.Item(), cb.Item()
+		switch {
+		case less(x, y):
+			if !seekTo(ca, y, less) {
+				return
+			}
+		case less(y, x):
+			if !seekTo(cb, x, less) {
+				return
+			}
+		default:
+			if !iter(x) || !ca.Next() || !cb.Next() {
+				return
+			}
+		}
+	}
+}
+
+// sharedLeaf returns true if c and d are at the same position of the same
+// leaf node, which happens when their trees share nodes through Clone.  The
+// rest of the leaf is then the same in both trees.
+func (c *CursorG[T]) sharedLeaf(d *CursorG[T]) bool {
+	if len(c.stack) == 0 || len(d.stack) == 0 {
+		return false
+	}
+	top, other := c.stack[len(c.stack)-1], d.stack[len(d.stack)-1]
+	return top == other && len(top.n.children) == 0
+}
+
+// toLeafEnd moves c to the last item of its current leaf.
+func (c *CursorG[T]) toLeafEnd() {
+	top := &c.stack[len(c.stack)-1]
+	top.index = len(top.n.items) - 1
+}
+
+// DifferenceAscend calls iter, in ascending order, for every item of a that
+// is not equal to any item of b, until iter returns false.  Both trees must
+// be ordered the same way; a's LessFunc is used.
+//
+// b's cursor seeks past runs of b that have nothing in common with a, and
+// where a and b still share leaf nodes, as a tree and its recent Clone
+// mostly do, both cursors skip the shared leaf without comparing its items.
+// Reconciling a tree against an older clone of itself therefore costs
+// roughly the number of leaves rather than the number of items.
+func DifferenceAscend[T any](a, b *BTreeG[T], iter ItemIteratorG[T]) {
+	less := a.cow.less
+	ca, cb := a.Cursor(), b.Cursor()
+	okA, okB := ca.First(), cb.First()
+	for okA && okB {
+		if ca.sharedLeaf(cb) {
+			ca.toLeafEnd()
+			cb.toLeafEnd()
+			okA, okB = ca.Next(), cb.Next()
+			continue
+		}
+		x, y := ca.Item(), cb.Item()
+		switch {
+		case less(x, y):
+			if !iter(x) {
+				return
+			}
+			okA = ca.Next()
+		case less(y, x):
+			okB = seekTo(cb, x, less)
+		default:
+			okA, okB = ca.Next(), cb.Next()
+		}
+	}
+	for ; okA; okA = ca.Next() {
+		if !iter(ca.Item()) {
+			return
+		}
+	}
+}
+
+// JoinAscend performs an ordered merge join of a and b, calling onPair with
+// every pair of items x from a and y from b for which matched(x, y) is true,
+// until onPair returns false.  Pairs are visited in ascending order of x,
+// then of y.
+//
+// matched must compare a join key that is a prefix of the trees' ordering:
+// if matched(x, y) is false, then the one of x and y that is less according
+// to a's LessFunc must also be less than every item the other matches.  For
+// example, trees of (user, time) pairs ordered by user and then time can be
+// joined on user.  Each run of items in b that match the same items in a is
+// buffered, so a run shouldn't be enormous.
+func JoinAscend[T any](a, b *BTreeG[T], matched func(x, y T) bool, onPair func(x, y T) bool) {
+	less := a.cow.less
+	ca, cb := a.Cursor(), b.Cursor()
+	okA, okB := ca.First(), cb.First()
+	var run []T
+	for okA && okB {
+		x, y := ca.Item(), cb.Item()
+		if !matched(x, y) {
+			if less(x, y) {
+				okA = ca.Next()
+			} else {
+				okB = cb.Next()
+			}
+			continue
+		}
+		// Gather the run of b matching x, then pair it with each item of a
+		// that matches the run's first item.
+		run = run[:0]
+		for okB && matched(x, cb.Item()) {
+			run = append(run, cb.Item())
+			okB = cb.Next()
+		}
+		for okA && matched(ca.Item(), run[0]) {
+			for _, y := range run {
+				if !onPair(ca.Item(), y) {
+					return
+				}
+			}
+			okA = ca.Next()
+		}
+	}
+}
@@ -0,0 +1,149 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// randomSet returns a tree, and a map, of up to n random ints in [0, max).
+func randomSet(r *rand.Rand, n, max int) (*BTreeG[int], map[int]bool) {
+	t := NewOrderedG[int](*btreeDegree)
+	m := map[int]bool{}
+	for i := 0; i < n; i++ {
+		v := r.Intn(max)
+		t.ReplaceOrInsert(v)
+		m[v] = true
+	}
+	return t, m
+}
+
+func TestIntersectAscend(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for _, sizes := range [][2]int{{0, 100}, {10, 1000}, {500, 500}, {1000, 3}} {
+		a, _ := randomSet(r, sizes[0], 2000)
+		b, bm := randomSet(r, sizes[1], 2000)
+		var want []int
+		a.Ascend(func(x int) bool {
+			if bm[x] {
+				want = append(want, x)
+			}
+			return true
+		})
+		var got []int
+		IntersectAscend(a, b, func(x int) bool {
+			got = append(got, x)
+			return true
+		})
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("sizes %v: got %v, want %v", sizes, got, want)
+		}
+	}
+}
+
+func BenchmarkIntersectAscendSmallLarge(b *testing.B) {
+	r := rand.New(rand.NewSource(1))
+	small, _ := randomSet(r, 100, 1000000)
+	large, _ := randomSet(r, 100000, 1000000)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		IntersectAscend(small, large, func(int) bool { return true })
+	}
+}
+
+func TestDifferenceAscend(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for _, sizes := range [][2]int{{0, 100}, {100, 0}, {10, 1000}, {500, 500}, {1000, 3}} {
+		a, _ := randomSet(r, sizes[0], 2000)
+		b, bm := randomSet(r, sizes[1], 2000)
+		var want []int
+		a.Ascend(func(x int) bool {
+			if !bm[x] {
+				want = append(want, x)
+			}
+			return true
+		})
+		var got []int
+		DifferenceAscend(a, b, func(x int) bool {
+			got = append(got, x)
+			return true
+		})
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("sizes %v: got %v, want %v", sizes, got, want)
+		}
+	}
+}
+
+func TestDifferenceAscendClone(t *testing.T) {
+	a := NewOrderedG[int](*btreeDegree)
+	for i := 0; i < 10000; i++ {
+		a.ReplaceOrInsert(i * 2)
+	}
+	b := a.Clone()
+	a.ReplaceOrInsert(5001)
+	a.ReplaceOrInsert(17)
+	b.Delete(9000)
+	b.ReplaceOrInsert(9001)
+	var got []int
+	DifferenceAscend(a, b, func(x int) bool {
+		got = append(got, x)
+		return true
+	})
+	if want := []int{17, 5001, 9000}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestJoinAscend(t *testing.T) {
+	type row struct{ key, seq int }
+	less := func(x, y row) bool { return x.key < y.key || (x.key == y.key && x.seq < y.seq) }
+	a, b := NewG(*btreeDegree, less), NewG(*btreeDegree, less)
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 300; i++ {
+		a.ReplaceOrInsert(row{r.Intn(100), i})
+		b.ReplaceOrInsert(row{r.Intn(100), i})
+	}
+	matched := func(x, y row) bool { return x.key == y.key }
+	var want, got [][2]row
+	a.Ascend(func(x row) bool {
+		b.Ascend(func(y row) bool {
+			if matched(x, y) {
+				want = append(want, [2]row{x, y})
+			}
+			return true
+		})
+		return true
+	})
+	JoinAscend(a, b, matched, func(x, y row) bool {
+		got = append(got, [2]row{x, y})
+		return true
+	})
+	if len(want) == 0 || !reflect.DeepEqual(got, want) {
+		t.Errorf("got %d pairs, want %d", len(got), len(want))
+	}
+	n := 0
+	JoinAscend(a, b, matched, func(x, y row) bool {
+		n++
+		return n < 3
+	})
+	if n != 3 {
+		t.Errorf("join didn't stop early: %d pairs", n)
+	}
+}
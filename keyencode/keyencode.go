@@ -0,0 +1,237 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+// Package keyencode encodes values as bytes that sort, compared bytewise, in
+// the same order as the values themselves (the "memcomparable" format).
+//
+// Every encoding is self-delimiting, so a tuple of values can be encoded by
+// appending their encodings one after another, and the result sorts by the
+// first value, then the second, and so on.  This makes it easy to build
+// composite keys for a tree of byte strings, such as a btree.BTreeG[string]
+// or a tree of []byte ordered by bytes.Compare:
+//
+//	key := keyencode.AppendString(nil, tenant)
+//	key = keyencode.AppendTime(key, created)
+//	key = keyencode.AppendUint64(key, id)
+//	t.ReplaceOrInsert(string(key))
+//
+// The encodings are:
+//   - unsigned integers: 8 bytes, big-endian;
+//   - signed integers: 8 bytes, big-endian, with the sign bit flipped;
+//   - floats: the IEEE 754 bits, big-endian, with the sign bit flipped for
+//     positive numbers and every bit flipped for negative ones, so that
+//     -Inf < negative numbers < -0 < +0 < positive numbers < +Inf, with NaNs
+//     beyond the infinities;
+//   - strings and byte slices: the bytes, with each 0x00 escaped as 0x00
+//     0xff, followed by the terminator 0x00 0x01;
+//   - times: the seconds since the Unix epoch as a signed integer, then the
+//     nanoseconds within the second as 4 big-endian bytes.  The time's
+//     location is not encoded; decoded times are in UTC.
+//
+// Each Append function has a matching Decode function that returns the value
+// at the front of its input and the rest of the input after it.
+package keyencode
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+// ErrCorrupt is returned by the Decode functions if their input is too short
+// or isn't a valid encoding.
+var ErrCorrupt = errors.New("keyencode: corrupt or truncated key")
+
+const signBit = 1 << 63
+
+// AppendUint64 appends the encoding of v to dst.
+func AppendUint64(dst []byte, v uint64) []byte {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], v)
+	return append(dst, b[:]...)
+}
+
+// DecodeUint64 decodes a value encoded by AppendUint64.
+func DecodeUint64(src []byte) (v uint64, rest []byte, err error) {
+	if len(src) < 8 {
+		return 0, src, ErrCorrupt
+	}
+	return binary.BigEndian.Uint64(src), src[8:], nil
+}
+
+// AppendInt64 appends the encoding of v to dst.
+func AppendInt64(dst []byte, v int64) []byte {
+	return AppendUint64(dst, uint64(v)^signBit)
+}
+
+// DecodeInt64 decodes a value encoded by AppendInt64.
+func DecodeInt64(src []byte) (v int64, rest []byte, err error) {
+	u, rest, err := DecodeUint64(src)
+	return int64(u ^ signBit), rest, err
+}
+
+// AppendFloat64 appends the encoding of v to dst.
+func AppendFloat64(dst []byte, v float64) []byte {
+	u := math.Float64bits(v)
+	if u&signBit != 0 {
+		u = ^u
+	} else {
+		u ^= signBit
+	}
+	return AppendUint64(dst, u)
+}
+
+// DecodeFloat64 decodes a value encoded by AppendFloat64.
+func DecodeFloat64(src []byte) (v float64, rest []byte, err error) {
+	u, rest, err := DecodeUint64(src)
+	if err != nil {
+		return 0, src, err
+	}
+	if u&signBit != 0 {
+		u ^= signBit
+	} else {
+		u = ^u
+	}
+	return math.Float64frombits(u), rest, nil
+}
+
+const (
+	escape     = 0x00
+	escaped00  = 0xff
+	terminator = 0x01
+)
+
+// AppendBytes appends the encoding of b to dst.
+func AppendBytes(dst, b []byte) []byte {
+	for _, c := range b {
+		dst = append(dst, c)
+		if c == escape {
+			dst = append(dst, escaped00)
+		}
+	}
+	return append(dst, escape, terminator)
+}
+
+// AppendString appends the encoding of s to dst.  It is the same as the
+// encoding of []byte(s).
+func AppendString(dst []byte, s string) []byte {
+	for i := 0; i < len(s); i++ {
+		dst = append(dst, s[i])
+		if s[i] == escape {
+			dst = append(dst, escaped00)
+		}
+	}
+	return append(dst, escape, terminator)
+}
+
+// DecodeBytes decodes a value encoded by AppendBytes or AppendString,
+// appending it to dst.
+func DecodeBytes(dst, src []byte) (b, rest []byte, err error) {
+	for i := 0; i < len(src); i++ {
+		if src[i] != escape {
+			dst = append(dst, src[i])
+			continue
+		}
+		if i++; i == len(src) {
+			break
+		}
+		switch src[i] {
+		case escaped00:
+			dst = append(dst, escape)
+		case terminator:
+			return dst, src[i+1:], nil
+		default:
+			return dst, src, ErrCorrupt
+		}
+	}
+	return dst, src, ErrCorrupt
+}
+
+// DecodeString decodes a value encoded by AppendString or AppendBytes.
+func DecodeString(src []byte) (s string, rest []byte, err error) {
+	b, rest, err := DecodeBytes(nil, src)
+	return string(b), rest, err
+}
+
+// AppendTime appends the encoding of t to dst.
+func AppendTime(dst []byte, t time.Time) []byte {
+	dst = AppendInt64(dst, t.Unix())
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], uint32(t.Nanosecond()))
+	return append(dst, b[:]...)
+}
+
+// DecodeTime decodes a value encoded by AppendTime.  The time is in UTC.
+func DecodeTime(src []byte) (t time.Time, rest []byte, err error) {
+	sec, rest, err := DecodeInt64(src)
+	if err != nil || len(rest) < 4 {
+		return time.Time{}, src, ErrCorrupt
+	}
+	nsec := binary.BigEndian.Uint32(rest)
+	if nsec >= 1e9 {
+		return time.Time{}, src, ErrCorrupt
+	}
+	return time.Unix(sec, int64(nsec)).UTC(), rest[4:], nil
+}
+
+// AppendTuple appends the encodings of values to dst, in order.  Each value
+// must be a signed or unsigned integer, a float32 or float64, a string, a
+// []byte, or a time.Time; AppendTuple panics on any other type.  Integers of
+// every size are encoded as 64-bit integers, and float32s as float64s.
+func AppendTuple(dst []byte, values ...interface{}) []byte {
+	for _, v := range values {
+		switch v := v.(type) {
+		case int:
+			dst = AppendInt64(dst, int64(v))
+		case int8:
+			dst = AppendInt64(dst, int64(v))
+		case int16:
+			dst = AppendInt64(dst, int64(v))
+		case int32:
+			dst = AppendInt64(dst, int64(v))
+		case int64:
+			dst = AppendInt64(dst, v)
+		case uint:
+			dst = AppendUint64(dst, uint64(v))
+		case uint8:
+			dst = AppendUint64(dst, uint64(v))
+		case uint16:
+			dst = AppendUint64(dst, uint64(v))
+		case uint32:
+			dst = AppendUint64(dst, uint64(v))
+		case uint64:
+			dst = AppendUint64(dst, v)
+		case uintptr:
+			dst = AppendUint64(dst, uint64(v))
+		case float32:
+			dst = AppendFloat64(dst, float64(v))
+		case float64:
+			dst = AppendFloat64(dst, v)
+		case string:
+			dst = AppendString(dst, v)
+		case []byte:
+			dst = AppendBytes(dst, v)
+		case time.Time:
+			dst = AppendTime(dst, v)
+		default:
+			panic(fmt.Sprintf("keyencode: can't encode %T", v))
+		}
+	}
+	return dst
+}
@@ -0,0 +1,128 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package keyencode
+
+import (
+	"bytes"
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/google/btree"
+)
+
+// checkOrder checks that the encodings of values, which are in ascending
+// order, are too.
+func checkOrder[T any](t *testing.T, values []T, enc func([]byte, T) []byte) {
+	t.Helper()
+	for i := 1; i < len(values); i++ {
+		a, b := enc(nil, values[i-1]), enc(nil, values[i])
+		if bytes.Compare(a, b) >= 0 {
+			t.Errorf("encoding of %v (%x) doesn't sort before %v (%x)", values[i-1], a, values[i], b)
+		}
+	}
+}
+
+func TestOrder(t *testing.T) {
+	checkOrder(t, []int64{math.MinInt64, -1 << 40, -2, -1, 0, 1, 255, 256, math.MaxInt64}, AppendInt64)
+	checkOrder(t, []uint64{0, 1, 255, 256, 1 << 40, math.MaxUint64}, AppendUint64)
+	checkOrder(t, []float64{math.Inf(-1), -1e300, -1, -math.SmallestNonzeroFloat64, math.Copysign(0, -1), 0, math.SmallestNonzeroFloat64, 0.5, 1, 1e300, math.Inf(1)}, AppendFloat64)
+	checkOrder(t, []string{"", "\x00", "\x00\x00", "\x00\x01", "\x01", "a", "a\x00", "a\x00b", "a\x01", "ab", "b", "\xff", "\xff\xff"}, AppendString)
+	base := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	checkOrder(t, []time.Time{time.Unix(-1e10, 0), base.Add(-time.Nanosecond), base, base.Add(time.Nanosecond), base.Add(time.Second)}, AppendTime)
+}
+
+func TestRoundTrip(t *testing.T) {
+	now := time.Now()
+	key := AppendTuple(nil, -5, uint8(7), 2.5, "a\x00b", []byte{0, 0xff}, now)
+	i, rest, err := DecodeInt64(key)
+	if err != nil || i != -5 {
+		t.Fatalf("DecodeInt64 = %v, %v", i, err)
+	}
+	u, rest, err := DecodeUint64(rest)
+	if err != nil || u != 7 {
+		t.Fatalf("DecodeUint64 = %v, %v", u, err)
+	}
+	f, rest, err := DecodeFloat64(rest)
+	if err != nil || f != 2.5 {
+		t.Fatalf("DecodeFloat64 = %v, %v", f, err)
+	}
+	s, rest, err := DecodeString(rest)
+	if err != nil || s != "a\x00b" {
+		t.Fatalf("DecodeString = %q, %v", s, err)
+	}
+	b, rest, err := DecodeBytes(nil, rest)
+	if err != nil || !bytes.Equal(b, []byte{0, 0xff}) {
+		t.Fatalf("DecodeBytes = %x, %v", b, err)
+	}
+	tm, rest, err := DecodeTime(rest)
+	if err != nil || !tm.Equal(now) {
+		t.Fatalf("DecodeTime = %v, %v; want %v", tm, err, now)
+	}
+	if len(rest) != 0 {
+		t.Errorf("%d bytes left over", len(rest))
+	}
+
+	for _, bad := range [][]byte{{1, 2, 3}, {'a', 0}, {'a', 0, 2}} {
+		if _, _, err := DecodeString(bad); err != ErrCorrupt {
+			t.Errorf("DecodeString(%x) error = %v, want ErrCorrupt", bad, err)
+		}
+	}
+	if _, _, err := DecodeTime(AppendInt64(nil, 1)); err != ErrCorrupt {
+		t.Errorf("DecodeTime of truncated key: error = %v, want ErrCorrupt", err)
+	}
+}
+
+func TestTupleKeys(t *testing.T) {
+	type row struct {
+		tenant string
+		score  float64
+		id     int
+	}
+	var rows []row
+	for i := 0; i < 500; i++ {
+		rows = append(rows, row{[]string{"", "a", "a\x00", "ab"}[rand.Intn(4)], rand.NormFloat64(), rand.Intn(1000) - 500})
+	}
+	tr := btree.NewOrderedG[string](4)
+	for _, r := range rows {
+		tr.ReplaceOrInsert(string(AppendTuple(nil, r.tenant, r.score, r.id)))
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		a, b := rows[i], rows[j]
+		if a.tenant != b.tenant {
+			return a.tenant < b.tenant
+		}
+		if a.score != b.score {
+			return a.score < b.score
+		}
+		return a.id < b.id
+	})
+	i := 0
+	tr.Ascend(func(key string) bool {
+		s, rest, _ := DecodeString([]byte(key))
+		f, rest, _ := DecodeFloat64(rest)
+		id, _, _ := DecodeInt64(rest)
+		if want := rows[i]; s != want.tenant || f != want.score || int(id) != want.id {
+			t.Fatalf("key %d decodes to %q, %v, %d; want %v", i, s, f, id, want)
+		}
+		i++
+		return true
+	})
+}
@@ -0,0 +1,167 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+import (
+	"math/bits"
+	"sync/atomic"
+	"time"
+)
+
+// LatencyStats holds latency histograms for a tree created with
+// WithLatencyHistograms, as reported by Stats.
+type LatencyStats struct {
+	// Inserts times ReplaceOrInsert and AppendMax, Gets times Get and Has,
+	// and Deletes times Delete, DeleteMin and DeleteMax.  RangeScans times
+	// the iterations: Ascend, AscendRange, AscendLessThan,
+	// AscendGreaterOrEqual and their Descend counterparts, including the
+	// time spent in their iterators.
+	Inserts, Gets, Deletes, RangeScans LatencyHistogram
+}
+
+// LatencyHistogram is a snapshot of the durations of one kind of operation.
+// Durations are recorded in buckets whose width is an eighth of a power of
+// two, so the quantiles it reports are within 12.5% of the true values.
+type LatencyHistogram struct {
+	// Count is the number of operations recorded, and Total their summed
+	// duration.
+	Count uint64
+	Total time.Duration
+	// counts holds the number of durations in each bucket; see
+	// latencyBucket.
+	counts []uint64
+}
+
+// Mean returns the mean duration, or 0 if no operations were recorded.
+func (h *LatencyHistogram) Mean() time.Duration {
+	if h.Count == 0 {
+		return 0
+	}
+	return h.Total / time.Duration(h.Count)
+}
+
+// Quantile returns an upper bound on the duration below which the fraction q
+// of the operations fell; for example, Quantile(0.99) is the 99th
+// percentile.  It returns 0 if no operations were recorded.
+func (h *LatencyHistogram) Quantile(q float64) time.Duration {
+	// The buckets are read one at a time while operations carry on, so
+	// they may not add up to Count.
+	var total uint64
+	for _, n := range h.counts {
+		total += n
+	}
+	if total == 0 {
+		return 0
+	}
+	rank := uint64(q * float64(total))
+	if rank >= total {
+		rank = total - 1
+	}
+	var seen uint64
+	for i, n := range h.counts {
+		if seen += n; seen > rank {
+			return time.Duration(latencyBucketMin(i+1) - 1)
+		}
+	}
+	return time.Duration(latencyBucketMin(latencyBuckets) - 1)
+}
+
+// WithLatencyHistograms makes the tree time its operations, reporting
+// histograms of their durations in the Latency field of Stats, so that
+// services can export tail latencies for their indexes without wrapping
+// every call.  Recording costs two reads of the clock and three atomic adds
+// per operation.  The histograms are shared with clones of the tree.
+func WithLatencyHistograms[T any]() Option[T] {
+	return func(o *options[T]) { o.timeOps = true }
+}
+
+// Durations of under latencySubBuckets nanoseconds get a bucket each.
+// Longer durations fall in one of latencySubBuckets buckets for each power
+// of two.
+const (
+	latencySubBits    = 3
+	latencySubBuckets = 1 << latencySubBits
+	latencyBuckets    = (64 - latencySubBits + 1) * latencySubBuckets
+)
+
+// latencyBucket returns the bucket for a duration of ns nanoseconds.
+func latencyBucket(ns uint64) int {
+	if ns < latencySubBuckets {
+		return int(ns)
+	}
+	exp := bits.Len64(ns) - 1 // at least latencySubBits
+	sub := int(ns>>(exp-latencySubBits)) & (latencySubBuckets - 1)
+	return (exp-latencySubBits+1)*latencySubBuckets + sub
+}
+
+// latencyBucketMin returns the smallest duration, in nanoseconds, that falls
+// in bucket i.
+func latencyBucketMin(i int) uint64 {
+	if i < latencySubBuckets {
+		return uint64(i)
+	}
+	exp := i/latencySubBuckets + latencySubBits - 1
+	if exp >= 64 {
+		return 1<<64 - 1
+	}
+	sub := uint64(i % latencySubBuckets)
+	return (latencySubBuckets + sub) << (exp - latencySubBits)
+}
+
+// latencyRecorder holds the histograms behind LatencyStats.  Its fields are
+// only accessed atomically.
+type latencyRecorder struct {
+	inserts, gets, deletes, rangeScans latencyHistogram
+}
+
+type latencyHistogram struct {
+	count, total uint64
+	counts       [latencyBuckets]uint64
+}
+
+// done records an operation that began at start.
+func (h *latencyHistogram) done(start time.Time) {
+	d := time.Since(start)
+	if d < 0 {
+		d = 0
+	}
+	atomic.AddUint64(&h.counts[latencyBucket(uint64(d))], 1)
+	atomic.AddUint64(&h.total, uint64(d))
+	atomic.AddUint64(&h.count, 1)
+}
+
+func (h *latencyHistogram) load() LatencyHistogram {
+	out := LatencyHistogram{
+		Count:  atomic.LoadUint64(&h.count),
+		Total:  time.Duration(atomic.LoadUint64(&h.total)),
+		counts: make([]uint64, latencyBuckets),
+	}
+	for i := range h.counts {
+		out.counts[i] = atomic.LoadUint64(&h.counts[i])
+	}
+	return out
+}
+
+func (l *latencyRecorder) stats() *LatencyStats {
+	return &LatencyStats{
+		Inserts:    l.inserts.load(),
+		Gets:       l.gets.load(),
+		Deletes:    l.deletes.load(),
+		RangeScans: l.rangeScans.load(),
+	}
+}
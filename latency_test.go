@@ -0,0 +1,90 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestLatencyBuckets(t *testing.T) {
+	for _, ns := range []uint64{0, 1, 7, 8, 9, 15, 16, 17, 1000, 123456789, 1<<63 + 5, 1<<64 - 1} {
+		i := latencyBucket(ns)
+		if i < 0 || i >= latencyBuckets {
+			t.Fatalf("latencyBucket(%d) = %d, out of range", ns, i)
+		}
+		lo, hi := latencyBucketMin(i), latencyBucketMin(i+1)
+		if ns < lo || (i+1 < latencyBuckets && ns >= hi) {
+			t.Errorf("latencyBucket(%d) = %d, which holds [%d, %d)", ns, i, lo, hi)
+		}
+		if ns >= 8 && float64(hi-lo) > float64(lo)/8 {
+			t.Errorf("bucket %d for %d is [%d, %d), wider than 12.5%%", i, ns, lo, hi)
+		}
+	}
+}
+
+func TestLatencyHistograms(t *testing.T) {
+	tr := NewWithOptions(WithOrdered[int](), WithDegree[int](*btreeDegree), WithLatencyHistograms[int]())
+	for _, v := range rand.Perm(1000) {
+		tr.ReplaceOrInsert(v)
+	}
+	for i := 0; i < 100; i++ {
+		tr.Get(i)
+	}
+	for i := 0; i < 50; i++ {
+		tr.Delete(i)
+	}
+	for i := 0; i < 10; i++ {
+		tr.AscendRange(100, 200, func(int) bool { return true })
+	}
+	tr.DescendGreaterThan(900, func(int) bool {
+		time.Sleep(time.Millisecond)
+		return false
+	})
+	tr.Ascend(func(int) bool { return true })
+	tr.Descend(func(int) bool { return true })
+
+	l := tr.Stats().Latency
+	if l == nil {
+		t.Fatal("no latency stats")
+	}
+	for _, c := range []struct {
+		name string
+		h    LatencyHistogram
+		want uint64
+	}{
+		{"Inserts", l.Inserts, 1000},
+		{"Gets", l.Gets, 100},
+		{"Deletes", l.Deletes, 50},
+		{"RangeScans", l.RangeScans, 13},
+	} {
+		if c.h.Count != c.want {
+			t.Errorf("%s.Count = %d, want %d", c.name, c.h.Count, c.want)
+		}
+		if c.h.Quantile(0.5) > c.h.Quantile(1) || c.h.Mean() > c.h.Quantile(1) {
+			t.Errorf("%s: mean %v, median %v, max %v", c.name, c.h.Mean(), c.h.Quantile(0.5), c.h.Quantile(1))
+		}
+	}
+	if got := l.RangeScans.Quantile(1); got < time.Millisecond {
+		t.Errorf("slowest range scan took %v, want at least 1ms", got)
+	}
+	if NewOrderedG[int](*btreeDegree).Stats().Latency != nil {
+		t.Error("latency recorded without WithLatencyHistograms")
+	}
+}
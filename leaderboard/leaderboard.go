@@ -0,0 +1,146 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+// Package leaderboard ranks IDs by score.
+//
+// A Board keeps a map from ID to score alongside a btree.AugmentedG of
+// (score, ID) entries whose nodes count the entries in their subtrees.  The
+// counts make finding an ID's rank, or the entry at a rank, take O(log n)
+// time instead of a scan.
+package leaderboard
+
+import "github.com/google/btree"
+
+// Entry is an ID and its score.
+type Entry[K, S btree.Ordered] struct {
+	ID    K
+	Score S
+}
+
+// less orders entries best first: by descending score, then ascending ID.
+func less[K, S btree.Ordered](a, b Entry[K, S]) bool {
+	if a.Score != b.Score {
+		return a.Score > b.Score
+	}
+	return a.ID < b.ID
+}
+
+func counter[K, S btree.Ordered]() btree.Augment[Entry[K, S], int] {
+	return btree.Augment[Entry[K, S], int]{
+		Of:      func(Entry[K, S]) int { return 1 },
+		Combine: func(a, b int) int { return a + b },
+	}
+}
+
+// Board ranks IDs of type K by scores of type S, highest score first, with
+// ties broken by ascending ID.  Scores must not be NaN.
+//
+// Write operations are not safe for concurrent mutation by multiple
+// goroutines, but Read operations are.
+type Board[K, S btree.Ordered] struct {
+	scores map[K]S
+	t      *btree.AugmentedG[Entry[K, S], int]
+}
+
+// New returns an empty board.
+func New[K, S btree.Ordered]() *Board[K, S] {
+	return &Board[K, S]{
+		scores: make(map[K]S),
+		t:      btree.NewAugmentedG(btree.DefaultDegree, less[K, S], counter[K, S]()),
+	}
+}
+
+// Len returns the number of IDs on the board.
+func (b *Board[K, S]) Len() int {
+	return len(b.scores)
+}
+
+// SetScore sets id's score, adding id to the board if it isn't already.
+func (b *Board[K, S]) SetScore(id K, score S) {
+	if old, ok := b.scores[id]; ok {
+		b.t.Delete(Entry[K, S]{id, old})
+	}
+	b.scores[id] = score
+	b.t.ReplaceOrInsert(Entry[K, S]{id, score})
+}
+
+// Remove removes id from the board, returning false if it wasn't on it.
+func (b *Board[K, S]) Remove(id K) bool {
+	score, ok := b.scores[id]
+	if !ok {
+		return false
+	}
+	delete(b.scores, id)
+	b.t.Delete(Entry[K, S]{id, score})
+	return true
+}
+
+// Score returns id's score.
+func (b *Board[K, S]) Score(id K) (score S, ok bool) {
+	score, ok = b.scores[id]
+	return
+}
+
+// RankOf returns id's rank, counting from 0 for the best, or (0, false) if
+// id isn't on the board.  It takes O(log n) time.
+func (b *Board[K, S]) RankOf(id K) (int, bool) {
+	score, ok := b.scores[id]
+	if !ok {
+		return 0, false
+	}
+	above, _ := b.t.SummaryLessThan(Entry[K, S]{id, score})
+	return above, true
+}
+
+// At returns the entry with the given rank.  It takes O(log n) time.
+func (b *Board[K, S]) At(rank int) (Entry[K, S], bool) {
+	return b.t.Search(func(n int) bool { return n > rank })
+}
+
+// TopN returns the n best entries, best first.
+func (b *Board[K, S]) TopN(n int) []Entry[K, S] {
+	return b.from(0, n)
+}
+
+// Around returns the entries ranked within k places of id, best first, or nil
+// if id isn't on the board.  There are fewer than 2k+1 entries if id is
+// within k places of either end of the board.
+func (b *Board[K, S]) Around(id K, k int) []Entry[K, S] {
+	rank, ok := b.RankOf(id)
+	if !ok {
+		return nil
+	}
+	first := rank - k
+	if first < 0 {
+		first = 0
+	}
+	return b.from(first, rank+k+1-first)
+}
+
+// from returns up to n entries, starting at the given rank.
+func (b *Board[K, S]) from(rank, n int) []Entry[K, S] {
+	start, ok := b.At(rank)
+	if !ok || n <= 0 {
+		return nil
+	}
+	var out []Entry[K, S]
+	b.t.AscendGreaterOrEqual(start, func(e Entry[K, S]) bool {
+		out = append(out, e)
+		return len(out) < n
+	})
+	return out
+}
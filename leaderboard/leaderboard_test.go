@@ -0,0 +1,91 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package leaderboard
+
+import (
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestBoard(t *testing.T) {
+	b := New[string, int]()
+	b.SetScore("alice", 10)
+	b.SetScore("bob", 30)
+	b.SetScore("carol", 20)
+	b.SetScore("dave", 20)
+	b.SetScore("erin", 5)
+	want := []Entry[string, int]{{"bob", 30}, {"carol", 20}, {"dave", 20}}
+	if got := b.TopN(3); !reflect.DeepEqual(got, want) {
+		t.Errorf("TopN(3) = %v, want %v", got, want)
+	}
+	b.SetScore("erin", 25)
+	if rank, ok := b.RankOf("erin"); !ok || rank != 1 {
+		t.Errorf("RankOf(erin) = %d, %v; want 1", rank, ok)
+	}
+	want = []Entry[string, int]{{"erin", 25}, {"carol", 20}, {"dave", 20}}
+	if got := b.Around("carol", 1); !reflect.DeepEqual(got, want) {
+		t.Errorf("Around(carol, 1) = %v, want %v", got, want)
+	}
+	want = []Entry[string, int]{{"bob", 30}, {"erin", 25}}
+	if got := b.Around("bob", 1); !reflect.DeepEqual(got, want) {
+		t.Errorf("Around(bob, 1) = %v, want %v", got, want)
+	}
+	if !b.Remove("bob") || b.Remove("bob") || b.Len() != 4 {
+		t.Errorf("Remove didn't remove exactly once")
+	}
+	if e, _ := b.At(0); e.ID != "erin" {
+		t.Errorf("At(0) = %v, want erin", e)
+	}
+	if b.Around("bob", 1) != nil || b.TopN(0) != nil {
+		t.Errorf("expected nil results")
+	}
+}
+
+func TestRanksRandom(t *testing.T) {
+	b := New[int, float64]()
+	scores := map[int]float64{}
+	for i := 0; i < 3000; i++ {
+		id := rand.Intn(300)
+		if rand.Intn(4) == 0 {
+			b.Remove(id)
+			delete(scores, id)
+		} else {
+			s := float64(rand.Intn(50))
+			b.SetScore(id, s)
+			scores[id] = s
+		}
+	}
+	var ids []int
+	for id := range scores {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		a, b := ids[i], ids[j]
+		return scores[a] > scores[b] || (scores[a] == scores[b] && a < b)
+	})
+	for want, id := range ids {
+		if got, _ := b.RankOf(id); got != want {
+			t.Fatalf("RankOf(%d) = %d, want %d", id, got, want)
+		}
+		if e, _ := b.At(want); e.ID != id {
+			t.Fatalf("At(%d) = %v, want %d", want, e, id)
+		}
+	}
+}
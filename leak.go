@@ -0,0 +1,104 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+import "sync"
+
+// LeakDetectorG tracks the nodes a tree and its clones allocate and free,
+// to find nodes that were dropped without being returned to the freelist.
+// Such nodes aren't leaked in the garbage collector's sense, but they are
+// allocations that a shared freelist could have saved, and finding them is
+// the way to check that code sharing a freelist between trees, such as
+// code that clones trees and later discards them, returns nodes as
+// intended.
+//
+// Install a detector's hooks when creating the tree to be checked:
+//
+//	d := btree.NewLeakDetectorG[Item]()
+//	t := btree.NewWithOptions(btree.WithLess(less), btree.WithHooks(d.Hooks()))
+//
+// It is meant for tests and debugging: it keeps every live node in a map.
+type LeakDetectorG[T any] struct {
+	mu               sync.Mutex
+	live             map[*node[T]]struct{}
+	allocated, freed int
+}
+
+// NewLeakDetectorG returns a detector that hasn't seen any nodes.
+func NewLeakDetectorG[T any]() *LeakDetectorG[T] {
+	return &LeakDetectorG[T]{live: make(map[*node[T]]struct{})}
+}
+
+// Hooks returns hooks that report node allocations and frees to d.
+func (d *LeakDetectorG[T]) Hooks() Hooks[T] {
+	return Hooks[T]{
+		OnNodeAlloc: func(n Node[T]) {
+			d.mu.Lock()
+			d.live[n.n] = struct{}{}
+			d.allocated++
+			d.mu.Unlock()
+		},
+		OnNodeFree: func(n Node[T]) {
+			d.mu.Lock()
+			delete(d.live, n.n)
+			d.freed++
+			d.mu.Unlock()
+		},
+	}
+}
+
+// Counts returns the number of nodes allocated and freed so far.
+func (d *LeakDetectorG[T]) Counts() (allocated, freed int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.allocated, d.freed
+}
+
+// Leaks returns the number of nodes that have been allocated and not freed,
+// but that no longer belong to any of the given trees, which should be every
+// tree still in use that shares d's hooks.  Nodes are typically leaked by
+// Clear(false), or by dropping a tree, or a clone, without first calling
+// Clear(true).  Leaks must not be called concurrently with writes to the
+// trees.
+func (d *LeakDetectorG[T]) Leaks(trees ...*BTreeG[T]) int {
+	reachable := make(map[*node[T]]struct{})
+	var walk func(n *node[T])
+	walk = func(n *node[T]) {
+		if _, ok := reachable[n]; ok {
+			return // shared with a tree already walked
+		}
+		reachable[n] = struct{}{}
+		for _, c := range n.children {
+			walk(c)
+		}
+	}
+	for _, t := range trees {
+		if t.root != nil {
+			walk(t.root)
+		}
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	leaks := 0
+	for n := range d.live {
+		if _, ok := reachable[n]; !ok {
+			leaks++
+		}
+	}
+	return leaks
+}
@@ -0,0 +1,61 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestLeakDetector(t *testing.T) {
+	d := NewLeakDetectorG[int]()
+	tr := NewWithOptions(WithOrdered[int](), WithDegree[int](*btreeDegree), WithHooks(d.Hooks()))
+	for _, v := range rand.Perm(1000) {
+		tr.ReplaceOrInsert(v)
+	}
+	for i := 0; i < 500; i++ {
+		tr.Delete(i)
+	}
+	if n := d.Leaks(tr); n != 0 {
+		t.Fatalf("%d leaks after inserts and deletes", n)
+	}
+
+	// A clone that's cleared properly doesn't leak.
+	c := tr.Clone()
+	for i := 500; i < 600; i++ {
+		c.Delete(i)
+	}
+	if n := d.Leaks(tr, c); n != 0 {
+		t.Fatalf("%d leaks with clone", n)
+	}
+	c.Clear(true)
+	if n := d.Leaks(tr); n != 0 {
+		t.Fatalf("%d leaks after clearing clone", n)
+	}
+
+	// One that's dropped does.
+	c = tr.Clone()
+	c.ReplaceOrInsert(-1)
+	if n := d.Leaks(tr); n == 0 {
+		t.Fatal("dropped clone's nodes not reported")
+	}
+	allocated, freed := d.Counts()
+	if allocated <= freed {
+		t.Errorf("allocated %d, freed %d", allocated, freed)
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+// MultisetG is an ordered collection that, unlike a BTreeG, may hold several
+// items that are equal according to its LessFunc.
+//
+// Equal items are kept in the order they were inserted: iteration visits
+// them oldest first (newest first when descending), and DeleteOne removes
+// the oldest.  This order is part of the ordering of the underlying tree,
+// which tags every item with its insertion sequence number, so it is
+// preserved by every split, merge and copy-on-write copy of its nodes, and
+// by Clone.
+//
+// A MultisetG is not safe for concurrent use if any goroutine writes to it.
+type MultisetG[T any] struct {
+	t   *BTreeG[multisetEntry[T]]
+	seq uint64
+}
+
+// multisetEntry is an item with its insertion sequence number.  Real entries
+// have seq >= 1, so an entry with seq 0 sorts before every real entry equal
+// to its item, and can be used as a search key.
+type multisetEntry[T any] struct {
+	item T
+	seq  uint64
+}
+
+// NewMultisetG returns an empty MultisetG with the given degree, ordered by
+// less.
+func NewMultisetG[T any](degree int, less LessFunc[T]) *MultisetG[T] {
+	return &MultisetG[T]{t: NewG(degree, func(a, b multisetEntry[T]) bool {
+		switch {
+		case less(a.item, b.item):
+			return true
+		case less(b.item, a.item):
+			return false
+		}
+		return a.seq < b.seq
+	})}
+}
+
+// Len returns the number of items in the multiset, counting each equal item.
+func (m *MultisetG[T]) Len() int {
+	return m.t.Len()
+}
+
+// Insert adds item to the multiset, after any items equal to it.
+func (m *MultisetG[T]) Insert(item T) {
+	m.seq++
+	m.t.ReplaceOrInsert(multisetEntry[T]{item: item, seq: m.seq})
+}
+
+// equal calls iter for each item equal to key, oldest first, until iter
+// returns false.
+func (m *MultisetG[T]) equal(key T, iter func(e multisetEntry[T]) bool) {
+	less := m.t.cow.less
+	m.t.AscendGreaterOrEqual(multisetEntry[T]{item: key}, func(e multisetEntry[T]) bool {
+		if less(multisetEntry[T]{item: key, seq: e.seq}, e) {
+			return false // e.item is greater than key
+		}
+		return iter(e)
+	})
+}
+
+// Count returns the number of items equal to key.
+func (m *MultisetG[T]) Count(key T) int {
+	n := 0
+	m.equal(key, func(multisetEntry[T]) bool {
+		n++
+		return true
+	})
+	return n
+}
+
+// GetAll calls iterator for each item equal to key, in the order they were
+// inserted, until iterator returns false.
+func (m *MultisetG[T]) GetAll(key T, iterator ItemIteratorG[T]) {
+	m.equal(key, func(e multisetEntry[T]) bool { return iterator(e.item) })
+}
+
+// DeleteOne removes the oldest item equal to key, returning it, or returns
+// (zeroValue, false) if there is none.
+func (m *MultisetG[T]) DeleteOne(key T) (_ T, _ bool) {
+	var oldest multisetEntry[T]
+	found := false
+	m.equal(key, func(e multisetEntry[T]) bool {
+		oldest, found = e, true
+		return false
+	})
+	if !found {
+		return
+	}
+	m.t.Delete(oldest)
+	return oldest.item, true
+}
+
+// DeleteAll removes every item equal to key, returning how many there were.
+func (m *MultisetG[T]) DeleteAll(key T) int {
+	var doomed []multisetEntry[T]
+	m.equal(key, func(e multisetEntry[T]) bool {
+		doomed = append(doomed, e)
+		return true
+	})
+	for _, e := range doomed {
+		m.t.Delete(e)
+	}
+	return len(doomed)
+}
+
+// Ascend calls iterator for every item in the multiset in ascending order,
+// with equal items in the order they were inserted, until iterator returns
+// false.
+func (m *MultisetG[T]) Ascend(iterator ItemIteratorG[T]) {
+	m.t.Ascend(func(e multisetEntry[T]) bool { return iterator(e.item) })
+}
+
+// AscendRange calls iterator for every item within the range
+// [greaterOrEqual, lessThan), as Ascend does, until iterator returns false.
+func (m *MultisetG[T]) AscendRange(greaterOrEqual, lessThan T, iterator ItemIteratorG[T]) {
+	m.t.AscendRange(multisetEntry[T]{item: greaterOrEqual}, multisetEntry[T]{item: lessThan}, func(e multisetEntry[T]) bool {
+		return iterator(e.item)
+	})
+}
+
+// Descend calls iterator for every item in the multiset in descending
+// order, with equal items in the reverse of the order they were inserted,
+// until iterator returns false.
+func (m *MultisetG[T]) Descend(iterator ItemIteratorG[T]) {
+	m.t.Descend(func(e multisetEntry[T]) bool { return iterator(e.item) })
+}
+
+// Clone returns a copy of the multiset, lazily, as BTreeG.Clone does.
+// Equal items keep their order in both copies, and items inserted into
+// either copy later are placed after them.
+func (m *MultisetG[T]) Clone() *MultisetG[T] {
+	return &MultisetG[T]{t: m.t.Clone(), seq: m.seq}
+}
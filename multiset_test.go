@@ -0,0 +1,104 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+type dupItem struct {
+	key, id int
+}
+
+func dupLess(a, b dupItem) bool { return a.key < b.key }
+
+// ids returns the ids of the items visited by f.
+func ids(f func(ItemIteratorG[dupItem])) []int {
+	var out []int
+	f(func(d dupItem) bool {
+		out = append(out, d.id)
+		return true
+	})
+	return out
+}
+
+func TestMultisetStableOrder(t *testing.T) {
+	// With degree 2, the thousands of inserts and deletes below split and
+	// merge nodes many times over.
+	m := NewMultisetG(2, dupLess)
+	want := make(map[int][]int) // ids for each key, in insertion order
+	id := 0
+	for i := 0; i < 3000; i++ {
+		key := rand.Intn(20)
+		if rand.Intn(4) == 0 {
+			if d, ok := m.DeleteOne(dupItem{key: key}); ok {
+				if d.id != want[key][0] {
+					t.Fatalf("DeleteOne(%d) removed id %d, want the oldest, %d", key, d.id, want[key][0])
+				}
+				want[key] = want[key][1:]
+			}
+			continue
+		}
+		id++
+		m.Insert(dupItem{key, id})
+		want[key] = append(want[key], id)
+	}
+	clone := m.Clone()
+	for key := 0; key < 20; key++ {
+		got := ids(func(f ItemIteratorG[dupItem]) { m.GetAll(dupItem{key: key}, f) })
+		if len(got) != len(want[key]) || (len(got) > 0 && !reflect.DeepEqual(got, want[key])) {
+			t.Fatalf("GetAll(%d) = %v, want %v", key, got, want[key])
+		}
+		if n := m.Count(dupItem{key: key}); n != len(want[key]) {
+			t.Errorf("Count(%d) = %d, want %d", key, n, len(want[key]))
+		}
+	}
+	var all []int
+	for key := 0; key < 20; key++ {
+		all = append(all, want[key]...)
+	}
+	if got := ids(m.Ascend); !reflect.DeepEqual(got, all) {
+		t.Errorf("Ascend = %v, want %v", got, all)
+	}
+	desc := ids(m.Descend)
+	for i := range desc {
+		if desc[i] != all[len(all)-1-i] {
+			t.Fatalf("Descend isn't the reverse of Ascend at %d", i)
+		}
+	}
+
+	// Changes to the clone don't affect the original, and new items go after
+	// the existing equal ones in both.
+	clone.DeleteAll(dupItem{key: 5})
+	clone.Insert(dupItem{7, -1})
+	m.Insert(dupItem{7, -2})
+	if got := ids(func(f ItemIteratorG[dupItem]) { m.GetAll(dupItem{key: 5}, f) }); len(got) != len(want[5]) {
+		t.Errorf("DeleteAll on the clone changed the original: %v", got)
+	}
+	if got := ids(func(f ItemIteratorG[dupItem]) { clone.GetAll(dupItem{key: 7}, f) }); got[len(got)-1] != -1 {
+		t.Errorf("clone's key 7: %v, want -1 last", got)
+	}
+	if got := ids(func(f ItemIteratorG[dupItem]) { m.GetAll(dupItem{key: 7}, f) }); got[len(got)-1] != -2 {
+		t.Errorf("original's key 7: %v, want -2 last", got)
+	}
+	if got := ids(func(f ItemIteratorG[dupItem]) { m.AscendRange(dupItem{key: 3}, dupItem{key: 5}, f) }); !reflect.DeepEqual(got, append(append([]int(nil), want[3]...), want[4]...)) {
+		t.Errorf("AscendRange(3, 5) = %v", got)
+	}
+}
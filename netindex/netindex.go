@@ -0,0 +1,158 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+// Package netindex provides a table of IP prefixes supporting longest-prefix
+// match, as used by routing tables and address-based policy.
+//
+// Prefixes are stored in a btree.BTreeG in canonical (masked) form, ordered
+// by address and then by length, so that every IPv4 prefix sorts before
+// every IPv6 one and a prefix sorts before the longer prefixes it contains.
+// Comparing unmasked prefixes, or comparing lengths before addresses, are the
+// usual mistakes that make hand-written tables miss matches.
+package netindex
+
+import (
+	"net/netip"
+
+	"github.com/google/btree"
+)
+
+type entry[V any] struct {
+	prefix netip.Prefix
+	value  V
+}
+
+func less[V any](a, b entry[V]) bool {
+	if c := a.prefix.Addr().Compare(b.prefix.Addr()); c != 0 {
+		return c < 0
+	}
+	return a.prefix.Bits() < b.prefix.Bits()
+}
+
+// Table maps IP prefixes to values of type V.
+//
+// Write operations are not safe for concurrent mutation by multiple
+// goroutines, but Read operations are.
+type Table[V any] struct {
+	t *btree.BTreeG[entry[V]]
+	// lengths counts the prefixes of each length, for IPv4 ([0]) and IPv6
+	// ([1]), so that lookups only probe lengths that are present.
+	lengths [2][129]int
+}
+
+// New returns an empty table.
+func New[V any]() *Table[V] {
+	return &Table[V]{t: btree.NewG(btree.DefaultDegree, less[V])}
+}
+
+func family(p netip.Prefix) int {
+	if p.Addr().Is4() {
+		return 0
+	}
+	return 1
+}
+
+// canonical returns p with its host bits cleared, panicking if p is invalid.
+func canonical(p netip.Prefix) netip.Prefix {
+	if !p.IsValid() {
+		panic("netindex: invalid prefix")
+	}
+	return p.Masked()
+}
+
+// Len returns the number of prefixes in the table.
+func (t *Table[V]) Len() int {
+	return t.t.Len()
+}
+
+// Insert maps p to value, returning the value p was previously mapped to, if
+// any.  p's host bits are ignored, so 10.1.2.3/8 and 10.0.0.0/8 are the same
+// prefix.  Insert panics if p is invalid.
+func (t *Table[V]) Insert(p netip.Prefix, value V) (old V, replaced bool) {
+	p = canonical(p)
+	e, replaced := t.t.ReplaceOrInsert(entry[V]{p, value})
+	if !replaced {
+		t.lengths[family(p)][p.Bits()]++
+	}
+	return e.value, replaced
+}
+
+// Delete removes p from the table, returning its value.
+func (t *Table[V]) Delete(p netip.Prefix) (_ V, _ bool) {
+	p = canonical(p)
+	e, ok := t.t.Delete(entry[V]{prefix: p})
+	if !ok {
+		return
+	}
+	t.lengths[family(p)][p.Bits()]--
+	return e.value, true
+}
+
+// Get returns the value of exactly the prefix p.
+func (t *Table[V]) Get(p netip.Prefix) (_ V, _ bool) {
+	e, ok := t.t.Get(entry[V]{prefix: canonical(p)})
+	return e.value, ok
+}
+
+// LookupLongestPrefix returns the longest prefix in the table that contains
+// addr, and its value.  Each distinct prefix length in the table costs at
+// most one O(log n) probe, so a lookup takes at most 33 probes for IPv4 and
+// 129 for IPv6, however many prefixes the table holds.
+//
+// IPv4-mapped IPv6 addresses are matched against IPv6 prefixes; use
+// addr.Unmap to match them against IPv4 prefixes instead.
+func (t *Table[V]) LookupLongestPrefix(addr netip.Addr) (_ netip.Prefix, _ V, _ bool) {
+	if !addr.IsValid() {
+		return
+	}
+	lengths := &t.lengths[1]
+	if addr.Is4() {
+		lengths = &t.lengths[0]
+	}
+	for bits := addr.BitLen(); bits >= 0; bits-- {
+		if lengths[bits] == 0 {
+			continue
+		}
+		p, _ := addr.Prefix(bits)
+		if e, ok := t.t.Get(entry[V]{prefix: p}); ok {
+			return e.prefix, e.value, true
+		}
+	}
+	return
+}
+
+// Ascend calls iter for each prefix in the table, and its value, in order,
+// until iter returns false.
+func (t *Table[V]) Ascend(iter func(p netip.Prefix, value V) bool) {
+	t.t.Ascend(func(e entry[V]) bool {
+		return iter(e.prefix, e.value)
+	})
+}
+
+// AscendContained calls iter for each prefix in the table contained within
+// p (including p itself), in order, until iter returns false.  These form a
+// contiguous run of the table, so this takes O(log n) time plus the number of
+// prefixes visited.
+func (t *Table[V]) AscendContained(p netip.Prefix, iter func(p netip.Prefix, value V) bool) {
+	p = canonical(p)
+	t.t.AscendGreaterOrEqual(entry[V]{prefix: p}, func(e entry[V]) bool {
+		if e.prefix.Addr().BitLen() != p.Addr().BitLen() || !p.Contains(e.prefix.Addr()) {
+			return false
+		}
+		return iter(e.prefix, e.value)
+	})
+}
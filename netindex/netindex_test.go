@@ -0,0 +1,84 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package netindex
+
+import (
+	"net/netip"
+	"reflect"
+	"testing"
+)
+
+func TestLookupLongestPrefix(t *testing.T) {
+	tbl := New[string]()
+	for _, s := range []string{
+		"0.0.0.0/0",
+		"10.0.0.0/8",
+		"10.1.0.0/16",
+		"10.1.2.0/24",
+		"10.2.0.0/16",
+		"::/0",
+		"2001:db8::/32",
+	} {
+		tbl.Insert(netip.MustParsePrefix(s), s)
+	}
+	for addr, want := range map[string]string{
+		"10.1.2.3":        "10.1.2.0/24",
+		"10.1.3.3":        "10.1.0.0/16",
+		"10.2.255.255":    "10.2.0.0/16",
+		"10.3.0.0":        "10.0.0.0/8",
+		"192.168.0.1":     "0.0.0.0/0",
+		"2001:db8::1":     "2001:db8::/32",
+		"2001:db9::1":     "::/0",
+		"::ffff:10.1.2.3": "::/0",
+	} {
+		_, got, ok := tbl.LookupLongestPrefix(netip.MustParseAddr(addr))
+		if !ok || got != want {
+			t.Errorf("LookupLongestPrefix(%s) = %q, %v; want %q", addr, got, ok, want)
+		}
+	}
+	tbl.Delete(netip.MustParsePrefix("0.0.0.0/0"))
+	if _, _, ok := tbl.LookupLongestPrefix(netip.MustParseAddr("192.168.0.1")); ok {
+		t.Errorf("matched after deleting the default route")
+	}
+}
+
+func TestInsertMasks(t *testing.T) {
+	tbl := New[int]()
+	tbl.Insert(netip.MustParsePrefix("10.1.2.3/8"), 1)
+	if old, replaced := tbl.Insert(netip.MustParsePrefix("10.0.0.0/8"), 2); !replaced || old != 1 {
+		t.Errorf("Insert didn't replace the unmasked prefix: %d, %v", old, replaced)
+	}
+	if v, ok := tbl.Get(netip.MustParsePrefix("10.9.9.9/8")); !ok || v != 2 || tbl.Len() != 1 {
+		t.Errorf("Get = %d, %v; Len %d", v, ok, tbl.Len())
+	}
+}
+
+func TestAscendContained(t *testing.T) {
+	tbl := New[int]()
+	for i, s := range []string{"10.0.0.0/8", "10.1.0.0/16", "10.1.2.0/24", "11.0.0.0/8", "9.0.0.0/8", "::/0"} {
+		tbl.Insert(netip.MustParsePrefix(s), i)
+	}
+	var got []string
+	tbl.AscendContained(netip.MustParsePrefix("10.0.0.0/8"), func(p netip.Prefix, _ int) bool {
+		got = append(got, p.String())
+		return true
+	})
+	if want := []string{"10.0.0.0/8", "10.1.0.0/16", "10.1.2.0/24"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
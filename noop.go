@@ -0,0 +1,64 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+// A write that turns out to change nothing, such as deleting an absent item,
+// would still copy every shared node on its way down the tree (and, for a
+// delete, rebalance nodes on the way), unsharing them from the tree's
+// clones for no reason.  So once a tree has been cloned, deletes first look
+// the item up without modifying anything, and return early if it's absent.
+// Trees that have never been cloned own all their nodes, so they skip the
+// extra lookup.  Inserts can only be no-ops if the tree knows when two items
+// are the same, which WithItemEqual tells it.
+
+// WithItemEqual tells the tree how to recognize an item that is the same as
+// the one it would replace, so that ReplaceOrInsert (and AppendMax and
+// Apply) can leave the tree untouched, and its nodes shared with clones,
+// when asked to replace an item with an identical one.  equal is only
+// called with items that are equal according to the tree's LessFunc, and
+// should report whether they are entirely alike, such as by comparing
+// their values with ==.
+//
+// ReplaceOrInsert then looks each item up before inserting it, which adds
+// the cost of a Get to inserts that aren't no-ops.
+func WithItemEqual[T any](equal func(a, b T) bool) Option[T] {
+	return func(o *options[T]) { o.equal = equal }
+}
+
+// noopInsert reports whether inserting item would change nothing, because
+// the tree holds an item equal to it according to cow.equal, returning
+// that item.
+func (t *BTreeG[T]) noopInsert(item T) (_ T, _ bool) {
+	if t.cow.equal == nil || t.root == nil {
+		return
+	}
+	if old, ok := t.root.get(item); ok && t.cow.equal(old, item) {
+		return old, true
+	}
+	return
+}
+
+// noopDelete reports whether deleting item would change nothing and copy
+// nodes shared with clones, because it isn't in the tree.
+func (t *BTreeG[T]) noopDelete(item T) bool {
+	if !t.shared {
+		return false
+	}
+	_, ok := t.root.get(item)
+	return !ok
+}
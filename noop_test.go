@@ -0,0 +1,70 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+import "testing"
+
+// sameNodes reports whether two subtrees consist of the very same nodes.
+func sameNodes[T any](a, b *node[T]) bool {
+	if a != b {
+		return false
+	}
+	for i := range a.children {
+		if !sameNodes(a.children[i], b.children[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestNoopWritesKeepNodesShared(t *testing.T) {
+	type kv struct{ k, v int }
+	tr := NewWithOptions(
+		WithDegree[kv](2),
+		WithLess(func(a, b kv) bool { return a.k < b.k }),
+		WithItemEqual(func(a, b kv) bool { return a == b }))
+	for i := 0; i < 100; i += 2 {
+		tr.ReplaceOrInsert(kv{i, i})
+	}
+	clone := tr.Clone()
+	for i := 1; i < 100; i += 2 {
+		if _, ok := clone.Delete(kv{k: i}); ok {
+			t.Fatalf("deleted absent item %d", i)
+		}
+	}
+	for i := 0; i < 100; i += 2 {
+		if old, ok := clone.ReplaceOrInsert(kv{i, i}); !ok || old != (kv{i, i}) {
+			t.Fatalf("ReplaceOrInsert(%d) = %v, %v", i, old, ok)
+		}
+	}
+	if !sameNodes(tr.root, clone.root) {
+		t.Error("no-op writes copied nodes")
+	}
+
+	// Real writes still happen.
+	clone.ReplaceOrInsert(kv{4, 5})
+	if got, _ := clone.Get(kv{k: 4}); got.v != 5 {
+		t.Errorf("Get(4) = %v after replacing it", got)
+	}
+	if got, _ := tr.Get(kv{k: 4}); got.v != 4 {
+		t.Errorf("replacing an item in the clone changed the original to %v", got)
+	}
+	if _, ok := clone.Delete(kv{k: 6}); !ok || clone.Len() != 49 {
+		t.Error("Delete(6) failed")
+	}
+}
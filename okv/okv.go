@@ -0,0 +1,171 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+// Package okv is a small in-memory ordered key-value store with snapshot
+// reads and a write-ahead persistence hook, built on btree.BTreeG.
+//
+// Snapshots are copy-on-write clones of the store's tree, so taking one is
+// O(1) and reading it never blocks, or is affected by, later writes.  Every
+// write is passed to the store's log function, if any, before it is applied,
+// so the log can persist it and a store can be rebuilt by replaying the
+// logged changes with Apply.
+package okv
+
+import (
+	"sync"
+
+	"github.com/google/btree"
+)
+
+type kv[K btree.Ordered, V any] struct {
+	key   K
+	value V
+}
+
+func less[K btree.Ordered, V any](a, b kv[K, V]) bool {
+	return a.key < b.key
+}
+
+// Change is a single write to a Store.
+type Change[K btree.Ordered, V any] struct {
+	// Version is the store's version after the change.
+	Version uint64
+	Key     K
+	Value   V    // unset if Delete is true
+	Delete  bool // whether the change deletes Key
+}
+
+// Store is an ordered key-value store.  It is safe for concurrent use.
+type Store[K btree.Ordered, V any] struct {
+	mu      sync.Mutex
+	t       *btree.BTreeG[kv[K, V]]
+	version uint64
+	log     func(Change[K, V]) error
+}
+
+// New returns an empty store.  If log is not nil, it is called with each
+// change before the change is applied, and if it returns an error the change
+// is abandoned and the error returned to the writer.  log is called with the
+// store locked, so changes reach it in order, and it must not use the store.
+func New[K btree.Ordered, V any](log func(Change[K, V]) error) *Store[K, V] {
+	return &Store[K, V]{t: btree.NewG(btree.DefaultDegree, less[K, V]), log: log}
+}
+
+// Put sets the value of key.
+func (s *Store[K, V]) Put(key K, value V) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.write(Change[K, V]{Version: s.version + 1, Key: key, Value: value}, true)
+}
+
+// Delete removes key from the store.  Deleting a missing key is not an error,
+// and isn't logged.
+func (s *Store[K, V]) Delete(key K) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.t.Has(kv[K, V]{key: key}) {
+		return nil
+	}
+	return s.write(Change[K, V]{Version: s.version + 1, Key: key, Delete: true}, true)
+}
+
+// Apply applies a change previously passed to a store's log function,
+// without logging it again, to rebuild a store from its log.  Changes must
+// be applied in order; Apply ignores changes older than the store's version.
+func (s *Store[K, V]) Apply(c Change[K, V]) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if c.Version > s.version {
+		s.write(c, false)
+	}
+}
+
+// write logs c if requested, and then applies it.  s.mu must be held.
+func (s *Store[K, V]) write(c Change[K, V], log bool) error {
+	if log && s.log != nil {
+		if err := s.log(c); err != nil {
+			return err
+		}
+	}
+	if c.Delete {
+		s.t.Delete(kv[K, V]{key: c.Key})
+	} else {
+		s.t.ReplaceOrInsert(kv[K, V]{c.Key, c.Value})
+	}
+	s.version = c.Version
+	return nil
+}
+
+// Get returns the value of key.
+func (s *Store[K, V]) Get(key K) (_ V, _ bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	item, ok := s.t.Get(kv[K, V]{key: key})
+	return item.value, ok
+}
+
+// Version returns the number of changes applied to the store.
+func (s *Store[K, V]) Version() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.version
+}
+
+// Snapshot returns a read-only view of the store as it is now.
+func (s *Store[K, V]) Snapshot() *Snapshot[K, V] {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return &Snapshot[K, V]{t: s.t.Clone(), version: s.version}
+}
+
+// Scan calls fn for each key in [from, to) and its value, in order, until fn
+// returns false.  It reads a snapshot, so fn may write to the store, and
+// doesn't see its own writes.
+func (s *Store[K, V]) Scan(from, to K, fn func(key K, value V) bool) {
+	s.Snapshot().Scan(from, to, fn)
+}
+
+// Snapshot is an unchanging view of a Store at some version.  It is safe for
+// concurrent use.
+type Snapshot[K btree.Ordered, V any] struct {
+	t       *btree.BTreeG[kv[K, V]]
+	version uint64
+}
+
+// Version returns the version of the store the snapshot was taken at.
+func (s *Snapshot[K, V]) Version() uint64 {
+	return s.version
+}
+
+// Len returns the number of keys in the snapshot.
+func (s *Snapshot[K, V]) Len() int {
+	return s.t.Len()
+}
+
+// Get returns the value of key.
+func (s *Snapshot[K, V]) Get(key K) (_ V, _ bool) {
+	item, ok := s.t.Get(kv[K, V]{key: key})
+	return item.value, ok
+}
+
+// Scan calls fn for each key in [from, to) and its value, in order, until fn
+// returns false.
+func (s *Snapshot[K, V]) Scan(from, to K, fn func(key K, value V) bool) {
+	s.t.AscendRange(kv[K, V]{key: from}, kv[K, V]{key: to}, func(item kv[K, V]) bool {
+		return fn(item.key, item.value)
+	})
+}
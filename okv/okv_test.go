@@ -0,0 +1,111 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package okv
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func scan(s interface {
+	Scan(from, to string, fn func(string, int) bool)
+}) []string {
+	var out []string
+	s.Scan("", "\xff", func(k string, v int) bool {
+		out = append(out, fmt.Sprintf("%s=%d", k, v))
+		return true
+	})
+	return out
+}
+
+func TestSnapshotIsolation(t *testing.T) {
+	s := New[string, int](nil)
+	s.Put("a", 1)
+	s.Put("b", 2)
+	snap := s.Snapshot()
+	s.Put("a", 10)
+	s.Delete("b")
+	s.Put("c", 3)
+	if got, want := scan(snap), []string{"a=1", "b=2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("snapshot has %v, want %v", got, want)
+	}
+	if got, want := scan(s), []string{"a=10", "c=3"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("store has %v, want %v", got, want)
+	}
+	if snap.Version() != 2 || s.Version() != 5 {
+		t.Errorf("versions %d, %d", snap.Version(), s.Version())
+	}
+}
+
+func TestLogReplay(t *testing.T) {
+	var log []Change[string, int]
+	fail := false
+	s := New(func(c Change[string, int]) error {
+		if fail {
+			return errors.New("disk full")
+		}
+		log = append(log, c)
+		return nil
+	})
+	s.Put("a", 1)
+	s.Put("b", 2)
+	s.Delete("a")
+	s.Delete("missing")
+	fail = true
+	if err := s.Put("c", 3); err == nil {
+		t.Error("Put succeeded despite log failure")
+	}
+	if _, ok := s.Get("c"); ok {
+		t.Error("failed Put was applied")
+	}
+	replay := New[string, int](nil)
+	for _, c := range log {
+		replay.Apply(c)
+	}
+	replay.Apply(log[0]) // ignored, already applied
+	if got, want := scan(replay), scan(s); !reflect.DeepEqual(got, want) || replay.Version() != s.Version() {
+		t.Errorf("replayed %v (version %d), want %v (version %d)", got, replay.Version(), want, s.Version())
+	}
+}
+
+func TestConcurrent(t *testing.T) {
+	s := New[string, int](nil)
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				s.Put(fmt.Sprint(g, i%20), i)
+				snap := s.Snapshot()
+				n := 0
+				snap.Scan("", "\xff", func(string, int) bool {
+					n++
+					return true
+				})
+				if n != snap.Len() {
+					t.Errorf("scan saw %d keys, Len %d", n, snap.Len())
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+}
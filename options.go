@@ -0,0 +1,220 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+// options collects the configuration passed to NewWithOptions.
+type options[T any] struct {
+	degree   int
+	less     LessFunc[T]
+	freelist *FreeListG[T]
+	search   func(s items[T], item T, less LessFunc[T]) (int, bool)
+	limit    *limitG[T]
+	// checkOrder is set by WithOrderingChecks.
+	checkOrder bool
+	// checkMods is set by WithModificationChecks.
+	checkMods bool
+	hooks     *Hooks[T]
+	tracer    func(TraceEvent)
+	// countComparisons is set by WithComparisonCounting.
+	countComparisons bool
+	bloomHash        func(T) uint64
+	splitPolicy      SplitPolicy
+	// recoverLess is set by WithLessRecovery.
+	recoverLess bool
+	keyHash     func(T) uint64
+	// timeOps is set by WithLatencyHistograms.
+	timeOps bool
+	equal   func(a, b T) bool
+	// orderStats is set by WithOrderStatistics.
+	orderStats bool
+}
+
+// setLess sets c's LessFunc to less, wrapped as the options recorded in c
+// require: for recovery, then ordering checks, then comparison counting.
+func (c *copyOnWriteContext[T]) setLess(less LessFunc[T]) {
+	if c.recoverLess {
+		less = recoveringLess(less)
+	}
+	if c.checkOrder {
+		less = checkedLess(less)
+	}
+	if c.comparisons != nil {
+		less = countingLess(less, c.comparisons)
+	}
+	c.less = less
+}
+
+// Option configures a tree created by NewWithOptions.
+type Option[T any] func(*options[T])
+
+// WithDegree sets the degree of the tree.  The default is DefaultDegree.
+func WithDegree[T any](degree int) Option[T] {
+	return func(o *options[T]) { o.degree = degree }
+}
+
+// WithLess sets the function used to order items.  Every tree needs an
+// ordering, given by WithLess, WithOrdered, or WithInterpolationSearch; the
+// last of them given wins.
+func WithLess[T any](less LessFunc[T]) Option[T] {
+	return func(o *options[T]) {
+		o.less = less
+		// Interpolation search assumes items are ordered by '<', so it
+		// can't be kept with a different ordering.
+		o.search = nil
+	}
+}
+
+// WithOrdered orders items with the '<' operator, like NewOrderedG.
+func WithOrdered[T Ordered]() Option[T] {
+	return WithLess(Less[T]())
+}
+
+// WithFreeList makes the tree allocate nodes from, and return them to, the
+// given free list, which may be shared with other trees.  By default each
+// tree gets its own free list of size DefaultFreeListSize.
+func WithFreeList[T any](f *FreeListG[T]) Option[T] {
+	return func(o *options[T]) { o.freelist = f }
+}
+
+// WithInterpolationSearch orders items with the '<' operator and searches
+// within nodes by interpolation, like NewInterpolatedG.  The search still
+// compares items with the tree's LessFunc, so WithOrderingChecks,
+// WithComparisonCounting and WithLessRecovery apply to it as usual.
+func WithInterpolationSearch[T Numeric]() Option[T] {
+	return func(o *options[T]) {
+		o.less = Less[T]()
+		o.search = interpolationFind[T]
+	}
+}
+
+// WithLimit sets a soft limit on the total weight of the tree's items, as if
+// by calling SetLimit on the new tree.  As with SetLimit, a max less than
+// zero sets no limit.
+func WithLimit[T any](max int64, weight func(T) int64, evict EvictFuncG[T]) Option[T] {
+	return func(o *options[T]) { o.limit = newLimit(max, weight, evict) }
+}
+
+// WithOrderingChecks makes the tree check that its LessFunc behaves like a
+// strict weak ordering, panicking with the offending items as soon as it
+// finds a problem.  Every comparison checks that less(a, b) and less(b, a)
+// are not both true, and every insert and delete spot-checks transitivity
+// against the neighbouring items in the leaf it changes.
+//
+// An inconsistent LessFunc otherwise corrupts the tree silently, and the
+// symptoms (missing items, crashes during Delete) tend to appear long after
+// the comparison that caused them.  The checks roughly double the cost of
+// every comparison, so they are meant for tests and debugging.
+func WithOrderingChecks[T any]() Option[T] {
+	return func(o *options[T]) { o.checkOrder = true }
+}
+
+// WithModificationChecks makes iteration over the tree panic with
+// ErrModifiedDuringIteration if the tree is written to while the iteration
+// is in progress, for example by an Ascend callback that deletes items, or by
+// writes between calls to a CursorG's Next.  Such iterations otherwise
+// silently skip or repeat items.
+//
+// The checks are cheap, but like all of this package's checks they can't
+// reliably catch writes made by other goroutines without synchronization;
+// those are data races, which the race detector finds.
+func WithModificationChecks[T any]() Option[T] {
+	return func(o *options[T]) { o.checkMods = true }
+}
+
+// WithHooks registers callbacks that observe changes to the tree's structure;
+// see Hooks.  The hooks are shared with clones of the tree.
+func WithHooks[T any](h Hooks[T]) Option[T] {
+	return func(o *options[T]) { o.hooks = &h }
+}
+
+// WithTracer makes the tree call trace after each bulk operation, such as
+// Rebuild or Clear(true), that may take time proportional to the size of
+// the tree; see TraceEvent.  The tracer is shared with clones of the tree.
+func WithTracer[T any](trace func(TraceEvent)) Option[T] {
+	return func(o *options[T]) { o.tracer = trace }
+}
+
+// WithOrderStatistics makes the tree count the items in each of its
+// subtrees, like NewCountedG, so that EstimateRange is exact and Counted
+// gives access to Rank, At and CountRange.  Writes pay O(log n) extra
+// additions to keep the counts current.
+func WithOrderStatistics[T any]() Option[T] {
+	return func(o *options[T]) { o.orderStats = true }
+}
+
+// NewWithOptions creates a new B-Tree configured by the given options.
+// An ordering option is required; everything else has a default.  For example:
+//
+//	tr := btree.NewWithOptions(
+//		btree.WithLess(func(a, b *Record) bool { return a.ID < b.ID }),
+//		btree.WithDegree[*Record](16),
+//	)
+//
+// NewWithOptions panics if the degree is less than 2 or no ordering is given.
+//
+// There is no option for duplicate items: a tree that keeps them stores
+// them tagged with their insertion order, so it's a different type,
+// MultisetG, created by NewMultisetG.
+func NewWithOptions[T any](opts ...Option[T]) *BTreeG[T] {
+	o := applyOptions(opts)
+	if o.less == nil {
+		panic("btree: no LessFunc")
+	}
+	return newFromOptions(o)
+}
+
+// applyOptions returns the configuration given by opts.
+func applyOptions[T any](opts []Option[T]) options[T] {
+	o := options[T]{degree: DefaultDegree}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// newFromOptions creates a new B-Tree configured by o, which must have a
+// LessFunc.
+func newFromOptions[T any](o options[T]) *BTreeG[T] {
+	if o.freelist == nil {
+		o.freelist = NewFreeListG[T](DefaultFreeListSize)
+	}
+	t := NewWithFreeListG(o.degree, o.less, o.freelist)
+	t.cow.search = o.search
+	t.cow.checkMods = o.checkMods
+	t.cow.hooks = o.hooks
+	t.cow.tracer = o.tracer
+	t.cow.bloomHash = o.bloomHash
+	t.cow.splitPolicy = o.splitPolicy
+	t.cow.keyHash = o.keyHash
+	t.cow.equal = o.equal
+	t.cow.counted = o.orderStats
+	t.cow.recoverLess = o.recoverLess
+	t.cow.checkOrder = o.checkOrder
+	if o.countComparisons {
+		t.cow.comparisons = &comparisonCounter{}
+	}
+	t.cow.setLess(o.less)
+	if o.timeOps {
+		t.cow.latency = &latencyRecorder{}
+	}
+	if o.orderStats {
+		t.cow.augment = countAugment[T]().refresh
+	}
+	t.limit = o.limit
+	return t
+}
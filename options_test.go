@@ -0,0 +1,108 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestNewWithOptions(t *testing.T) {
+	fl := NewFreeListG[int](10)
+	for _, tr := range []*BTreeG[int]{
+		NewWithOptions(WithOrdered[int]()),
+		NewWithOptions(WithLess(Less[int]()), WithDegree[int](2), WithFreeList(fl)),
+		NewWithOptions(WithInterpolationSearch[int](), WithDegree[int](3)),
+	} {
+		for _, v := range rand.Perm(100) {
+			tr.ReplaceOrInsert(v)
+		}
+		if got, want := intAll(tr), intRange(100, false); !reflect.DeepEqual(got, want) {
+			t.Fatalf("mismatch:\n got: %v\nwant: %v", got, want)
+		}
+	}
+	tr := NewWithOptions(WithOrdered[int](), WithLimit[int](10, nil, EvictMaxG[int]))
+	for _, v := range rand.Perm(100) {
+		tr.ReplaceOrInsert(v)
+	}
+	if got, want := intAll(tr), intRange(10, false); !reflect.DeepEqual(got, want) {
+		t.Fatalf("WithLimit:\n got: %v\nwant: %v", got, want)
+	}
+	// A negative max means no limit, as with SetLimit.
+	tr = NewWithOptions(WithOrdered[int](), WithLimit[int](-1, nil, nil))
+	for _, v := range rand.Perm(100) {
+		tr.ReplaceOrInsert(v)
+	}
+	if tr.Len() != 100 || tr.limit != nil {
+		t.Fatalf("WithLimit(-1): Len() = %v", tr.Len())
+	}
+
+	// A later WithLess replaces interpolation search along with its ordering.
+	desc := NewWithOptions(WithInterpolationSearch[int](), WithLess(func(a, b int) bool { return a > b }))
+	for _, v := range rand.Perm(100) {
+		desc.ReplaceOrInsert(v)
+	}
+	for v := 0; v < 100; v++ {
+		if !desc.Has(v) {
+			t.Fatalf("WithInterpolationSearch then WithLess: Has(%v) = false", v)
+		}
+	}
+	if min, _ := desc.Min(); min != 99 {
+		t.Fatalf("WithInterpolationSearch then WithLess: Min() = %v, want 99", min)
+	}
+
+	for _, opts := range [][]Option[int]{
+		{},
+		{WithOrdered[int](), WithDegree[int](1)},
+	} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("NewWithOptions(%v options) didn't panic", len(opts))
+				}
+			}()
+			NewWithOptions(opts...)
+		}()
+	}
+}
+
+func TestWithOrderStatistics(t *testing.T) {
+	if NewWithOptions(WithOrdered[int]()).Counted() != nil {
+		t.Fatal("Counted() of a tree without WithOrderStatistics isn't nil")
+	}
+	tr := NewWithOptions(WithOrdered[int](), WithDegree[int](2), WithOrderStatistics[int]())
+	for _, v := range rand.Perm(1000) {
+		tr.ReplaceOrInsert(v)
+	}
+	for v := 0; v < 1000; v += 2 {
+		tr.Delete(v)
+	}
+	c := tr.Counted()
+	for i := 0; i < 500; i++ {
+		if got, ok := c.At(i); !ok || got != 2*i+1 {
+			t.Fatalf("At(%v) = %v, %v, want %v", i, got, ok, 2*i+1)
+		}
+		if got := c.Rank(2*i + 1); got != i {
+			t.Fatalf("Rank(%v) = %v, want %v", 2*i+1, got, i)
+		}
+	}
+	if got, exact := tr.EstimateRange(100, 200); got != 50 || !exact {
+		t.Fatalf("EstimateRange(100, 200) = %v, %v, want 50, true", got, exact)
+	}
+}
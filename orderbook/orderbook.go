@@ -0,0 +1,167 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+// Package orderbook aggregates orders into price levels on both sides of a
+// market, as kept by trading systems and matching engines.
+//
+// Each side is a btree.BTreeG of levels ordered best price first, that is
+// descending for bids and ascending for asks, so both sides are walked the
+// same way, from the best level outwards.  The best level of each side is
+// cached, so reading it takes O(1) time.
+package orderbook
+
+import "github.com/google/btree"
+
+// Side is a side of the book.
+type Side int
+
+const (
+	Bid Side = iota
+	Ask
+)
+
+// Level is the total quantity resting at a price on one side of the book.
+type Level[P btree.Ordered, Q btree.Numeric] struct {
+	Price    P
+	Quantity Q
+	Orders   int // the number of orders making up Quantity
+}
+
+// Book is an order book with price type P and quantity type Q.  It is not
+// safe for concurrent use.
+type Book[P btree.Ordered, Q btree.Numeric] struct {
+	sides [2]*btree.BTreeG[Level[P, Q]]
+	best  [2]*Level[P, Q] // nil if the side is empty
+}
+
+// New returns an empty book.
+func New[P btree.Ordered, Q btree.Numeric]() *Book[P, Q] {
+	bids := func(a, b Level[P, Q]) bool { return a.Price > b.Price }
+	asks := func(a, b Level[P, Q]) bool { return a.Price < b.Price }
+	return &Book[P, Q]{sides: [2]*btree.BTreeG[Level[P, Q]]{
+		btree.NewG(btree.DefaultDegree, bids),
+		btree.NewG(btree.DefaultDegree, asks),
+	}}
+}
+
+// Add adds an order for quantity at price to a side of the book.
+func (b *Book[P, Q]) Add(side Side, price P, quantity Q) {
+	t := b.sides[side]
+	l, _ := t.Get(Level[P, Q]{Price: price})
+	l.Price = price
+	l.Quantity += quantity
+	l.Orders++
+	t.ReplaceOrInsert(l)
+	b.refresh(side)
+}
+
+// Remove removes an order for quantity at price from a side of the book,
+// such as when it is cancelled or filled.  The level is removed once its
+// last order is.  Remove returns false if there is no level at price.
+func (b *Book[P, Q]) Remove(side Side, price P, quantity Q) bool {
+	t := b.sides[side]
+	l, ok := t.Get(Level[P, Q]{Price: price})
+	if !ok {
+		return false
+	}
+	l.Quantity -= quantity
+	l.Orders--
+	if l.Orders <= 0 {
+		t.Delete(l)
+	} else {
+		t.ReplaceOrInsert(l)
+	}
+	b.refresh(side)
+	return true
+}
+
+// refresh updates the cached best level of a side after a change.
+func (b *Book[P, Q]) refresh(side Side) {
+	if l, ok := b.sides[side].Min(); ok {
+		b.best[side] = &l
+	} else {
+		b.best[side] = nil
+	}
+}
+
+// Best returns the best level of a side: the highest bid or the lowest ask.
+func (b *Book[P, Q]) Best(side Side) (_ Level[P, Q], _ bool) {
+	if l := b.best[side]; l != nil {
+		return *l, true
+	}
+	return
+}
+
+// BestBid returns the highest bid level.
+func (b *Book[P, Q]) BestBid() (Level[P, Q], bool) {
+	return b.Best(Bid)
+}
+
+// BestAsk returns the lowest ask level.
+func (b *Book[P, Q]) BestAsk() (Level[P, Q], bool) {
+	return b.Best(Ask)
+}
+
+// Crossed returns true if the best bid is at or above the best ask, meaning
+// orders on opposite sides can be matched.
+func (b *Book[P, Q]) Crossed() bool {
+	bid, ask := b.best[Bid], b.best[Ask]
+	return bid != nil && ask != nil && bid.Price >= ask.Price
+}
+
+// Level returns the level at price on a side of the book.
+func (b *Book[P, Q]) Level(side Side, price P) (Level[P, Q], bool) {
+	return b.sides[side].Get(Level[P, Q]{Price: price})
+}
+
+// Len returns the number of levels on a side of the book.
+func (b *Book[P, Q]) Len(side Side) int {
+	return b.sides[side].Len()
+}
+
+// Levels calls iter for each level on a side of the book, best first, until
+// iter returns false.
+func (b *Book[P, Q]) Levels(side Side, iter func(Level[P, Q]) bool) {
+	b.sides[side].Ascend(btree.ItemIteratorG[Level[P, Q]](iter))
+}
+
+// Depth returns the total quantity on a side of the book at prices as good
+// as limit or better, that is, bids at or above limit, or asks at or below
+// it: the quantity available to an order crossing up to limit.
+func (b *Book[P, Q]) Depth(side Side, limit P) (total Q) {
+	b.sides[side].Ascend(func(l Level[P, Q]) bool {
+		if (side == Bid && l.Price < limit) || (side == Ask && l.Price > limit) {
+			return false
+		}
+		total += l.Quantity
+		return true
+	})
+	return total
+}
+
+// Top returns the best n levels of a side, best first.
+func (b *Book[P, Q]) Top(side Side, n int) []Level[P, Q] {
+	var out []Level[P, Q]
+	b.sides[side].Ascend(func(l Level[P, Q]) bool {
+		if len(out) >= n {
+			return false
+		}
+		out = append(out, l)
+		return true
+	})
+	return out
+}
@@ -0,0 +1,66 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package orderbook
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestBook(t *testing.T) {
+	b := New[int, int]()
+	if _, ok := b.BestBid(); ok {
+		t.Fatal("empty book has a best bid")
+	}
+	b.Add(Bid, 99, 10)
+	b.Add(Bid, 100, 5)
+	b.Add(Bid, 100, 7)
+	b.Add(Ask, 102, 3)
+	b.Add(Ask, 101, 4)
+	if l, _ := b.BestBid(); l != (Level[int, int]{100, 12, 2}) {
+		t.Errorf("BestBid() = %+v", l)
+	}
+	if l, _ := b.BestAsk(); l.Price != 101 {
+		t.Errorf("BestAsk() = %+v", l)
+	}
+	if b.Crossed() {
+		t.Error("book crossed")
+	}
+	if got := b.Depth(Ask, 102); got != 7 {
+		t.Errorf("Depth(Ask, 102) = %d, want 7", got)
+	}
+	if got := b.Depth(Bid, 100); got != 12 {
+		t.Errorf("Depth(Bid, 100) = %d, want 12", got)
+	}
+	want := []Level[int, int]{{100, 12, 2}, {99, 10, 1}}
+	if got := b.Top(Bid, 5); !reflect.DeepEqual(got, want) {
+		t.Errorf("Top(Bid, 5) = %v, want %v", got, want)
+	}
+	b.Remove(Bid, 100, 5)
+	b.Remove(Bid, 100, 7)
+	if l, _ := b.BestBid(); l.Price != 99 || b.Len(Bid) != 1 {
+		t.Errorf("after removing level 100, BestBid() = %+v", l)
+	}
+	if b.Remove(Bid, 100, 1) {
+		t.Error("removed from a missing level")
+	}
+	b.Add(Bid, 101, 1)
+	if !b.Crossed() {
+		t.Error("book not crossed")
+	}
+}
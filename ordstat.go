@@ -0,0 +1,203 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+// CountedG is an AugmentedG whose summary is the number of items in each
+// subtree.  The counts give it order statistics: finding the rank of an
+// item, the item at a rank, or the number of items in a range all take
+// O(log n) time.  Other methods that deal in ranks or counts, such as
+// EstimateRange, use the counts when they're available.
+type CountedG[T any] struct {
+	*AugmentedG[T, int]
+}
+
+// NewCountedG creates a new CountedG with the given degree, ordered by less.
+func NewCountedG[T any](degree int, less LessFunc[T]) *CountedG[T] {
+	t := NewAugmentedG(degree, less, countAugment[T]())
+	t.cow.counted = true
+	return &CountedG[T]{t}
+}
+
+// countAugment summarizes a run of items by its length.
+func countAugment[T any]() Augment[T, int] {
+	return Augment[T, int]{
+		Of:      func(T) int { return 1 },
+		Combine: func(a, b int) int { return a + b },
+	}
+}
+
+// Counted returns a CountedG sharing t's items, or nil if t doesn't count
+// its subtrees; only trees created by NewCountedG, or by NewWithOptions with
+// WithOrderStatistics, do.
+func (t *BTreeG[T]) Counted() *CountedG[T] {
+	if !t.cow.counted {
+		return nil
+	}
+	return &CountedG[T]{&AugmentedG[T, int]{BTreeG: t, aug: countAugment[T]()}}
+}
+
+// Clone clones the tree lazily, like BTreeG.Clone.
+func (t *CountedG[T]) Clone() *CountedG[T] {
+	return &CountedG[T]{t.AugmentedG.Clone()}
+}
+
+// Rank returns the number of items in the tree less than key.  If key is in
+// the tree, this is its index in ascending order.
+func (t *CountedG[T]) Rank(key T) int {
+	rank, _ := t.rank(key)
+	return rank
+}
+
+// Rank64 is like Rank, but returns an int64; see BTreeG.Len64.
+func (t *CountedG[T]) Rank64(key T) int64 {
+	return int64(t.Rank(key))
+}
+
+// At returns the item with the given rank, that is, the item at index i in
+// ascending order, or (zeroValue, false) if i is out of range.
+func (t *CountedG[T]) At(i int) (_ T, _ bool) {
+	if i < 0 {
+		return
+	}
+	return t.Search(func(count int) bool { return count > i })
+}
+
+// CountRange returns the number of items within the range [greaterOrEqual,
+// lessThan).
+func (t *CountedG[T]) CountRange(greaterOrEqual, lessThan T) int {
+	count, _ := t.EstimateRange(greaterOrEqual, lessThan)
+	return count
+}
+
+// childSize returns the number of items under the i'th child of the node
+// counted by s, or estimate if s is nil because the tree doesn't count them.
+func childSize[T any](s *sumNode[T, int], i, estimate int) int {
+	if s != nil {
+		return s.children[i].sum
+	}
+	return estimate
+}
+
+// rank returns the number of items less than key, and whether that is exact.
+// It is exact if the tree counts its subtrees; otherwise it is estimated
+// from the path to key, assuming that each node's subtrees are the same size.
+func (t *BTreeG[T]) rank(key T) (rank int, exact bool) {
+	size := t.length
+	var counts *sumNode[T, int]
+	if t.cow.counted {
+		counts, _ = t.sums.(*sumNode[T, int])
+	}
+	for n := t.root; n != nil; {
+		i, found := n.find(key)
+		rank += i
+		if len(n.children) == 0 {
+			break
+		}
+		each := (size - len(n.items)) / len(n.children)
+		for j := 0; j < i; j++ {
+			rank += childSize(counts, j, each)
+		}
+		size = childSize(counts, i, each)
+		if found {
+			rank += size
+			break
+		}
+		n = n.children[i]
+		if counts != nil {
+			counts = counts.children[i]
+		}
+	}
+	return rank, t.cow.counted
+}
+
+// EstimateRange returns the number of items within the range
+// [greaterOrEqual, lessThan), for query planning and the like, without
+// visiting them.  The count is exact, and exact is true, if the tree was
+// created by NewCountedG or with WithOrderStatistics.  Otherwise the count
+// is estimated from the shape of the tree along the paths to the two ends of
+// the range, assuming that the subtrees of each node on those paths hold
+// equal numbers of items.
+// Either way, EstimateRange takes O(log n) time.
+func (t *BTreeG[T]) EstimateRange(greaterOrEqual, lessThan T) (count int, exact bool) {
+	if !t.cow.less(greaterOrEqual, lessThan) {
+		return 0, true
+	}
+	lo, exact := t.rank(greaterOrEqual)
+	hi, _ := t.rank(lessThan)
+	if hi < lo {
+		return 0, exact
+	}
+	return hi - lo, exact
+}
+
+// exactRank returns the number of items less than key.  It takes O(log n)
+// time if the tree counts its subtrees, and otherwise counts the nodes (but
+// not the items) to the left of key's path.
+func (t *BTreeG[T]) exactRank(key T) int {
+	if t.cow.counted {
+		rank, _ := t.rank(key)
+		return rank
+	}
+	rank := 0
+	for n := t.root; n != nil; {
+		i, found := n.find(key)
+		rank += i
+		if len(n.children) == 0 {
+			break
+		}
+		for _, c := range n.children[:i] {
+			rank += countItems(c)
+		}
+		if found {
+			rank += countItems(n.children[i])
+			break
+		}
+		n = n.children[i]
+	}
+	return rank
+}
+
+// IndexIteratorG is like ItemIteratorG, but is also passed the index of item
+// in ascending order within the whole tree.
+type IndexIteratorG[T any] func(i int, item T) bool
+
+// AscendWithIndex calls the iterator for every value in the tree, with its
+// index, until iterator returns false.
+func (t *BTreeG[T]) AscendWithIndex(iterator IndexIteratorG[T]) {
+	i := 0
+	t.ascend(func(item T) bool {
+		i++
+		return iterator(i-1, item)
+	})
+}
+
+// AscendRangeWithIndex calls the iterator for every value in the tree within
+// the range [greaterOrEqual, lessThan), with its index in the whole tree,
+// until iterator returns false.  Finding the index of the first item takes
+// O(log n) time in a CountedG, and otherwise time proportional to the
+// number of nodes before it.
+func (t *BTreeG[T]) AscendRangeWithIndex(greaterOrEqual, lessThan T, iterator IndexIteratorG[T]) {
+	i := -1
+	t.AscendRange(greaterOrEqual, lessThan, func(item T) bool {
+		if i < 0 {
+			i = t.exactRank(item)
+		}
+		i++
+		return iterator(i-1, item)
+	})
+}
@@ -0,0 +1,112 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestCounted(t *testing.T) {
+	tr := NewCountedG(*btreeDegree, Less[int]())
+	for _, v := range rand.Perm(1000) {
+		tr.ReplaceOrInsert(v * 2)
+	}
+	for i := 0; i < 500; i++ {
+		tr.Delete(rand.Intn(2000))
+	}
+	all := tr.all()
+	for i, v := range all {
+		if got := tr.Rank(v); got != i {
+			t.Fatalf("Rank(%d) = %d, want %d", v, got, i)
+		}
+		if got, _ := tr.At(i); got != v {
+			t.Fatalf("At(%d) = %d, want %d", i, got, v)
+		}
+	}
+	if got := tr.Len64(); got != int64(len(all)) {
+		t.Errorf("Len64() = %d, want %d", got, len(all))
+	}
+	if got := tr.Rank64(all[len(all)-1] + 1); got != int64(len(all)) {
+		t.Errorf("Rank64(past max) = %d, want %d", got, len(all))
+	}
+	if _, ok := tr.At(len(all)); ok {
+		t.Errorf("At(Len()) succeeded")
+	}
+	for trial := 0; trial < 100; trial++ {
+		lo, hi := rand.Intn(2100)-50, rand.Intn(2100)-50
+		want := 0
+		for _, v := range all {
+			if v >= lo && v < hi {
+				want++
+			}
+		}
+		if got, exact := tr.EstimateRange(lo, hi); got != want || !exact {
+			t.Fatalf("EstimateRange(%d, %d) = %d, %v; want %d", lo, hi, got, exact, want)
+		}
+	}
+}
+
+func TestEstimateRange(t *testing.T) {
+	tr := NewOrderedG[int](*btreeDegree)
+	const n = 100000
+	for _, v := range rand.Perm(n) {
+		tr.ReplaceOrInsert(v)
+	}
+	for trial := 0; trial < 100; trial++ {
+		lo := rand.Intn(n)
+		hi := lo + 1 + rand.Intn(n-lo)
+		got, exact := tr.EstimateRange(lo, hi)
+		if exact {
+			t.Fatal("estimate claims to be exact")
+		}
+		if diff := got - (hi - lo); diff < -n/10 || diff > n/10 {
+			t.Errorf("EstimateRange(%d, %d) = %d, off by %d", lo, hi, got, diff)
+		}
+	}
+	if got, _ := tr.EstimateRange(5, 5); got != 0 {
+		t.Errorf("empty range estimated at %d", got)
+	}
+}
+
+func TestAscendWithIndex(t *testing.T) {
+	plain := NewOrderedG[int](*btreeDegree)
+	counted := NewCountedG(*btreeDegree, Less[int]())
+	for _, v := range rand.Perm(500) {
+		plain.ReplaceOrInsert(v * 2)
+		counted.ReplaceOrInsert(v * 2)
+	}
+	for _, tr := range []*BTreeG[int]{plain, counted.BTreeG} {
+		tr.AscendWithIndex(func(i, item int) bool {
+			if item != i*2 {
+				t.Fatalf("index %d has item %d", i, item)
+			}
+			return true
+		})
+		for _, lo := range []int{-1, 0, 1, 77, 500, 998, 999} {
+			n := 0
+			tr.AscendRangeWithIndex(lo, lo+30, func(i, item int) bool {
+				if item != i*2 {
+					t.Fatalf("from %d: index %d has item %d", lo, i, item)
+				}
+				n++
+				return n < 10
+			})
+		}
+	}
+}
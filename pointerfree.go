@@ -0,0 +1,79 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+import "reflect"
+
+// The items methods zero the slots they vacate, so that a node's spare
+// capacity doesn't keep removed items reachable by the garbage collector.
+// For item types that contain no pointers there is nothing to keep
+// reachable, so the node methods below skip the zeroing for them.
+
+// pointerFree reports whether values of type T contain no pointers.
+func pointerFree[T any]() bool {
+	return !hasPointers(reflect.TypeOf((*T)(nil)).Elem())
+}
+
+func hasPointers(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64, reflect.Complex64, reflect.Complex128:
+		return false
+	case reflect.Array:
+		return t.Len() > 0 && hasPointers(t.Elem())
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if hasPointers(t.Field(i).Type) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// removeItem removes and returns the item at index i.
+func (n *node[T]) removeItem(i int) T {
+	if !n.cow.pointerFree {
+		return n.items.removeAt(i)
+	}
+	item := n.items[i]
+	copy(n.items[i:], n.items[i+1:])
+	n.items = n.items[:len(n.items)-1]
+	return item
+}
+
+// popItem removes and returns the last item.
+func (n *node[T]) popItem() T {
+	if !n.cow.pointerFree {
+		return n.items.pop()
+	}
+	item := n.items[len(n.items)-1]
+	n.items = n.items[:len(n.items)-1]
+	return item
+}
+
+// truncateItems keeps only the first i items.
+func (n *node[T]) truncateItems(i int) {
+	if !n.cow.pointerFree {
+		n.items.truncate(i)
+		return
+	}
+	n.items = n.items[:i]
+}
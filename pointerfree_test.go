@@ -0,0 +1,74 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+import "testing"
+
+func TestPointerFree(t *testing.T) {
+	type flat struct {
+		a int
+		b [4]float64
+	}
+	type withString struct {
+		a int
+		s string
+	}
+	for _, c := range []struct {
+		name string
+		got  bool
+		want bool
+	}{
+		{"int", pointerFree[int](), true},
+		{"float64", pointerFree[float64](), true},
+		{"flat struct", pointerFree[flat](), true},
+		{"[0]*int", pointerFree[[0]*int](), true},
+		{"string", pointerFree[string](), false},
+		{"*int", pointerFree[*int](), false},
+		{"struct with string", pointerFree[withString](), false},
+		{"[]int", pointerFree[[]int](), false},
+		{"Item", pointerFree[Item](), false},
+	} {
+		if c.got != c.want {
+			t.Errorf("pointerFree[%s]() = %v, want %v", c.name, c.got, c.want)
+		}
+	}
+}
+
+func TestRemovedPointersCleared(t *testing.T) {
+	tr := NewG(*btreeDegree, func(a, b *int) bool { return *a < *b })
+	for i := 0; i < 100; i++ {
+		v := i
+		tr.ReplaceOrInsert(&v)
+	}
+	for i := 0; i < 100; i += 3 {
+		v := i
+		tr.Delete(&v)
+	}
+	var walk func(n *node[*int])
+	walk = func(n *node[*int]) {
+		for _, p := range n.items[len(n.items):cap(n.items)] {
+			if p != nil {
+				t.Fatalf("removed item %d still referenced by a node", *p)
+			}
+		}
+		for _, c := range n.children {
+			walk(c)
+		}
+	}
+	walk(tr.root)
+}
@@ -0,0 +1,150 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+// PQ is a double-ended priority queue built on a BTreeG.
+//
+// Unlike a BTreeG, a PQ may hold several items that are equal according to
+// its LessFunc.  PopMin pops equal items in the order they were pushed, and
+// PopMax in the reverse order.  Push
+// returns a Handle to the queued item, which stays valid until the item is
+// popped or removed, and can be used to change the item's priority in
+// O(log n) time.
+//
+// A PQ is not safe for concurrent use.
+type PQ[T any] struct {
+	t   *BTreeG[*Handle[T]]
+	seq uint64
+}
+
+// Handle refers to an item in a PQ.
+type Handle[T any] struct {
+	item T
+	seq  uint64 // push order, to break ties between equal items
+	pq   *PQ[T] // nil once the item has left the queue
+}
+
+// Item returns the item the handle refers to.
+func (h *Handle[T]) Item() T {
+	return h.item
+}
+
+// Queued returns true if the item is still in its queue.
+func (h *Handle[T]) Queued() bool {
+	return h.pq != nil
+}
+
+// NewPQ returns an empty priority queue ordered by less.
+func NewPQ[T any](less LessFunc[T]) *PQ[T] {
+	return &PQ[T]{t: NewG(DefaultDegree, func(a, b *Handle[T]) bool {
+		switch {
+		case less(a.item, b.item):
+			return true
+		case less(b.item, a.item):
+			return false
+		}
+		return a.seq < b.seq
+	})}
+}
+
+// Len returns the number of items in the queue.
+func (q *PQ[T]) Len() int {
+	return q.t.Len()
+}
+
+// Push adds item to the queue, returning a handle to it.
+func (q *PQ[T]) Push(item T) *Handle[T] {
+	q.seq++
+	h := &Handle[T]{item: item, seq: q.seq, pq: q}
+	q.t.ReplaceOrInsert(h)
+	return h
+}
+
+// PeekMin returns the smallest item in the queue, or (zeroValue, false) if
+// the queue is empty.
+func (q *PQ[T]) PeekMin() (_ T, _ bool) {
+	if h, ok := q.t.Min(); ok {
+		return h.item, true
+	}
+	return
+}
+
+// PeekMax returns the largest item in the queue, or (zeroValue, false) if
+// the queue is empty.
+func (q *PQ[T]) PeekMax() (_ T, _ bool) {
+	if h, ok := q.t.Max(); ok {
+		return h.item, true
+	}
+	return
+}
+
+// PopMin removes and returns the smallest item in the queue, or
+// (zeroValue, false) if the queue is empty.
+func (q *PQ[T]) PopMin() (_ T, _ bool) {
+	if h, ok := q.t.DeleteMin(); ok {
+		h.pq = nil
+		return h.item, true
+	}
+	return
+}
+
+// PopMax removes and returns the largest item in the queue, or
+// (zeroValue, false) if the queue is empty.
+func (q *PQ[T]) PopMax() (_ T, _ bool) {
+	if h, ok := q.t.DeleteMax(); ok {
+		h.pq = nil
+		return h.item, true
+	}
+	return
+}
+
+// Remove removes h's item from the queue, returning false if it had already
+// left the queue.
+func (q *PQ[T]) Remove(h *Handle[T]) bool {
+	if h.pq != q {
+		return false
+	}
+	q.t.Delete(h)
+	h.pq = nil
+	return true
+}
+
+// UpdatePriority replaces h's item with item, which may order differently,
+// and moves it to its new place in the queue.  It returns false, and does
+// nothing, if h's item has already left the queue.  The updated item is
+// treated as newly pushed when breaking ties.
+func (q *PQ[T]) UpdatePriority(h *Handle[T], item T) bool {
+	if h.pq != q {
+		return false
+	}
+	q.t.Delete(h)
+	q.seq++
+	h.item, h.seq = item, q.seq
+	q.t.ReplaceOrInsert(h)
+	return true
+}
+
+// AscendRange calls the iterator for every item in the queue within the
+// range [greaterOrEqual, lessThan), in the order PopMin would pop them,
+// until iterator returns false.  The queue must not be modified during the
+// iteration.
+func (q *PQ[T]) AscendRange(greaterOrEqual, lessThan T, iterator ItemIteratorG[T]) {
+	q.t.AscendRange(&Handle[T]{item: greaterOrEqual}, &Handle[T]{item: lessThan}, func(h *Handle[T]) bool {
+		return iterator(h.item)
+	})
+}
@@ -0,0 +1,98 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+import (
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestPQ(t *testing.T) {
+	q := NewPQ(Less[int]())
+	perm := rand.Perm(100)
+	for _, v := range perm {
+		q.Push(v)
+	}
+	if v, _ := q.PeekMin(); v != 0 {
+		t.Errorf("PeekMin() = %d, want 0", v)
+	}
+	if v, _ := q.PeekMax(); v != 99 {
+		t.Errorf("PeekMax() = %d, want 99", v)
+	}
+	var got []int
+	for q.Len() > 0 {
+		lo, _ := q.PopMin()
+		hi, _ := q.PopMax()
+		got = append(got, lo, hi)
+	}
+	if want := []int{0, 99, 1, 98, 2, 97}; !reflect.DeepEqual(got[:6], want) {
+		t.Errorf("popped %v, want prefix %v", got, want)
+	}
+	if _, ok := q.PopMin(); ok {
+		t.Error("PopMin on empty queue succeeded")
+	}
+}
+
+type pqJob struct {
+	name     string
+	priority int
+}
+
+func TestPQEqualPriorities(t *testing.T) {
+	q := NewPQ(func(a, b pqJob) bool { return a.priority < b.priority })
+	for _, name := range []string{"a", "b", "c"} {
+		q.Push(pqJob{name, 1})
+	}
+	var got []string
+	for q.Len() > 0 {
+		j, _ := q.PopMin()
+		got = append(got, j.name)
+	}
+	if want := []string{"a", "b", "c"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("popped %v, want %v", got, want)
+	}
+}
+
+func TestPQUpdatePriority(t *testing.T) {
+	q := NewPQ(func(a, b pqJob) bool { return a.priority < b.priority })
+	var handles []*Handle[pqJob]
+	for i := 0; i < 100; i++ {
+		handles = append(handles, q.Push(pqJob{"", i}))
+	}
+	for i, h := range handles {
+		if !q.UpdatePriority(h, pqJob{"", 99 - i}) {
+			t.Fatalf("UpdatePriority(%d) failed", i)
+		}
+	}
+	if !q.Remove(handles[99]) || q.Remove(handles[99]) || handles[99].Queued() {
+		t.Error("Remove didn't remove exactly once")
+	}
+	var got []int
+	for q.Len() > 0 {
+		j, _ := q.PopMin()
+		got = append(got, j.priority)
+	}
+	if len(got) != 99 || got[0] != 1 || !sort.IntsAreSorted(got) {
+		t.Errorf("popped %v", got)
+	}
+	if handles[0].Queued() || q.UpdatePriority(handles[0], pqJob{}) {
+		t.Error("popped handle still usable")
+	}
+}
@@ -0,0 +1,269 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+// Package prefixset is an ordered set of strings that stores runs of
+// neighbouring strings with their common prefix factored out, for sets such
+// as URLs and file paths, whose strings mostly repeat the ones next to them.
+//
+// The strings are held in blocks of up to blockSize consecutive strings.
+// Each block stores the prefix its strings share once, and a copy of the
+// rest of each string, and the blocks are kept in a btree.BTreeG ordered by
+// their first strings.  Strings are put back together as they are read, so
+// reads allocate, in exchange for the set taking much less memory than the
+// strings themselves.
+package prefixset
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/google/btree"
+)
+
+// blockSize is the maximum number of strings in a block.  Blocks that fall
+// below a quarter of this are merged with their successor where possible.
+const blockSize = 64
+
+type block struct {
+	first    string // the first string in the block, by which blocks are ordered
+	prefix   string
+	suffixes []string // sorted
+}
+
+func less(a, b *block) bool {
+	return a.first < b.first
+}
+
+// newBlock returns a block holding keys, which must be sorted, non-empty,
+// and free of duplicates.
+func newBlock(keys []string) *block {
+	prefix := clone(commonPrefix(keys[0], keys[len(keys)-1]))
+	b := &block{first: clone(keys[0]), prefix: prefix, suffixes: make([]string, len(keys))}
+	for i, k := range keys {
+		b.suffixes[i] = clone(k[len(prefix):])
+	}
+	return b
+}
+
+func (b *block) key(i int) string {
+	return b.prefix + b.suffixes[i]
+}
+
+func (b *block) keys() []string {
+	keys := make([]string, len(b.suffixes))
+	for i := range b.suffixes {
+		keys[i] = b.key(i)
+	}
+	return keys
+}
+
+// find returns the index at which s is or would be in b, and whether it's
+// there.  s must have b's prefix.
+func (b *block) find(s string) (int, bool) {
+	suffix := s[len(b.prefix):]
+	i := sort.SearchStrings(b.suffixes, suffix)
+	return i, i < len(b.suffixes) && b.suffixes[i] == suffix
+}
+
+// Set is an ordered set of strings.  It is not safe for concurrent use.
+type Set struct {
+	t *btree.BTreeG[*block]
+	n int
+}
+
+// New returns an empty set.
+func New() *Set {
+	return &Set{t: btree.NewG(btree.DefaultDegree, less)}
+}
+
+// Len returns the number of strings in the set.
+func (s *Set) Len() int {
+	return s.n
+}
+
+// floor returns the last block whose first string is not greater than key,
+// or nil if there is none.
+func (s *Set) floor(key string) (b *block) {
+	s.t.DescendLessOrEqual(&block{first: key}, func(x *block) bool {
+		b = x
+		return false
+	})
+	return b
+}
+
+// Has returns true if key is in the set.
+func (s *Set) Has(key string) bool {
+	b := s.floor(key)
+	if b == nil || !strings.HasPrefix(key, b.prefix) {
+		return false
+	}
+	_, found := b.find(key)
+	return found
+}
+
+// Insert adds key to the set, returning false if it was already there.
+func (s *Set) Insert(key string) bool {
+	b := s.floor(key)
+	if b == nil {
+		if b, _ = s.t.Min(); b == nil {
+			s.t.ReplaceOrInsert(newBlock([]string{key}))
+			s.n++
+			return true
+		}
+	}
+	if !strings.HasPrefix(key, b.prefix) {
+		b.reprefix(commonPrefix(b.prefix, key))
+	}
+	i, found := b.find(key)
+	if found {
+		return false
+	}
+	b.suffixes = append(b.suffixes, "")
+	copy(b.suffixes[i+1:], b.suffixes[i:])
+	b.suffixes[i] = clone(key[len(b.prefix):])
+	s.n++
+	if i == 0 {
+		// key is less than every string in the set, so b is the first
+		// block, and no other block can be ordered between its old first
+		// string and key: it can be reordered in place.
+		b.first = clone(key)
+	}
+	if len(b.suffixes) > blockSize {
+		keys := b.keys()
+		s.t.ReplaceOrInsert(newBlock(keys[:len(keys)/2]))
+		s.t.ReplaceOrInsert(newBlock(keys[len(keys)/2:]))
+	}
+	return true
+}
+
+// reprefix shortens b's prefix to p, which must be a prefix of it.
+func (b *block) reprefix(p string) {
+	moved := b.prefix[len(p):]
+	for i, suffix := range b.suffixes {
+		b.suffixes[i] = moved + suffix
+	}
+	b.prefix = p
+}
+
+// Delete removes key from the set, returning false if it wasn't there.
+func (s *Set) Delete(key string) bool {
+	b := s.floor(key)
+	if b == nil || !strings.HasPrefix(key, b.prefix) {
+		return false
+	}
+	i, found := b.find(key)
+	if !found {
+		return false
+	}
+	s.n--
+	if len(b.suffixes) == 1 {
+		s.t.Delete(b)
+		return true
+	}
+	b.suffixes = append(b.suffixes[:i], b.suffixes[i+1:]...)
+	if i == 0 {
+		// The block's new first string is still greater than the last
+		// string of the block before it, so it keeps its place.
+		b.first = b.key(0)
+	}
+	if len(b.suffixes) < blockSize/4 {
+		s.mergeNext(b)
+	}
+	return true
+}
+
+// mergeNext merges b with the block after it, if there is one and their
+// strings fit in one block.
+func (s *Set) mergeNext(b *block) {
+	var next *block
+	s.t.AscendGreaterOrEqual(b, func(x *block) bool {
+		if x == b {
+			return true
+		}
+		next = x
+		return false
+	})
+	if next == nil || len(b.suffixes)+len(next.suffixes) > blockSize {
+		return
+	}
+	s.t.Delete(next)
+	s.t.ReplaceOrInsert(newBlock(append(b.keys(), next.keys()...)))
+}
+
+// Ascend calls fn for every string in the set, in order, until fn returns
+// false.
+func (s *Set) Ascend(fn func(key string) bool) {
+	s.t.Ascend(func(b *block) bool {
+		for i := range b.suffixes {
+			if !fn(b.key(i)) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// AscendRange calls fn for every string in the set within the range
+// [greaterOrEqual, lessThan), in order, until fn returns false.
+func (s *Set) AscendRange(greaterOrEqual, lessThan string, fn func(key string) bool) {
+	start := &block{first: greaterOrEqual}
+	if b := s.floor(greaterOrEqual); b != nil {
+		start = b
+	}
+	s.t.AscendRange(start, &block{first: lessThan}, func(b *block) bool {
+		for i := range b.suffixes {
+			k := b.key(i)
+			if k < greaterOrEqual {
+				continue
+			}
+			if k >= lessThan || !fn(k) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// Bytes returns the number of bytes of string data the set holds, which
+// can be compared with the total length of its strings to see how much
+// sharing prefixes saves.  It takes time proportional to the size of the
+// set.
+func (s *Set) Bytes() int {
+	n := 0
+	s.t.Ascend(func(b *block) bool {
+		n += len(b.first) + len(b.prefix)
+		for _, suffix := range b.suffixes {
+			n += len(suffix)
+		}
+		return true
+	})
+	return n
+}
+
+func commonPrefix(a, b string) string {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return a[:i]
+}
+
+// clone returns a copy of s that doesn't share memory with it, so that the
+// set doesn't keep larger strings alive.
+func clone(s string) string {
+	return string([]byte(s))
+}
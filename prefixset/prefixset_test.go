@@ -0,0 +1,102 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package prefixset
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func url(i int) string {
+	return fmt.Sprintf("https://example.com/users/%d/photos/%d.jpg", i%97, i)
+}
+
+func TestSet(t *testing.T) {
+	s := New()
+	want := map[string]bool{}
+	for i := 0; i < 20000; i++ {
+		key := url(rand.Intn(5000))
+		if rand.Intn(3) == 0 {
+			if got := s.Delete(key); got != want[key] {
+				t.Fatalf("Delete(%q) = %v, want %v", key, got, want[key])
+			}
+			delete(want, key)
+		} else {
+			if got := s.Insert(key); got == want[key] {
+				t.Fatalf("Insert(%q) = %v", key, got)
+			}
+			want[key] = true
+		}
+	}
+	if s.Len() != len(want) {
+		t.Fatalf("Len() = %d, want %d", s.Len(), len(want))
+	}
+	var sorted []string
+	for k := range want {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+	var got []string
+	s.Ascend(func(k string) bool {
+		got = append(got, k)
+		return true
+	})
+	if !reflect.DeepEqual(got, sorted) {
+		t.Fatal("Ascend returned the wrong strings")
+	}
+	for i := 0; i < 5000; i++ {
+		if k := url(i); s.Has(k) != want[k] {
+			t.Fatalf("Has(%q) = %v", k, !want[k])
+		}
+	}
+	if s.Has("a") || s.Has("zzz") {
+		t.Error("Has found a string never inserted")
+	}
+
+	lo, hi := url(40), url(41)
+	got = got[:0]
+	s.AscendRange(lo, hi, func(k string) bool {
+		got = append(got, k)
+		return true
+	})
+	var wantRange []string
+	for _, k := range sorted {
+		if k >= lo && k < hi {
+			wantRange = append(wantRange, k)
+		}
+	}
+	if !reflect.DeepEqual(got, wantRange) {
+		t.Errorf("AscendRange got %v, want %v", got, wantRange)
+	}
+}
+
+func TestBytes(t *testing.T) {
+	s := New()
+	raw := 0
+	for i := 0; i < 10000; i++ {
+		k := fmt.Sprintf("/var/lib/service/data/shard-%03d/segment-%06d.log", i/1000, i)
+		s.Insert(k)
+		raw += len(k)
+	}
+	if got := s.Bytes(); got > raw/2 {
+		t.Errorf("set holds %d bytes of %d", got, raw)
+	}
+}
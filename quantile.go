@@ -0,0 +1,95 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+import (
+	"math"
+	"sort"
+)
+
+// QuantileDigestG is a compact summary of the distribution of a tree's
+// items, as returned by BTreeG.QuantileDigest, for exporting to monitoring
+// systems or comparing distributions without keeping the items.
+type QuantileDigestG[T any] struct {
+	// Count is the number of items summarized.
+	Count int
+	// Epsilon bounds the error of Quantile, as a fraction of Count.
+	Epsilon float64
+	// Samples holds items of the tree with their ranks, in order.  The
+	// first and last samples are the smallest and largest items, and
+	// consecutive samples are at most Epsilon*Count ranks apart.
+	Samples []QuantileSample[T]
+}
+
+// QuantileSample is an item and its rank: the number of items before it.
+type QuantileSample[T any] struct {
+	Rank int
+	Item T
+}
+
+// QuantileDigest returns a summary of the tree's items from which any
+// quantile can be read with a rank error of at most epsilon times the number
+// of items.  Because the tree is already sorted, the digest is built from a
+// single ascending pass, and its samples are exact: it holds about
+// 1/epsilon+2 items however large the tree is.
+//
+// QuantileDigest panics unless 0 < epsilon <= 1.
+func (t *BTreeG[T]) QuantileDigest(epsilon float64) *QuantileDigestG[T] {
+	if !(epsilon > 0 && epsilon <= 1) {
+		panic("btree: QuantileDigest epsilon must be in (0, 1]")
+	}
+	d := &QuantileDigestG[T]{Count: t.length, Epsilon: epsilon}
+	if t.length == 0 {
+		return d
+	}
+	step := int(math.Floor(epsilon * float64(t.length)))
+	if step < 1 {
+		step = 1
+	}
+	d.Samples = make([]QuantileSample[T], 0, t.length/step+2)
+	rank := 0
+	t.ascend(func(item T) bool {
+		if rank%step == 0 || rank == t.length-1 {
+			d.Samples = append(d.Samples, QuantileSample[T]{Rank: rank, Item: item})
+		}
+		rank++
+		return true
+	})
+	return d
+}
+
+// Quantile returns an item whose rank is within Epsilon*Count of q*Count,
+// for q between 0 and 1: Quantile(0.5) is approximately the median.  It
+// returns (zeroValue, false) if the digest is empty.
+func (d *QuantileDigestG[T]) Quantile(q float64) (_ T, _ bool) {
+	if len(d.Samples) == 0 {
+		return
+	}
+	if q < 0 {
+		q = 0
+	} else if q > 1 {
+		q = 1
+	}
+	want := int(q * float64(d.Count-1))
+	// The first sample at or after want is within a step of it.
+	i := sort.Search(len(d.Samples), func(i int) bool { return d.Samples[i].Rank >= want })
+	if i == len(d.Samples) {
+		i--
+	}
+	return d.Samples[i].Item, true
+}
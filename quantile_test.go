@@ -0,0 +1,49 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestQuantileDigest(t *testing.T) {
+	tr := NewOrderedG[int](*btreeDegree)
+	if _, ok := tr.QuantileDigest(0.1).Quantile(0.5); ok {
+		t.Error("empty digest returned a quantile")
+	}
+	// Items 0, 10, 20, ..., so an item's rank is item/10.
+	for _, v := range rand.Perm(10000) {
+		tr.ReplaceOrInsert(v * 10)
+	}
+	d := tr.QuantileDigest(0.01)
+	if len(d.Samples) > 102 || d.Count != 10000 {
+		t.Errorf("digest has %d samples of %d items", len(d.Samples), d.Count)
+	}
+	if d.Samples[0].Item != 0 || d.Samples[len(d.Samples)-1].Item != 99990 {
+		t.Errorf("digest doesn't span the items: %v ... %v", d.Samples[0], d.Samples[len(d.Samples)-1])
+	}
+	for _, q := range []float64{0, 0.001, 0.25, 0.5, 0.9, 0.999, 1} {
+		got, _ := d.Quantile(q)
+		rank, want := got/10, q*9999
+		if diff := float64(rank) - want; diff < -100 || diff > 100 {
+			t.Errorf("Quantile(%v) = %d, rank %d, want rank within 100 of %v", q, got, rank, want)
+		}
+	}
+	expectPanic(t, "epsilon", func() { tr.QuantileDigest(0) })
+}
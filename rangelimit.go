@@ -0,0 +1,62 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+// AscendRangeLimit is like AscendRange, but calls the iterator for at most
+// limit items.  It returns the first item within the range that the iterator
+// wasn't called for, if any, so that a caller processing a large range a
+// bounded piece at a time can continue where it left off:
+//
+//	next, more := t.AscendRangeLimit(lo, hi, 100, process)
+//	...
+//	next, more = t.AscendRangeLimit(next, hi, 100, process)
+func (t *BTreeG[T]) AscendRangeLimit(greaterOrEqual, lessThan T, limit int, iterator ItemIteratorG[T]) (next T, more bool) {
+	n, stopped := 0, false
+	t.AscendRange(greaterOrEqual, lessThan, func(item T) bool {
+		if stopped || n >= limit {
+			next, more = item, true
+			return false
+		}
+		n++
+		stopped = !iterator(item)
+		return true
+	})
+	return next, more
+}
+
+// DeleteRangeLimit deletes at most limit items within the range
+// [greaterOrEqual, lessThan), in ascending order, returning the number
+// deleted and whether any items remain in the range.  Calling it repeatedly
+// until more is false deletes the whole range while bounding the work done
+// by each call.
+func (t *BTreeG[T]) DeleteRangeLimit(greaterOrEqual, lessThan T, limit int) (deleted int, more bool) {
+	if limit < 0 {
+		limit = 0
+	}
+	items := make([]T, 0, limit)
+	_, more = t.AscendRangeLimit(greaterOrEqual, lessThan, limit, func(item T) bool {
+		items = append(items, item)
+		return true
+	})
+	for _, item := range items {
+		if _, ok := t.Delete(item); ok {
+			deleted++
+		}
+	}
+	return deleted, more
+}
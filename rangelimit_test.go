@@ -0,0 +1,78 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAscendRangeLimit(t *testing.T) {
+	tr := NewOrderedG[int](*btreeDegree)
+	for i := 0; i < 100; i++ {
+		tr.ReplaceOrInsert(i)
+	}
+	var got []int
+	lo, calls := 10, 0
+	for more := true; more; calls++ {
+		lo, more = tr.AscendRangeLimit(lo, 95, 7, func(item int) bool {
+			got = append(got, item)
+			return true
+		})
+	}
+	if want := intRange(95, false)[10:]; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if calls != 13 {
+		t.Errorf("took %d calls, want 13", calls)
+	}
+
+	// Stopping early reports the item after the last one visited.
+	next, more := tr.AscendRangeLimit(0, 100, 50, func(item int) bool {
+		return item < 3
+	})
+	if next != 4 || !more {
+		t.Errorf("stopped early: got %d, %v; want 4, true", next, more)
+	}
+	if _, more := tr.AscendRangeLimit(90, 100, 10, func(int) bool { return true }); more {
+		t.Error("exhausted range reported more")
+	}
+}
+
+func TestDeleteRangeLimit(t *testing.T) {
+	tr := NewOrderedG[int](*btreeDegree)
+	for i := 0; i < 100; i++ {
+		tr.ReplaceOrInsert(i)
+	}
+	total := 0
+	for more := true; more; {
+		var n int
+		n, more = tr.DeleteRangeLimit(20, 80, 8)
+		if n > 8 {
+			t.Fatalf("deleted %d, limit 8", n)
+		}
+		total += n
+	}
+	if total != 60 || tr.Len() != 40 {
+		t.Errorf("deleted %d, %d left; want 60, 40", total, tr.Len())
+	}
+	want := append(intRange(20, false), intRange(100, false)[80:]...)
+	if got := tr.all(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,157 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+// Package rangelock provides shared and exclusive locks on ranges of keys,
+// as used by storage engines to lock key ranges for transactions.
+//
+// Held ranges are stored in a btree.AugmentedG ordered by their start, whose
+// nodes record the furthest end of any range in their subtree.  Whether a
+// new range conflicts with any held one is then answered in O(log n) time:
+// it does if some held range starting before the new range's end finishes
+// after its start.
+package rangelock
+
+import (
+	"sync"
+
+	"github.com/google/btree"
+)
+
+// Mode is the mode of a lock.
+type Mode int
+
+const (
+	// Shared locks may overlap other shared locks, but not exclusive ones.
+	Shared Mode = iota
+	// Exclusive locks may not overlap any other lock.
+	Exclusive
+)
+
+// Lock is a held lock on the range [Lo, Hi).
+type Lock[K btree.Ordered] struct {
+	Lo, Hi K
+	Mode   Mode
+	id     uint64 // distinguishes locks on the same range
+}
+
+// reach records the furthest end of a run of locks, of any mode and of
+// exclusive locks only.
+type reach[K btree.Ordered] struct {
+	any, excl     K
+	hasAny, hasEx bool
+}
+
+func furthest[K btree.Ordered](a K, aok bool, b K, bok bool) (K, bool) {
+	if !aok || (bok && a < b) {
+		return b, bok
+	}
+	return a, aok
+}
+
+func augment[K btree.Ordered]() btree.Augment[*Lock[K], reach[K]] {
+	return btree.Augment[*Lock[K], reach[K]]{
+		Of: func(l *Lock[K]) reach[K] {
+			r := reach[K]{any: l.Hi, hasAny: true}
+			if l.Mode == Exclusive {
+				r.excl, r.hasEx = l.Hi, true
+			}
+			return r
+		},
+		Combine: func(a, b reach[K]) reach[K] {
+			var r reach[K]
+			r.any, r.hasAny = furthest(a.any, a.hasAny, b.any, b.hasAny)
+			r.excl, r.hasEx = furthest(a.excl, a.hasEx, b.excl, b.hasEx)
+			return r
+		},
+	}
+}
+
+func less[K btree.Ordered](a, b *Lock[K]) bool {
+	if a.Lo != b.Lo {
+		return a.Lo < b.Lo
+	}
+	return a.id < b.id
+}
+
+// Manager tracks held range locks.  It is safe for concurrent use.
+type Manager[K btree.Ordered] struct {
+	mu     sync.Mutex
+	t      *btree.AugmentedG[*Lock[K], reach[K]]
+	nextID uint64
+}
+
+// New returns a Manager with no locks held.
+func New[K btree.Ordered]() *Manager[K] {
+	return &Manager[K]{t: btree.NewAugmentedG(btree.DefaultDegree, less[K], augment[K]())}
+}
+
+// conflicts reports whether a lock on [lo, hi) in mode conflicts with a held
+// lock.  m.mu must be held.
+func (m *Manager[K]) conflicts(lo, hi K, mode Mode) bool {
+	// Every held lock starting before hi; id 0 sorts before any held lock.
+	r, ok := m.t.SummaryLessThan(&Lock[K]{Lo: hi})
+	if !ok {
+		return false
+	}
+	if mode == Exclusive {
+		return lo < r.any
+	}
+	return r.hasEx && lo < r.excl
+}
+
+// Conflicts reports whether a lock on [lo, hi) in the given mode would
+// conflict with a held lock.  Empty ranges never conflict.
+func (m *Manager[K]) Conflicts(lo, hi K, mode Mode) bool {
+	if !(lo < hi) {
+		return false
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.conflicts(lo, hi, mode)
+}
+
+// TryLock acquires a lock on [lo, hi) in the given mode, returning it, or nil
+// if it conflicts with a held lock.  It panics if the range is empty.
+func (m *Manager[K]) TryLock(lo, hi K, mode Mode) *Lock[K] {
+	if !(lo < hi) {
+		panic("rangelock: empty range")
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.conflicts(lo, hi, mode) {
+		return nil
+	}
+	m.nextID++
+	l := &Lock[K]{Lo: lo, Hi: hi, Mode: mode, id: m.nextID}
+	m.t.ReplaceOrInsert(l)
+	return l
+}
+
+// Unlock releases l, returning false if it was not held.
+func (m *Manager[K]) Unlock(l *Lock[K]) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, ok := m.t.Delete(l)
+	return ok
+}
+
+// Len returns the number of locks held.
+func (m *Manager[K]) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.t.Len()
+}
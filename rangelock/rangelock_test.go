@@ -0,0 +1,82 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package rangelock
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestTryLock(t *testing.T) {
+	m := New[string]()
+	ab := m.TryLock("a", "c", Exclusive)
+	if ab == nil {
+		t.Fatal("first lock failed")
+	}
+	if m.TryLock("b", "d", Shared) != nil {
+		t.Error("shared lock overlapping an exclusive one succeeded")
+	}
+	if m.TryLock("c", "e", Exclusive) == nil {
+		t.Error("adjacent lock failed")
+	}
+	if m.TryLock("x", "z", Shared) == nil || m.TryLock("w", "y", Shared) == nil {
+		t.Error("overlapping shared locks failed")
+	}
+	if !m.Conflicts("y", "yy", Exclusive) || m.Conflicts("y", "yy", Shared) {
+		t.Error("wrong conflicts with shared locks")
+	}
+	if !m.Unlock(ab) || m.Unlock(ab) {
+		t.Error("Unlock didn't release exactly once")
+	}
+	if m.TryLock("b", "c", Exclusive) == nil {
+		t.Error("lock after Unlock failed")
+	}
+	if m.Len() != 4 {
+		t.Errorf("Len() = %d, want 4", m.Len())
+	}
+}
+
+func TestRandom(t *testing.T) {
+	m := New[int]()
+	var held []*Lock[int]
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 5000; i++ {
+		if len(held) > 0 && r.Intn(3) == 0 {
+			j := r.Intn(len(held))
+			m.Unlock(held[j])
+			held = append(held[:j], held[j+1:]...)
+			continue
+		}
+		lo := r.Intn(1000)
+		hi := lo + 1 + r.Intn(20)
+		mode := Mode(r.Intn(2))
+		want := false
+		for _, l := range held {
+			if l.Lo < hi && lo < l.Hi && (mode == Exclusive || l.Mode == Exclusive) {
+				want = true
+			}
+		}
+		l := m.TryLock(lo, hi, mode)
+		if (l == nil) != want {
+			t.Fatalf("op %d: TryLock(%d, %d, %v) = %v, want conflict %v", i, lo, hi, mode, l, want)
+		}
+		if l != nil {
+			held = append(held, l)
+		}
+	}
+}
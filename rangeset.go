@@ -0,0 +1,162 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+// RangeG is the half-open range [Start, End).
+type RangeG[T any] struct {
+	Start, End T
+}
+
+// RangeSetG is a set of values stored as disjoint half-open ranges, such as
+// the downloaded byte ranges of a file or the IDs in use.  Adding a range
+// merges it with any ranges it overlaps or touches, and removing one splits
+// the ranges it cuts, so the set always holds the fewest ranges possible.
+//
+// Write operations are not safe for concurrent mutation by multiple
+// goroutines, but Read operations are.
+type RangeSetG[T any] struct {
+	t    *BTreeG[RangeG[T]]
+	less LessFunc[T]
+}
+
+// NewRangeSetG creates a new, empty range set with the given degree, with
+// values ordered by less.
+func NewRangeSetG[T any](degree int, less LessFunc[T]) *RangeSetG[T] {
+	return &RangeSetG[T]{
+		t:    NewG(degree, func(a, b RangeG[T]) bool { return less(a.Start, b.Start) }),
+		less: less,
+	}
+}
+
+// Len returns the number of disjoint ranges in the set.
+func (s *RangeSetG[T]) Len() int {
+	return s.t.Len()
+}
+
+// containing returns the range that starts at or before x, and whether
+// there is one.
+func (s *RangeSetG[T]) containing(x T) (r RangeG[T], ok bool) {
+	s.t.DescendLessOrEqual(RangeG[T]{Start: x}, func(item RangeG[T]) bool {
+		r, ok = item, true
+		return false
+	})
+	return
+}
+
+// Contains returns true if x is in the set.
+func (s *RangeSetG[T]) Contains(x T) bool {
+	r, ok := s.containing(x)
+	return ok && s.less(x, r.End)
+}
+
+// ContainsRange returns true if every value in [start, end) is in the set.
+// Empty ranges are always contained.
+func (s *RangeSetG[T]) ContainsRange(start, end T) bool {
+	if !s.less(start, end) {
+		return true
+	}
+	r, ok := s.containing(start)
+	return ok && !s.less(r.End, end)
+}
+
+// Add adds the values in [start, end) to the set.
+func (s *RangeSetG[T]) Add(start, end T) {
+	if !s.less(start, end) {
+		return
+	}
+	if r, ok := s.containing(start); ok && !s.less(r.End, start) {
+		// r overlaps or touches the new range; absorb it.
+		start = r.Start
+		if s.less(end, r.End) {
+			end = r.End
+		}
+		s.t.Delete(r)
+	}
+	var absorbed []RangeG[T]
+	s.t.AscendGreaterOrEqual(RangeG[T]{Start: start}, func(r RangeG[T]) bool {
+		if s.less(end, r.Start) {
+			return false
+		}
+		absorbed = append(absorbed, r)
+		return true
+	})
+	for _, r := range absorbed {
+		if s.less(end, r.End) {
+			end = r.End
+		}
+		s.t.Delete(r)
+	}
+	s.t.ReplaceOrInsert(RangeG[T]{start, end})
+}
+
+// Remove removes the values in [start, end) from the set.
+func (s *RangeSetG[T]) Remove(start, end T) {
+	if !s.less(start, end) {
+		return
+	}
+	if r, ok := s.containing(start); ok && s.less(r.Start, start) && s.less(start, r.End) {
+		// r straddles start; keep the part before it, and after end.
+		s.t.ReplaceOrInsert(RangeG[T]{r.Start, start})
+		if s.less(end, r.End) {
+			s.t.ReplaceOrInsert(RangeG[T]{end, r.End})
+			return
+		}
+	}
+	var cut []RangeG[T]
+	s.t.AscendRange(RangeG[T]{Start: start}, RangeG[T]{Start: end}, func(r RangeG[T]) bool {
+		cut = append(cut, r)
+		return true
+	})
+	for _, r := range cut {
+		s.t.Delete(r)
+		if s.less(end, r.End) {
+			s.t.ReplaceOrInsert(RangeG[T]{end, r.End})
+		}
+	}
+}
+
+// Ascend calls iter for each range in the set, in order, until iter returns
+// false.
+func (s *RangeSetG[T]) Ascend(iter ItemIteratorG[RangeG[T]]) {
+	s.t.Ascend(iter)
+}
+
+// Gaps calls iter for each maximal range within [start, end) that contains
+// no values in the set, in order, until iter returns false.
+func (s *RangeSetG[T]) Gaps(start, end T, iter ItemIteratorG[RangeG[T]]) {
+	if !s.less(start, end) {
+		return
+	}
+	pos := start
+	if r, ok := s.containing(start); ok && s.less(start, r.End) {
+		pos = r.End
+	}
+	more := true
+	s.t.AscendRange(RangeG[T]{Start: pos}, RangeG[T]{Start: end}, func(r RangeG[T]) bool {
+		if s.less(pos, r.Start) {
+			if more = iter(RangeG[T]{pos, r.Start}); !more {
+				return false
+			}
+		}
+		pos = r.End
+		return true
+	})
+	if more && s.less(pos, end) {
+		iter(RangeG[T]{pos, end})
+	}
+}
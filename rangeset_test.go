@@ -0,0 +1,102 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func rangesOf(s *RangeSetG[int]) []RangeG[int] {
+	var out []RangeG[int]
+	s.Ascend(func(r RangeG[int]) bool {
+		out = append(out, r)
+		return true
+	})
+	return out
+}
+
+func TestRangeSet(t *testing.T) {
+	s := NewRangeSetG(2, Less[int]())
+	s.Add(10, 20)
+	s.Add(30, 40)
+	s.Add(20, 25) // touches [10, 20)
+	s.Add(35, 50) // overlaps [30, 40)
+	if got, want := rangesOf(s), []RangeG[int]{{10, 25}, {30, 50}}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("after Add got %v, want %v", got, want)
+	}
+	s.Remove(12, 14)
+	s.Remove(24, 31)
+	if got, want := rangesOf(s), []RangeG[int]{{10, 12}, {14, 24}, {31, 50}}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("after Remove got %v, want %v", got, want)
+	}
+	if !s.Contains(10) || s.Contains(12) || !s.Contains(49) || s.Contains(50) {
+		t.Errorf("Contains wrong")
+	}
+	if !s.ContainsRange(14, 24) || s.ContainsRange(14, 25) {
+		t.Errorf("ContainsRange wrong")
+	}
+	var gaps []RangeG[int]
+	s.Gaps(0, 40, func(r RangeG[int]) bool {
+		gaps = append(gaps, r)
+		return true
+	})
+	if want := []RangeG[int]{{0, 10}, {12, 14}, {24, 31}}; !reflect.DeepEqual(gaps, want) {
+		t.Errorf("Gaps got %v, want %v", gaps, want)
+	}
+	s.Add(0, 100)
+	if got, want := rangesOf(s), []RangeG[int]{{0, 100}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("after covering Add got %v, want %v", got, want)
+	}
+}
+
+func TestRangeSetRandom(t *testing.T) {
+	s := NewRangeSetG(3, Less[int]())
+	var set [200]bool
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 3000; i++ {
+		lo := r.Intn(200)
+		hi := lo + r.Intn(20)
+		if hi > 200 {
+			hi = 200
+		}
+		add := r.Intn(2) == 0
+		if add {
+			s.Add(lo, hi)
+		} else {
+			s.Remove(lo, hi)
+		}
+		for x := lo; x < hi; x++ {
+			set[x] = add
+		}
+		// The set must hold exactly the maximal runs of true values.
+		var want []RangeG[int]
+		for x := 0; x < 200; x++ {
+			if set[x] && (x == 0 || !set[x-1]) {
+				want = append(want, RangeG[int]{x, x})
+			}
+			if set[x] {
+				want[len(want)-1].End = x + 1
+			}
+		}
+		if got := rangesOf(s); !reflect.DeepEqual(got, want) {
+			t.Fatalf("op %d: got %v, want %v", i, got, want)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// RCUTreeG is a tree for read-mostly workloads that many goroutines read
+// while one at a time writes, in the read-copy-update style: writers change
+// a private copy-on-write clone of the tree and then publish it, and
+// readers always see the last published version, without locking and
+// without ever waiting for a writer.
+//
+// Published versions are never written to, so a reader sees a consistent
+// tree for as long as it holds one, however many updates are published in
+// the meantime.  Each update copies only the nodes it changes.
+type RCUTreeG[T any] struct {
+	mu        sync.Mutex // held by writers
+	private   *BTreeG[T] // the latest version; only touched with mu held
+	published atomic.Value
+}
+
+// NewRCUTreeG returns an RCUTreeG whose first version holds t's items.  It
+// takes ownership of t, which must not be used afterwards.
+func NewRCUTreeG[T any](t *BTreeG[T]) *RCUTreeG[T] {
+	r := &RCUTreeG[T]{private: t}
+	r.published.Store(t.Clone())
+	return r
+}
+
+// Load returns the last published version of the tree.  It must only be
+// read, never written to; it's safe to read from any number of goroutines,
+// and it never changes.
+func (r *RCUTreeG[T]) Load() *BTreeG[T] {
+	return r.published.Load().(*BTreeG[T])
+}
+
+// Read calls f with the last published version of the tree, which f must
+// only read.  It never blocks.
+func (r *RCUTreeG[T]) Read(f func(t *BTreeG[T])) {
+	f(r.Load())
+}
+
+// Update calls f with a private copy of the latest version of the tree, for
+// f to modify, and then publishes the result.  Updates are serialized: an
+// Update waits for earlier ones to finish.  If f panics, nothing is
+// published and the tree is unchanged.
+//
+// Readers don't see any of f's changes until it returns, and then see all
+// of them at once.
+func (r *RCUTreeG[T]) Update(f func(t *BTreeG[T])) {
+	r.UpdateCompact(0, f)
+}
+
+// UpdateCompact is like Update, but before publishing the result it
+// rebuilds it with fully packed nodes, as Rebuild does, if its fill factor
+// has fallen below minFill.  This keeps a long series of updates that mix
+// inserts and deletes from leaving every published version sparse.
+//
+// Checking the fill factor visits every node, and a rebuild copies the whole
+// tree, so UpdateCompact suits occasional or batched updates better than
+// frequent small ones; those can call Update, and UpdateCompact now and
+// then.  A minFill of 0.5 rebuilds trees that are less than half full,
+// which a tree of random inserts never is.
+func (r *RCUTreeG[T]) UpdateCompact(minFill float64, f func(t *BTreeG[T])) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	next := r.private.Clone()
+	f(next)
+	if minFill > 0 && next.Len() > 0 && next.FillFactor() < minFill {
+		next.Rebuild()
+	}
+	r.private = next
+	r.published.Store(next.Clone())
+}
@@ -0,0 +1,116 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestRCUTree(t *testing.T) {
+	r := NewRCUTreeG(NewOrderedG[int](*btreeDegree))
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				// Every update adds a batch of 10 items, so a consistent
+				// version holds a multiple of 10.
+				r.Read(func(tr *BTreeG[int]) {
+					n := 0
+					tr.Ascend(func(int) bool {
+						n++
+						return true
+					})
+					if n%10 != 0 || n != tr.Len() {
+						t.Errorf("read a version with %d items and Len %d", n, tr.Len())
+					}
+				})
+			}
+		}()
+	}
+	for i := 0; i < 100; i++ {
+		r.Update(func(tr *BTreeG[int]) {
+			for j := 0; j < 10; j++ {
+				tr.ReplaceOrInsert(i*10 + j)
+			}
+		})
+	}
+	close(stop)
+	wg.Wait()
+	if got := r.Load().Len(); got != 1000 {
+		t.Errorf("Len = %d, want 1000", got)
+	}
+
+	before := r.Load()
+	expectPanic(t, "boom", func() {
+		r.Update(func(tr *BTreeG[int]) {
+			tr.DeleteMin()
+			panic("boom")
+		})
+	})
+	if r.Load() != before || before.Len() != 1000 || !before.Has(0) {
+		t.Error("a panicking Update published changes")
+	}
+	r.Update(func(tr *BTreeG[int]) { tr.DeleteMin() })
+	if before.Len() != 1000 || r.Load().Len() != 999 || r.Load().Has(0) {
+		t.Error("an Update changed an earlier version")
+	}
+}
+
+func TestRCUUpdateCompact(t *testing.T) {
+	r := NewRCUTreeG(NewOrderedG[int](4))
+	r.Update(func(tr *BTreeG[int]) {
+		for i := 0; i < 10000; i++ {
+			tr.ReplaceOrInsert(i)
+		}
+	})
+	// Deleting most items leaves the tree sparse, unless it's compacted.
+	thin := func(tr *BTreeG[int]) {
+		for i := 0; i < 10000; i++ {
+			if i%10 != 0 {
+				tr.Delete(i)
+			}
+		}
+	}
+	old := r.Load()
+	r.Update(thin)
+	sparse := r.Load().FillFactor()
+	r.Update(func(tr *BTreeG[int]) {
+		for i := 0; i < 10000; i++ {
+			tr.ReplaceOrInsert(i)
+		}
+	})
+	r.UpdateCompact(0.9, thin)
+	if got := r.Load().FillFactor(); got < 0.9 || got <= sparse {
+		t.Errorf("fill factor after UpdateCompact = %v (%v without)", got, sparse)
+	}
+	if r.Load().Len() != 1000 || old.Len() != 10000 {
+		t.Error("UpdateCompact lost items or changed an earlier version")
+	}
+	if err := r.Load().Verify(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+import "sync"
+
+// ReduceG computes an aggregate over every item in t using up to
+// parallelism goroutines.  It calls mapFn with the items of each node of
+// the tree, which are in order but must not be modified or retained, and
+// combines the results with combine.  For example, to sum a tree of ints:
+//
+//	sum := btree.ReduceG(tr, runtime.GOMAXPROCS(0),
+//		func(items []int) (s int) {
+//			for _, i := range items {
+//				s += i
+//			}
+//			return s
+//		},
+//		func(a, b int) int { return a + b })
+//
+// Nodes are mapped and combined in no particular order, so combine must be
+// associative and commutative.  ReduceG returns the zero R for an empty
+// tree.  t must not be modified until ReduceG returns; clone it first to
+// reduce a tree that other goroutines are writing to.
+func ReduceG[T, R any](t *BTreeG[T], parallelism int, mapFn func(items []T) R, combine func(R, R) R) (result R) {
+	var nodes []*node[T]
+	var walk func(n *node[T])
+	walk = func(n *node[T]) {
+		if len(n.items) > 0 {
+			nodes = append(nodes, n)
+		}
+		for _, c := range n.children {
+			walk(c)
+		}
+	}
+	if t.root != nil {
+		walk(t.root)
+	}
+	if len(nodes) == 0 {
+		return result
+	}
+	if parallelism < 1 {
+		parallelism = 1
+	}
+	if parallelism > len(nodes) {
+		parallelism = len(nodes)
+	}
+	results := make([]R, parallelism)
+	var wg sync.WaitGroup
+	for w := 0; w < parallelism; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			// Worker w maps every parallelism'th node, which spreads
+			// leaves and internal nodes evenly across the workers.
+			for i := w; i < len(nodes); i += parallelism {
+				items := nodes[i].items
+				r := mapFn(items[:len(items):len(items)])
+				if i == w {
+					results[w] = r
+				} else {
+					results[w] = combine(results[w], r)
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	result = results[0]
+	for _, r := range results[1:] {
+		result = combine(result, r)
+	}
+	return result
+}
@@ -0,0 +1,63 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestReduce(t *testing.T) {
+	sum := func(items []int) (s int) {
+		for _, i := range items {
+			s += i
+		}
+		return s
+	}
+	add := func(a, b int) int { return a + b }
+	tr := NewOrderedG[int](*btreeDegree)
+	if got := ReduceG(tr, 4, sum, add); got != 0 {
+		t.Errorf("empty tree: got %d, want 0", got)
+	}
+	for _, v := range rand.Perm(10000) {
+		tr.ReplaceOrInsert(v)
+	}
+	want := 10000 * 9999 / 2
+	for _, p := range []int{0, 1, 3, 8, 1 << 20} {
+		if got := ReduceG(tr, p, sum, add); got != want {
+			t.Errorf("parallelism %d: got %d, want %d", p, got, want)
+		}
+	}
+	// A histogram, reduced into a different type.
+	hist := ReduceG(tr, 4, func(items []int) (h [10]int) {
+		for _, i := range items {
+			h[i%10]++
+		}
+		return h
+	}, func(a, b [10]int) [10]int {
+		for i := range a {
+			a[i] += b[i]
+		}
+		return a
+	})
+	for i, n := range hist {
+		if n != 1000 {
+			t.Errorf("hist[%d] = %d, want 1000", i, n)
+		}
+	}
+}
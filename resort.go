@@ -0,0 +1,84 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+import "sort"
+
+// Resort returns a new tree containing t's items ordered by newLess, such as
+// when a cache ordered by key needs to be walked in order of expiry.  t is
+// left unchanged.  Items that are equal according to newLess collapse into
+// one, keeping the last of them in t's order, just as if they had been
+// inserted into the new tree in that order with ReplaceOrInsert.
+//
+// The new tree shares t's degree, freelist, limit, and other configuration,
+// except that it searches nodes with newLess (so it doesn't use
+// interpolation search), its views are rebuilt from its items, and it drops
+// the hash functions given by WithBloomFilter and WithKeyChecks and the
+// function given by WithItemEqual, which are tied to t's ordering.  Resort
+// sorts the items and then builds the tree bottom-up, so it takes
+// O(n log n) time.
+func (t *BTreeG[T]) Resort(newLess LessFunc[T]) *BTreeG[T] {
+	start := t.traceStart()
+	cow := *t.cow
+	cow.search = nil
+	// Items equal by newLess needn't hash equally by t's hashes, so the
+	// new tree can't use them.
+	cow.bloomHash, cow.keyHash, cow.equal = nil, nil, nil
+	cow.setLess(newLess)
+	items := t.all()
+	sort.SliceStable(items, func(i, j int) bool { return cow.less(items[i], items[j]) })
+	// Keep the last of each run of equal items.
+	kept := items[:0]
+	for i, item := range items {
+		if i+1 < len(items) && !cow.less(item, items[i+1]) {
+			continue
+		}
+		kept = append(kept, item)
+	}
+	out := t.built(&cow, kept)
+	t.traceEnd("Resort", start, t.length)
+	return out
+}
+
+// built returns a new tree, with t's degree, limit, and views but the given
+// context, containing items, which must be sorted and free of duplicates
+// according to cow.less.  The views are cloned from t's, then reset and
+// filled with items.
+func (t *BTreeG[T]) built(cow *copyOnWriteContext[T], items []T) *BTreeG[T] {
+	out := &BTreeG[T]{
+		degree: t.degree,
+		length: len(items),
+		cow:    cow,
+		limit:  t.limit,
+		views:  cloneViews(t.views),
+	}
+	out.root = cow.buildSorted(items, out.maxItems())
+	for _, v := range out.views {
+		v.Reset()
+	}
+	for _, item := range items {
+		if out.limit != nil {
+			out.usage += out.limit.weigh(item)
+		}
+		for _, v := range out.views {
+			v.Add(item)
+		}
+	}
+	out.refresh()
+	return out
+}
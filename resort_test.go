@@ -0,0 +1,83 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+type cacheEntry struct {
+	key, expiry int
+}
+
+func TestResort(t *testing.T) {
+	byKey := NewG(*btreeDegree, func(a, b cacheEntry) bool { return a.key < b.key })
+	for _, k := range rand.Perm(300) {
+		byKey.ReplaceOrInsert(cacheEntry{k, (k * 7) % 300})
+	}
+	byExpiry := byKey.Resort(func(a, b cacheEntry) bool { return a.expiry < b.expiry })
+	if err := byExpiry.Verify(); err != nil {
+		t.Fatal(err)
+	}
+	if byExpiry.Len() != 300 || byKey.Len() != 300 {
+		t.Fatalf("lengths %d, %d", byExpiry.Len(), byKey.Len())
+	}
+	i := 0
+	byExpiry.Ascend(func(e cacheEntry) bool {
+		if e.expiry != i {
+			t.Fatalf("item %d has expiry %d", i, e.expiry)
+		}
+		i++
+		return true
+	})
+	if _, ok := byExpiry.Get(cacheEntry{expiry: 42}); !ok {
+		t.Error("Get by the new ordering failed")
+	}
+
+	// Items equal under the new ordering collapse, keeping the last.
+	byBucket := byKey.Resort(func(a, b cacheEntry) bool { return a.key/100 < b.key/100 })
+	var keys []int
+	byBucket.Ascend(func(e cacheEntry) bool {
+		keys = append(keys, e.key)
+		return true
+	})
+	if want := []int{99, 199, 299}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("got keys %v, want %v", keys, want)
+	}
+}
+
+func TestResortDropsBloomFilter(t *testing.T) {
+	byKey := NewWithOptions(
+		WithDegree[cacheEntry](*btreeDegree),
+		WithLess(func(a, b cacheEntry) bool { return a.key < b.key }),
+		WithBloomFilter(func(e cacheEntry) uint64 { return uint64(e.key) * 0x9e3779b97f4a7c15 }))
+	for _, k := range rand.Perm(300) {
+		byKey.ReplaceOrInsert(cacheEntry{k, (k * 7) % 300})
+	}
+	byExpiry := byKey.Resort(func(a, b cacheEntry) bool { return a.expiry < b.expiry })
+	// An insert would build a filter, if the new tree kept the key hash, and
+	// lookups by expiry alone would then miss.
+	byExpiry.ReplaceOrInsert(cacheEntry{1000, 1000})
+	for e := 0; e < 300; e++ {
+		if !byExpiry.Has(cacheEntry{key: -1, expiry: e}) {
+			t.Fatalf("Has(expiry %d) = false after Resort", e)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+// Package schedule holds events to be fired at given times, as a simpler,
+// exact alternative to a timer wheel.
+//
+// A Schedule is a btree.PQ ordered by fire time.  Adding, cancelling, and
+// rescheduling an event each take O(log n) time, and events due at the same
+// time fire in the order they were added.
+package schedule
+
+import (
+	"time"
+
+	"github.com/google/btree"
+)
+
+type event[V any] struct {
+	at    time.Time
+	value V
+}
+
+func less[V any](a, b event[V]) bool {
+	return a.at.Before(b.at)
+}
+
+// Handle refers to an event in a Schedule.
+type Handle[V any] struct {
+	h *btree.Handle[event[V]]
+}
+
+// When returns the time the event fires.
+func (h Handle[V]) When() time.Time {
+	return h.h.Item().at
+}
+
+// Value returns the event's value.
+func (h Handle[V]) Value() V {
+	return h.h.Item().value
+}
+
+// Pending returns true if the event hasn't yet fired or been cancelled.
+func (h Handle[V]) Pending() bool {
+	return h.h.Queued()
+}
+
+// Schedule holds events with values of type V.  It is not safe for
+// concurrent use.
+type Schedule[V any] struct {
+	q *btree.PQ[event[V]]
+}
+
+// New returns an empty schedule.
+func New[V any]() *Schedule[V] {
+	return &Schedule[V]{q: btree.NewPQ(less[V])}
+}
+
+// Len returns the number of pending events.
+func (s *Schedule[V]) Len() int {
+	return s.q.Len()
+}
+
+// Add schedules an event with the given value to fire at time at.
+func (s *Schedule[V]) Add(at time.Time, value V) Handle[V] {
+	return Handle[V]{s.q.Push(event[V]{at, value})}
+}
+
+// Cancel removes h's event, returning false if it has already fired or been
+// cancelled.
+func (s *Schedule[V]) Cancel(h Handle[V]) bool {
+	return s.q.Remove(h.h)
+}
+
+// Reschedule moves h's event to fire at time at instead, returning false if
+// it has already fired or been cancelled.
+func (s *Schedule[V]) Reschedule(h Handle[V], at time.Time) bool {
+	return s.q.UpdatePriority(h.h, event[V]{at, h.Value()})
+}
+
+// NextFireTime returns the time the earliest pending event fires.
+func (s *Schedule[V]) NextFireTime() (time.Time, bool) {
+	e, ok := s.q.PeekMin()
+	return e.at, ok
+}
+
+// PopDue removes and returns the values of every event due at or before now,
+// in the order they fire.
+func (s *Schedule[V]) PopDue(now time.Time) []V {
+	var out []V
+	for {
+		e, ok := s.q.PeekMin()
+		if !ok || e.at.After(now) {
+			return out
+		}
+		s.q.PopMin()
+		out = append(out, e.value)
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package schedule
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSchedule(t *testing.T) {
+	t0 := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	sec := func(n int) time.Time { return t0.Add(time.Duration(n) * time.Second) }
+	s := New[string]()
+	if _, ok := s.NextFireTime(); ok {
+		t.Fatal("empty schedule has a next fire time")
+	}
+	s.Add(sec(5), "e")
+	b := s.Add(sec(2), "b")
+	s.Add(sec(1), "a")
+	s.Add(sec(2), "c")
+	d := s.Add(sec(9), "d")
+	if next, _ := s.NextFireTime(); !next.Equal(sec(1)) {
+		t.Errorf("NextFireTime() = %v", next)
+	}
+	if !s.Cancel(b) || s.Cancel(b) || b.Pending() {
+		t.Error("Cancel didn't cancel exactly once")
+	}
+	s.Reschedule(d, sec(3))
+	if got, want := s.PopDue(sec(3)), []string{"a", "c", "d"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("PopDue(3s) = %v, want %v", got, want)
+	}
+	if got := s.PopDue(sec(4)); got != nil {
+		t.Errorf("PopDue(4s) = %v, want nothing", got)
+	}
+	if s.Len() != 1 || d.Pending() || d.Value() != "d" || !d.When().Equal(sec(3)) {
+		t.Errorf("Len() = %d, d = %v %v %v", s.Len(), d.Pending(), d.Value(), d.When())
+	}
+}
@@ -0,0 +1,141 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+// SealedTreeG is a read-only tree, created by Seal, for the common lifecycle
+// of building a tree privately and then sharing it.  Unlike a FrozenTreeG,
+// it keeps the nodes of the tree it was sealed from, so sealing takes O(1)
+// time, and Clone can return a writable copy-on-write copy of it, also in
+// O(1) time.
+//
+// A SealedTreeG can't be modified, so it is safe for concurrent use by
+// multiple goroutines.
+type SealedTreeG[T any] struct {
+	t *BTreeG[T]
+}
+
+// Seal moves t's items into a new SealedTreeG, leaving t empty, in O(1)
+// time.  t's views stay with t, and are reset; the sealed tree has none.  t
+// can still be used, but shares nothing with the sealed tree.
+func (t *BTreeG[T]) Seal() *SealedTreeG[T] {
+	sealed := *t
+	sealed.views = nil
+	if t.bloom != nil {
+		t.bloom.shared = true
+	}
+	if t.keys != nil {
+		t.keys.shared = true
+	}
+	// Give t a new context, so that the sealed tree's nodes are read-only to
+	// t, and to clones of either.
+	cow := *t.cow
+	t.cow = &cow
+	t.Clear(false)
+	return &SealedTreeG[T]{t: &sealed}
+}
+
+// Clone returns a writable copy of the sealed tree, lazily, in O(1) time.
+// As with BTreeG.Clone, nodes are copied only as the copy writes to them.
+func (s *SealedTreeG[T]) Clone() *BTreeG[T] {
+	// s.t never writes to its nodes, so unlike BTreeG.Clone this needn't
+	// give s.t a new context, and so doesn't modify s.
+	out := *s.t
+	cow := *s.t.cow
+	out.cow = &cow
+	out.shared = true
+	return &out
+}
+
+// Len returns the number of items in the tree.
+func (s *SealedTreeG[T]) Len() int {
+	return s.t.Len()
+}
+
+// Get looks for the key item in the tree, returning it.  It returns
+// (zeroValue, false) if unable to find that item.
+func (s *SealedTreeG[T]) Get(key T) (T, bool) {
+	return s.t.Get(key)
+}
+
+// Has returns true if the given key is in the tree.
+func (s *SealedTreeG[T]) Has(key T) bool {
+	return s.t.Has(key)
+}
+
+// Min returns the smallest item in the tree, or (zeroValue, false) if the
+// tree is empty.
+func (s *SealedTreeG[T]) Min() (T, bool) {
+	return s.t.Min()
+}
+
+// Max returns the largest item in the tree, or (zeroValue, false) if the
+// tree is empty.
+func (s *SealedTreeG[T]) Max() (T, bool) {
+	return s.t.Max()
+}
+
+// Ascend calls the iterator for every value in the tree, in ascending order,
+// until iterator returns false.
+func (s *SealedTreeG[T]) Ascend(iterator ItemIteratorG[T]) {
+	s.t.Ascend(iterator)
+}
+
+// AscendRange calls the iterator for every value in the tree within the
+// range [greaterOrEqual, lessThan), until iterator returns false.
+func (s *SealedTreeG[T]) AscendRange(greaterOrEqual, lessThan T, iterator ItemIteratorG[T]) {
+	s.t.AscendRange(greaterOrEqual, lessThan, iterator)
+}
+
+// AscendLessThan calls the iterator for every value in the tree within the
+// range [first, pivot), until iterator returns false.
+func (s *SealedTreeG[T]) AscendLessThan(pivot T, iterator ItemIteratorG[T]) {
+	s.t.AscendLessThan(pivot, iterator)
+}
+
+// AscendGreaterOrEqual calls the iterator for every value in the tree within
+// the range [pivot, last], until iterator returns false.
+func (s *SealedTreeG[T]) AscendGreaterOrEqual(pivot T, iterator ItemIteratorG[T]) {
+	s.t.AscendGreaterOrEqual(pivot, iterator)
+}
+
+// Descend calls the iterator for every value in the tree, in descending
+// order, until iterator returns false.
+func (s *SealedTreeG[T]) Descend(iterator ItemIteratorG[T]) {
+	s.t.Descend(iterator)
+}
+
+// DescendRange calls the iterator for every value in the tree within the
+// range [lessOrEqual, greaterThan), in descending order, until iterator
+// returns false.
+func (s *SealedTreeG[T]) DescendRange(lessOrEqual, greaterThan T, iterator ItemIteratorG[T]) {
+	s.t.DescendRange(lessOrEqual, greaterThan, iterator)
+}
+
+// DescendLessOrEqual calls the iterator for every value in the tree within
+// the range [pivot, first], in descending order, until iterator returns
+// false.
+func (s *SealedTreeG[T]) DescendLessOrEqual(pivot T, iterator ItemIteratorG[T]) {
+	s.t.DescendLessOrEqual(pivot, iterator)
+}
+
+// DescendGreaterThan calls the iterator for every value in the tree within
+// the range [last, pivot), in descending order, until iterator returns
+// false.
+func (s *SealedTreeG[T]) DescendGreaterThan(pivot T, iterator ItemIteratorG[T]) {
+	s.t.DescendGreaterThan(pivot, iterator)
+}
@@ -0,0 +1,79 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestSeal(t *testing.T) {
+	tr := NewOrderedG[int](*btreeDegree)
+	for i := 0; i < 1000; i++ {
+		tr.ReplaceOrInsert(i)
+	}
+	root := tr.root
+	s := tr.Seal()
+	if s.t.root != root || s.Len() != 1000 {
+		t.Fatal("Seal copied the tree")
+	}
+	if tr.Len() != 0 {
+		t.Errorf("sealed tree's source has %d items", tr.Len())
+	}
+	tr.ReplaceOrInsert(-1)
+	if s.Has(-1) || s.Len() != 1000 {
+		t.Error("writing to the source changed the sealed tree")
+	}
+
+	// Clones are writable and independent, and can be made concurrently.
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		w := w
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c := s.Clone()
+			for i := w; i < 1000; i += 4 {
+				c.Delete(i)
+			}
+			c.ReplaceOrInsert(1000 + w)
+			if c.Len() != 751 || !c.Has(1000+w) {
+				t.Errorf("clone %d has %d items", w, c.Len())
+			}
+			if err := c.Verify(); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if min, _ := s.Min(); min != 0 {
+		t.Errorf("Min() = %d", min)
+	}
+	if max, _ := s.Max(); max != 999 {
+		t.Errorf("Max() = %d", max)
+	}
+	var got []int
+	s.DescendRange(5, 2, func(i int) bool {
+		got = append(got, i)
+		return true
+	})
+	if want := []int{5, 4, 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("DescendRange(5, 2) = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,152 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+// Package seqbuf reassembles a byte stream from segments that arrive out of
+// order, possibly overlapping or repeated, as in TCP reassembly or applying
+// a log received out of order.
+//
+// Segments are held in a btree.BTreeG ordered by offset.  Overlapping and
+// adjacent segments are coalesced as they are inserted, so the tree only
+// ever holds disjoint, non-touching segments, and the data at the front of
+// the stream is available as a single slice once the gap before it fills.
+package seqbuf
+
+import "github.com/google/btree"
+
+type segment struct {
+	offset int64
+	data   []byte
+}
+
+func (s segment) end() int64 {
+	return s.offset + int64(len(s.data))
+}
+
+func less(a, b segment) bool {
+	return a.offset < b.offset
+}
+
+// Buffer holds segments of a stream that have arrived but can't yet be
+// consumed, because data before them is missing.  It is not safe for
+// concurrent use.
+type Buffer struct {
+	t        *btree.BTreeG[segment]
+	next     int64
+	buffered int
+}
+
+// New returns an empty buffer expecting the stream to continue at offset
+// next.
+func New(next int64) *Buffer {
+	return &Buffer{t: btree.NewG(btree.DefaultDegree, less), next: next}
+}
+
+// Next returns the offset of the next byte the buffer will return from Pop.
+func (b *Buffer) Next() int64 {
+	return b.next
+}
+
+// Buffered returns the number of bytes held in the buffer.
+func (b *Buffer) Buffered() int {
+	return b.buffered
+}
+
+// Segments returns the number of disjoint segments held in the buffer.
+func (b *Buffer) Segments() int {
+	return b.t.Len()
+}
+
+// Insert adds data at the given offset of the stream.  Bytes before Next,
+// which have already been popped, are ignored, as are bytes the buffer
+// already holds: where segments overlap, the first to arrive wins.  Insert
+// doesn't retain data.
+func (b *Buffer) Insert(offset int64, data []byte) {
+	if offset < b.next {
+		if int64(len(data)) <= b.next-offset {
+			return
+		}
+		data = data[b.next-offset:]
+		offset = b.next
+	}
+	if len(data) == 0 {
+		return
+	}
+	seg := segment{offset: offset, data: data}
+	// Collect the held segments that overlap or touch the new one: possibly
+	// one starting before it, and any starting within it.
+	var merge []segment
+	b.t.DescendLessOrEqual(seg, func(s segment) bool {
+		if s.end() >= seg.offset {
+			merge = append(merge, s)
+		}
+		return false
+	})
+	b.t.AscendGreaterOrEqual(seg, func(s segment) bool {
+		if s.offset > seg.end() {
+			return false
+		}
+		if len(merge) == 0 || merge[len(merge)-1].offset != s.offset {
+			merge = append(merge, s)
+		}
+		return true
+	})
+	start, end := seg.offset, seg.end()
+	for _, s := range merge {
+		if s.offset < start {
+			start = s.offset
+		}
+		if s.end() > end {
+			end = s.end()
+		}
+	}
+	out := make([]byte, end-start)
+	copy(out[seg.offset-start:], seg.data)
+	for _, s := range merge {
+		copy(out[s.offset-start:], s.data)
+		b.t.Delete(s)
+		b.buffered -= len(s.data)
+	}
+	b.t.ReplaceOrInsert(segment{offset: start, data: out})
+	b.buffered += len(out)
+}
+
+// Pop removes and returns the data at the front of the stream, starting at
+// Next, or returns nil if the first byte hasn't arrived yet.  The returned
+// slice holds every contiguous byte available, and Next advances past it.
+func (b *Buffer) Pop() []byte {
+	s, ok := b.t.Min()
+	if !ok || s.offset != b.next {
+		return nil
+	}
+	b.t.DeleteMin()
+	b.next = s.end()
+	b.buffered -= len(s.data)
+	return s.data
+}
+
+// Gaps calls iter with each range [from, to) of the stream that is missing
+// before the last buffered byte, in order, until iter returns false.
+func (b *Buffer) Gaps(iter func(from, to int64) bool) {
+	pos := b.next
+	b.t.Ascend(func(s segment) bool {
+		if s.offset > pos && !iter(pos, s.offset) {
+			return false
+		}
+		pos = s.end()
+		return true
+	})
+}
@@ -0,0 +1,88 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package seqbuf
+
+import (
+	"bytes"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestInOrder(t *testing.T) {
+	b := New(100)
+	b.Insert(100, []byte("abc"))
+	if got := b.Pop(); string(got) != "abc" || b.Next() != 103 {
+		t.Errorf("Pop() = %q, Next() = %d", got, b.Next())
+	}
+	if got := b.Pop(); got != nil {
+		t.Errorf("Pop() on empty buffer = %q", got)
+	}
+}
+
+func TestCoalesce(t *testing.T) {
+	b := New(0)
+	b.Insert(6, []byte("gh"))
+	b.Insert(2, []byte("cd"))
+	b.Insert(10, []byte("kl"))
+	if b.Segments() != 3 || b.Pop() != nil {
+		t.Fatalf("segments %d", b.Segments())
+	}
+	var gaps [][2]int64
+	b.Gaps(func(from, to int64) bool {
+		gaps = append(gaps, [2]int64{from, to})
+		return true
+	})
+	if want := [][2]int64{{0, 2}, {4, 6}, {8, 10}}; !reflect.DeepEqual(gaps, want) {
+		t.Errorf("gaps %v, want %v", gaps, want)
+	}
+	// Fills 4-6 and touches 8, joining three segments; the overlapping
+	// bytes already held win.
+	b.Insert(3, []byte("XefX"))
+	if b.Segments() != 2 || b.Buffered() != 8 {
+		t.Errorf("segments %d, buffered %d", b.Segments(), b.Buffered())
+	}
+	b.Insert(0, []byte("abYY"))
+	if got := b.Pop(); string(got) != "abcdefgh" {
+		t.Errorf("Pop() = %q", got)
+	}
+	b.Insert(0, []byte("stale daij"))
+	if got := b.Pop(); string(got) != "ijkl" || b.Buffered() != 0 {
+		t.Errorf("Pop() = %q, buffered %d", got, b.Buffered())
+	}
+}
+
+func TestRandomReassembly(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	stream := make([]byte, 5000)
+	r.Read(stream)
+	b := New(0)
+	var out []byte
+	for b.Next() < int64(len(stream)) {
+		off := r.Intn(len(stream))
+		n := r.Intn(50) + 1
+		if off+n > len(stream) {
+			n = len(stream) - off
+		}
+		b.Insert(int64(off), stream[off:off+n])
+		out = append(out, b.Pop()...)
+	}
+	if !bytes.Equal(out, stream) || b.Segments() != 0 {
+		t.Errorf("reassembled stream differs, %d segments left", b.Segments())
+	}
+}
@@ -0,0 +1,172 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+)
+
+// A snapshot is a header, a sequence of blocks, and a trailer:
+//
+//	header:  "btsnap" and the format version (1 byte)
+//	block:   item count (uvarint, > 0), payload size (uvarint), payload
+//	         (the items' encodings), CRC-32C of the payload (4 bytes,
+//	         little-endian)
+//	trailer: 0 (uvarint), total item count (uvarint)
+//
+// Blocks are checksummed separately so that a corrupt snapshot is caught
+// before its items are used, and the trailer catches one that was cut short
+// at a block boundary.
+const (
+	snapshotMagic     = "btsnap"
+	snapshotVersion   = 1
+	snapshotBlockSize = 64 << 10
+)
+
+var crc32c = crc32.MakeTable(crc32.Castagnoli)
+
+// ErrChecksum is returned when restoring a snapshot that fails its checksum.
+var ErrChecksum = errors.New("btree: snapshot checksum mismatch")
+
+// AppendSnapshot appends a snapshot of the tree's items, encoded with codec,
+// to dst, returning the extended buffer.  Unlike AppendItems, the snapshot
+// carries a format version and checksums, so that RestoreSnapshot can
+// reject corrupt or incompatible data.
+func (t *BTreeG[T]) AppendSnapshot(dst []byte, codec ItemCodec[T]) (_ []byte, err error) {
+	var buf [binary.MaxVarintLen64]byte
+	uvarint := func(v uint64) {
+		dst = append(dst, buf[:binary.PutUvarint(buf[:], v)]...)
+	}
+	dst = append(dst, snapshotMagic...)
+	dst = append(dst, snapshotVersion)
+	var block []byte
+	count := 0
+	flush := func() {
+		uvarint(uint64(count))
+		uvarint(uint64(len(block)))
+		dst = append(dst, block...)
+		binary.LittleEndian.PutUint32(buf[:], crc32.Checksum(block, crc32c))
+		dst = append(dst, buf[:4]...)
+		block, count = block[:0], 0
+	}
+	t.ascend(func(item T) bool {
+		if block, err = codec.Append(block, item); err != nil {
+			return false
+		}
+		if count++; len(block) >= snapshotBlockSize {
+			flush()
+		}
+		return true
+	})
+	if err != nil {
+		return dst, err
+	}
+	if count > 0 {
+		flush()
+	}
+	uvarint(0)
+	uvarint(uint64(t.length))
+	return dst, nil
+}
+
+// RestoreSnapshot decodes a snapshot written by AppendSnapshot from the
+// start of src and adds its items to the tree, returning the length of the
+// snapshot.  Every block is decoded and its checksum checked before any
+// item is added, so if the snapshot is corrupt, truncated, or of an unknown
+// version, RestoreSnapshot returns an error and leaves the tree unchanged.
+//
+// If strict is true, RestoreSnapshot also fails if the snapshot's items are
+// not in strictly ascending order according to the tree's LessFunc (as when
+// it was written by a tree ordered differently), and, after adding them,
+// runs Verify and returns its error, if any.  A non-strict restore adds
+// out-of-order items as ReplaceOrInsert would.
+func (t *BTreeG[T]) RestoreSnapshot(src []byte, codec ItemCodec[T], strict bool) (int, error) {
+	if len(src) < len(snapshotMagic)+1 {
+		return 0, ErrTruncated
+	}
+	if !bytes.Equal(src[:len(snapshotMagic)], []byte(snapshotMagic)) {
+		return 0, errors.New("btree: not a snapshot")
+	}
+	if v := src[len(snapshotMagic)]; v != snapshotVersion {
+		return 0, fmt.Errorf("btree: unsupported snapshot version %d", v)
+	}
+	n := len(snapshotMagic) + 1
+	uvarint := func() (uint64, error) {
+		v, size := binary.Uvarint(src[n:])
+		if size <= 0 {
+			return 0, varintError(size)
+		}
+		n += size
+		return v, nil
+	}
+	var items []T
+	for {
+		count, err := uvarint()
+		if err != nil {
+			return n, err
+		}
+		if count == 0 {
+			break
+		}
+		size, err := uvarint()
+		if err != nil {
+			return n, err
+		}
+		if size > uint64(len(src)-n) || len(src)-n-int(size) < 4 {
+			return n, ErrTruncated
+		}
+		block := src[n : n+int(size)]
+		n += int(size)
+		if crc32.Checksum(block, crc32c) != binary.LittleEndian.Uint32(src[n:]) {
+			return n, ErrChecksum
+		}
+		n += 4
+		for ; count > 0; count-- {
+			item, size, err := codec.Decode(block)
+			if err != nil {
+				return n, err
+			}
+			if strict && len(items) > 0 && !t.cow.less(items[len(items)-1], item) {
+				return n, fmt.Errorf("btree: snapshot items %v and %v are out of order", items[len(items)-1], item)
+			}
+			items = append(items, item)
+			block = block[size:]
+		}
+		if len(block) != 0 {
+			return n, errors.New("btree: snapshot block has trailing data")
+		}
+	}
+	total, err := uvarint()
+	if err != nil {
+		return n, err
+	}
+	if total != uint64(len(items)) {
+		return n, fmt.Errorf("btree: snapshot has %d items, but its trailer says %d", len(items), total)
+	}
+	for _, item := range items {
+		t.AppendMax(item)
+	}
+	if strict {
+		return n, t.Verify()
+	}
+	return n, nil
+}
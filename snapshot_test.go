@@ -0,0 +1,105 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSnapshotRoundTrip(t *testing.T) {
+	tr := NewOrderedG[string](*btreeDegree)
+	for i := 0; i < 20000; i++ {
+		tr.ReplaceOrInsert(strings.Repeat("x", i%7) + string(rune('a'+i%26)) + string(rune(i)))
+	}
+	codec := OrderedCodec[string]()
+	buf, err := tr.AppendSnapshot([]byte("header"), codec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(buf) < 2*snapshotBlockSize {
+		t.Fatalf("snapshot is %d bytes, too small to test multiple blocks", len(buf))
+	}
+	buf = append(buf, "trailer"...)
+	got := NewOrderedG[string](*btreeDegree)
+	n, err := got.RestoreSnapshot(buf[len("header"):], codec, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rest := string(buf[len("header")+n:]); rest != "trailer" {
+		t.Errorf("RestoreSnapshot left %q, want %q", rest, "trailer")
+	}
+	if !reflect.DeepEqual(got.all(), tr.all()) {
+		t.Error("restored tree differs from the original")
+	}
+}
+
+func TestSnapshotCorruption(t *testing.T) {
+	tr := NewOrderedG[int](*btreeDegree)
+	for i := 0; i < 1000; i++ {
+		tr.ReplaceOrInsert(i)
+	}
+	codec := OrderedCodec[int]()
+	good, err := tr.AppendSnapshot(nil, codec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	flipped := append([]byte(nil), good...)
+	flipped[len(flipped)/2] ^= 1
+	version := append([]byte(nil), good...)
+	version[len(snapshotMagic)]++
+	for name, c := range map[string]struct {
+		src  []byte
+		want error
+	}{
+		"flipped bit":  {flipped, ErrChecksum},
+		"truncated":    {good[:len(good)-3], ErrTruncated},
+		"version":      {version, nil},
+		"not snapshot": {[]byte("btree snapshot"), nil},
+	} {
+		got := NewOrderedG[int](*btreeDegree)
+		got.ReplaceOrInsert(-1)
+		_, err := got.RestoreSnapshot(c.src, codec, false)
+		if err == nil || (c.want != nil && err != c.want) {
+			t.Errorf("%s: RestoreSnapshot returned %v, want %v", name, err, c.want)
+		}
+		if got.Len() != 1 {
+			t.Errorf("%s: failed RestoreSnapshot changed the tree", name)
+		}
+	}
+}
+
+func TestSnapshotStrict(t *testing.T) {
+	// A snapshot of a descending tree is out of order for an ascending one.
+	tr := NewG(*btreeDegree, func(a, b int) bool { return a > b })
+	for i := 0; i < 10; i++ {
+		tr.ReplaceOrInsert(i)
+	}
+	buf, err := tr.AppendSnapshot(nil, OrderedCodec[int]())
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := NewOrderedG[int](*btreeDegree)
+	if _, err := got.RestoreSnapshot(buf, OrderedCodec[int](), true); err == nil || got.Len() != 0 {
+		t.Errorf("strict restore of out-of-order items returned %v with %d items", err, got.Len())
+	}
+	if _, err := got.RestoreSnapshot(buf, OrderedCodec[int](), false); err != nil || got.Len() != 10 {
+		t.Errorf("non-strict restore returned %v with %d items", err, got.Len())
+	}
+}
@@ -0,0 +1,55 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+// SplitPolicy determines where a full node is split in two when an insert
+// needs room in it.
+type SplitPolicy int
+
+const (
+	// SplitEven splits full nodes in half.  This is the default, and suits
+	// inserts in random order.
+	SplitEven SplitPolicy = iota
+	// SplitRightBiased splits a full node on the right edge of the tree
+	// (the nodes holding the largest items) so that only one item moves to
+	// the new right-hand node, if the item being inserted is greater than
+	// every item in it.  This leaves the nodes behind an ascending run of
+	// inserts nearly full, rather than half full, roughly halving the
+	// memory used by trees of increasing keys such as timestamps and
+	// sequence numbers.  Other splits are even.
+	//
+	// The nodes on the right edge of the tree may then hold fewer than the
+	// usual minimum number of items; Verify allows for this.
+	SplitRightBiased
+)
+
+// WithSplitPolicy sets where the tree splits full nodes.  The default is
+// SplitEven.
+func WithSplitPolicy[T any](p SplitPolicy) Option[T] {
+	return func(o *options[T]) { o.splitPolicy = p }
+}
+
+// splitIndex returns the index at which to split n, which is full.  If edge
+// is true, n is on the right edge of the tree, and item is the item about to
+// be inserted.
+func (n *node[T]) splitIndex(maxItems int, edge bool, item T) int {
+	if edge && n.cow.splitPolicy == SplitRightBiased && n.cow.less(n.items[len(n.items)-1], item) {
+		return maxItems - 2
+	}
+	return maxItems / 2
+}
@@ -0,0 +1,74 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func TestSplitRightBiased(t *testing.T) {
+	even := NewWithOptions(WithOrdered[int](), WithDegree[int](*btreeDegree))
+	biased := NewWithOptions(WithOrdered[int](), WithDegree[int](*btreeDegree),
+		WithSplitPolicy[int](SplitRightBiased))
+	const n = 10000
+	for i := 0; i < n; i++ {
+		even.ReplaceOrInsert(i)
+		if i%2 == 0 {
+			biased.ReplaceOrInsert(i)
+		} else {
+			biased.AppendMax(i)
+		}
+	}
+	if err := biased.Verify(); err != nil {
+		t.Fatal(err)
+	}
+	if e, b := even.FillFactor(), biased.FillFactor(); b < 0.9 || b <= e {
+		t.Errorf("fill factor %.2f with right-biased splits, %.2f with even", b, e)
+	}
+
+	// The tree stays valid through random inserts and deletes.
+	for _, v := range rand.Perm(2 * n) {
+		if v%3 == 0 {
+			biased.Delete(v)
+		} else {
+			biased.ReplaceOrInsert(v)
+		}
+		if v%500 == 0 {
+			if err := biased.Verify(); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	var want []int
+	for v := 0; v < 2*n; v++ {
+		if v%3 != 0 {
+			want = append(want, v)
+		}
+	}
+	if got := biased.all(); !reflect.DeepEqual(got, want) {
+		t.Error("wrong contents after random writes")
+	}
+	for biased.Len() > 0 {
+		biased.DeleteMax()
+	}
+	if err := biased.Verify(); err != nil {
+		t.Fatal(err)
+	}
+}
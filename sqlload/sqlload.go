@@ -0,0 +1,51 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+// Package sqlload loads the results of SQL queries into a btree.BTreeG.
+//
+// It lives in its own package so that the btree package doesn't depend on
+// database/sql.
+package sqlload
+
+import (
+	"database/sql"
+
+	"github.com/google/btree"
+)
+
+// LoadFromRows scans each of rows with scan and adds the result to t, as
+// with ReplaceOrInsert, returning the number of rows loaded.  It stops at
+// the first error from scan or from rows, which it returns; the rows loaded
+// before it remain in t.  rows is closed when they are exhausted, but the
+// caller should still close it, as usual, in case of error.
+//
+// Items are added with AppendMax, so a query whose ORDER BY matches the
+// tree's order loads with one comparison per row, and with the tree's nodes
+// filled as for ascending inserts.  Rows in any other order still load
+// correctly, at the usual cost of ReplaceOrInsert plus one comparison.
+func LoadFromRows[T any](t *btree.BTreeG[T], rows *sql.Rows, scan func(*sql.Rows) (T, error)) (int, error) {
+	n := 0
+	for rows.Next() {
+		item, err := scan(rows)
+		if err != nil {
+			return n, err
+		}
+		t.AppendMax(item)
+		n++
+	}
+	return n, rows.Err()
+}
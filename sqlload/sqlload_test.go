@@ -0,0 +1,137 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package sqlload
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"math/rand"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/google/btree"
+)
+
+// fakeDriver serves every query with the rows of fakeRows, a single int64
+// column, named by the query text.
+type fakeDriver struct{}
+
+var fakeRows = map[string][]int64{}
+
+func init() {
+	sql.Register("sqlload-fake", fakeDriver{})
+}
+
+func (fakeDriver) Open(string) (driver.Conn, error) { return fakeConn{}, nil }
+
+type fakeConn struct{}
+
+func (fakeConn) Prepare(query string) (driver.Stmt, error) { return fakeStmt(query), nil }
+func (fakeConn) Close() error                              { return nil }
+func (fakeConn) Begin() (driver.Tx, error)                 { return nil, errors.New("no transactions") }
+
+type fakeStmt string
+
+func (fakeStmt) Close() error  { return nil }
+func (fakeStmt) NumInput() int { return 0 }
+func (fakeStmt) Exec([]driver.Value) (driver.Result, error) {
+	return nil, errors.New("no exec")
+}
+func (s fakeStmt) Query([]driver.Value) (driver.Rows, error) {
+	return &fakeResult{values: fakeRows[string(s)]}, nil
+}
+
+type fakeResult struct {
+	values []int64
+}
+
+func (r *fakeResult) Columns() []string { return []string{"v"} }
+func (r *fakeResult) Close() error      { return nil }
+func (r *fakeResult) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	dest[0], r.values = r.values[0], r.values[1:]
+	return nil
+}
+
+func query(t *testing.T, values []int64) *sql.Rows {
+	t.Helper()
+	db, err := sql.Open("sqlload-fake", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	name := strconv.Itoa(len(fakeRows))
+	fakeRows[name] = values
+	rows, err := db.Query(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { rows.Close() })
+	return rows
+}
+
+func scanInt(rows *sql.Rows) (v int64, err error) {
+	err = rows.Scan(&v)
+	return v, err
+}
+
+func TestLoadFromRows(t *testing.T) {
+	for _, ordered := range []bool{true, false} {
+		var values, want []int64
+		for i := 0; i < 1000; i++ {
+			want = append(want, int64(i))
+		}
+		values = append(values, want...)
+		if !ordered {
+			rand.Shuffle(len(values), func(i, j int) { values[i], values[j] = values[j], values[i] })
+		}
+		tr := btree.NewOrderedG[int64](4)
+		n, err := LoadFromRows(tr, query(t, values), scanInt)
+		if err != nil || n != len(values) {
+			t.Fatalf("LoadFromRows = %d, %v", n, err)
+		}
+		var got []int64
+		tr.Ascend(func(v int64) bool {
+			got = append(got, v)
+			return true
+		})
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("ordered=%v: tree holds %v", ordered, got)
+		}
+	}
+}
+
+func TestLoadFromRowsScanError(t *testing.T) {
+	bad := errors.New("bad row")
+	tr := btree.NewOrderedG[int64](4)
+	n, err := LoadFromRows(tr, query(t, []int64{1, 2, 3}), func(rows *sql.Rows) (int64, error) {
+		v, err := scanInt(rows)
+		if v == 3 {
+			err = bad
+		}
+		return v, err
+	})
+	if err != bad || n != 2 || tr.Len() != 2 {
+		t.Errorf("LoadFromRows = %d, %v with %d items loaded; want 2, %v", n, err, tr.Len(), bad)
+	}
+}
@@ -0,0 +1,358 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+// Package sstable writes the items of a btree.BTreeG to a sorted, immutable
+// file, and reads them back lazily, as in the memtable-to-SSTable flow of a
+// log-structured store: a tree that has grown too large is written out with
+// Write and cleared, and its items are later found with Get and
+// AscendGreaterOrEqual, which read only the parts of the file they need.
+//
+// A table is a sequence of data blocks, an index block, and a footer.  Each
+// data block holds the encodings, by a btree.ItemCodec, of a run of items
+// totalling about Options.BlockSize bytes, optionally compressed.  The index
+// block is sparse: it holds the first item of each data block, with the
+// block's position, size and item count.  The footer is the last
+// footerSize bytes of the file:
+//
+//	index offset (8 bytes, little-endian)
+//	index size   (8 bytes, little-endian)
+//	compression  (1 byte)
+//	magic        (7 bytes, "btsst01")
+//
+// Opening a table reads only its footer and index.  The file can be read
+// through any io.ReaderAt, such as an *os.File, or a bytes.Reader over a
+// memory-mapped file.
+package sstable
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/google/btree"
+)
+
+// Compression is how a table's blocks are compressed.
+type Compression byte
+
+const (
+	// NoCompression stores blocks as is.
+	NoCompression Compression = iota
+	// Flate compresses each block with DEFLATE, at the default level.
+	Flate
+)
+
+// Options configure Write.
+type Options struct {
+	// BlockSize is the size, before compression, at which a data block is
+	// ended and a new one begun.  Larger blocks compress better and make the
+	// index smaller, but make every lookup read and decode more.  The
+	// default is 4096.
+	BlockSize int
+	// Compression is how blocks are compressed.  The default is
+	// NoCompression.
+	Compression Compression
+}
+
+const (
+	magic      = "btsst01"
+	footerSize = 8 + 8 + 1 + 7 // offset, size, compression, magic
+)
+
+// ErrFormat is returned when reading a file that isn't a table, or is
+// corrupt.
+var ErrFormat = errors.New("sstable: not a table, or corrupt")
+
+// writer writes a table, keeping track of its size.
+type writer struct {
+	w           io.Writer
+	offset      int64
+	compression Compression
+	err         error
+}
+
+// write compresses and writes a block, returning its position and size.
+func (w *writer) write(block []byte, compress bool) (offset, size int64) {
+	if w.err != nil {
+		return 0, 0
+	}
+	if compress && w.compression == Flate {
+		var buf bytes.Buffer
+		fw, err := flate.NewWriter(&buf, flate.DefaultCompression)
+		if err == nil {
+			_, err = fw.Write(block)
+		}
+		if err == nil {
+			err = fw.Close()
+		}
+		if err != nil {
+			w.err = err
+			return 0, 0
+		}
+		block = buf.Bytes()
+	}
+	offset = w.offset
+	n, err := w.w.Write(block)
+	w.offset += int64(n)
+	w.err = err
+	return offset, int64(n)
+}
+
+func appendUvarint(dst []byte, v uint64) []byte {
+	var buf [binary.MaxVarintLen64]byte
+	return append(dst, buf[:binary.PutUvarint(buf[:], v)]...)
+}
+
+// Write writes t's items to w as a table, encoding them with codec.
+func Write[T any](w io.Writer, t *btree.BTreeG[T], codec btree.ItemCodec[T], opts Options) error {
+	if opts.BlockSize <= 0 {
+		opts.BlockSize = 4096
+	}
+	switch opts.Compression {
+	case NoCompression, Flate:
+	default:
+		return fmt.Errorf("sstable: unknown compression %d", opts.Compression)
+	}
+	tw := &writer{w: w, compression: opts.Compression}
+	var block, index []byte
+	var first T
+	count := 0
+	// err holds codec errors; tw.err holds write errors.
+	var err error
+	flush := func() {
+		if count == 0 || tw.err != nil {
+			return
+		}
+		offset, size := tw.write(block, true)
+		if tw.err != nil {
+			return
+		}
+		if index, err = codec.Append(index, first); err != nil {
+			return
+		}
+		index = appendUvarint(index, uint64(offset))
+		index = appendUvarint(index, uint64(size))
+		index = appendUvarint(index, uint64(count))
+		block, count = block[:0], 0
+	}
+	t.Ascend(func(item T) bool {
+		if count == 0 {
+			first = item
+		}
+		if block, err = codec.Append(block, item); err != nil {
+			return false
+		}
+		count++
+		if len(block) >= opts.BlockSize {
+			flush()
+		}
+		return err == nil && tw.err == nil
+	})
+	if err == nil {
+		flush()
+	}
+	if err != nil {
+		return err
+	}
+	offset, size := tw.write(index, false)
+	var footer [footerSize]byte
+	binary.LittleEndian.PutUint64(footer[0:], uint64(offset))
+	binary.LittleEndian.PutUint64(footer[8:], uint64(size))
+	footer[16] = byte(opts.Compression)
+	copy(footer[17:], magic)
+	tw.write(footer[:], false)
+	return tw.err
+}
+
+// blockHandle is an index entry: a data block's first item, position, size,
+// and item count.
+type blockHandle[T any] struct {
+	first        T
+	offset, size int64
+	count        int
+}
+
+// Table is a table opened for reading.  It is safe for concurrent use if
+// its io.ReaderAt is.
+type Table[T any] struct {
+	r           io.ReaderAt
+	codec       btree.ItemCodec[T]
+	less        btree.LessFunc[T]
+	compression Compression
+	blocks      []blockHandle[T]
+	length      int
+}
+
+// Open opens the table of the given size read from r, whose items are
+// decoded by codec and ordered by less, which must be the order of the tree
+// it was written from.  It reads the table's footer and index.
+func Open[T any](r io.ReaderAt, size int64, codec btree.ItemCodec[T], less btree.LessFunc[T]) (*Table[T], error) {
+	if size < int64(footerSize) {
+		return nil, ErrFormat
+	}
+	var footer [footerSize]byte
+	if _, err := r.ReadAt(footer[:], size-footerSize); err != nil {
+		return nil, err
+	}
+	offset := binary.LittleEndian.Uint64(footer[0:])
+	indexSize := binary.LittleEndian.Uint64(footer[8:])
+	tb := &Table[T]{r: r, codec: codec, less: less, compression: Compression(footer[16])}
+	if string(footer[17:]) != magic || tb.compression > Flate ||
+		offset > uint64(size-footerSize) || indexSize > uint64(size-footerSize)-offset {
+		return nil, ErrFormat
+	}
+	index := make([]byte, indexSize)
+	if _, err := r.ReadAt(index, int64(offset)); err != nil {
+		return nil, err
+	}
+	for len(index) > 0 {
+		var h blockHandle[T]
+		var n int
+		var err error
+		if h.first, n, err = codec.Decode(index); err != nil {
+			return nil, err
+		}
+		index = index[n:]
+		var fields [3]uint64
+		for i := range fields {
+			if fields[i], n = binary.Uvarint(index); n <= 0 {
+				return nil, ErrFormat
+			}
+			index = index[n:]
+		}
+		h.offset, h.size, h.count = int64(fields[0]), int64(fields[1]), int(fields[2])
+		if fields[0] > offset || fields[1] > offset-fields[0] || h.count <= 0 {
+			return nil, ErrFormat
+		}
+		tb.blocks = append(tb.blocks, h)
+		tb.length += h.count
+	}
+	return tb, nil
+}
+
+// Len returns the number of items in the table.
+func (tb *Table[T]) Len() int {
+	return tb.length
+}
+
+// Blocks returns the number of data blocks in the table.
+func (tb *Table[T]) Blocks() int {
+	return len(tb.blocks)
+}
+
+// block reads and decodes the i'th data block.
+func (tb *Table[T]) block(i int) ([]T, error) {
+	h := tb.blocks[i]
+	data := make([]byte, h.size)
+	if _, err := tb.r.ReadAt(data, h.offset); err != nil {
+		return nil, err
+	}
+	if tb.compression == Flate {
+		var err error
+		if data, err = io.ReadAll(flate.NewReader(bytes.NewReader(data))); err != nil {
+			return nil, err
+		}
+	}
+	items := make([]T, 0, h.count)
+	for len(items) < h.count {
+		item, n, err := tb.codec.Decode(data)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+		data = data[n:]
+	}
+	if len(data) != 0 {
+		return nil, ErrFormat
+	}
+	return items, nil
+}
+
+// seek returns the index of the block that would hold key: the last one
+// whose first item is not greater than key, or 0 if there is none.
+func (tb *Table[T]) seek(key T) int {
+	i := sort.Search(len(tb.blocks), func(i int) bool { return tb.less(key, tb.blocks[i].first) })
+	if i > 0 {
+		i--
+	}
+	return i
+}
+
+// Get looks for key in the table, returning the item equal to it if there
+// is one.  It reads at most one data block.
+func (tb *Table[T]) Get(key T) (_ T, _ bool, err error) {
+	if len(tb.blocks) == 0 {
+		return
+	}
+	items, err := tb.block(tb.seek(key))
+	if err != nil {
+		return
+	}
+	j := sort.Search(len(items), func(j int) bool { return !tb.less(items[j], key) })
+	if j < len(items) && !tb.less(key, items[j]) {
+		return items[j], true, nil
+	}
+	return
+}
+
+// AscendGreaterOrEqual calls iterator for every item in the table not less
+// than pivot, in order, until iterator returns false.  It reads data blocks
+// one at a time, as iteration reaches them.
+func (tb *Table[T]) AscendGreaterOrEqual(pivot T, iterator btree.ItemIteratorG[T]) error {
+	if len(tb.blocks) == 0 {
+		return nil
+	}
+	return tb.ascendFrom(tb.seek(pivot), &pivot, iterator)
+}
+
+// Ascend calls iterator for every item in the table, in order, until
+// iterator returns false.
+func (tb *Table[T]) Ascend(iterator btree.ItemIteratorG[T]) error {
+	return tb.ascendFrom(0, nil, iterator)
+}
+
+func (tb *Table[T]) ascendFrom(i int, pivot *T, iterator btree.ItemIteratorG[T]) error {
+	for ; i < len(tb.blocks); i++ {
+		items, err := tb.block(i)
+		if err != nil {
+			return err
+		}
+		for _, item := range items {
+			if pivot != nil && tb.less(item, *pivot) {
+				continue
+			}
+			if !iterator(item) {
+				return nil
+			}
+		}
+	}
+	return nil
+}
+
+// Load adds every item in the table to t, as with ReplaceOrInsert.  If t
+// is empty and ordered like the table, it is built with ascending inserts,
+// one comparison each.
+func (tb *Table[T]) Load(t *btree.BTreeG[T]) error {
+	return tb.Ascend(func(item T) bool {
+		t.AppendMax(item)
+		return true
+	})
+}
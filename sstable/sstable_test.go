@@ -0,0 +1,200 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package sstable
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/google/btree"
+)
+
+// countingReader counts the reads made through it.
+type countingReader struct {
+	r     io.ReaderAt
+	reads int
+}
+
+func (c *countingReader) ReadAt(p []byte, off int64) (int, error) {
+	c.reads++
+	return c.r.ReadAt(p, off)
+}
+
+// write writes a table of the even numbers below 2n.
+func write(t *testing.T, n int, opts Options) []byte {
+	t.Helper()
+	tr := btree.NewOrderedG[int](8)
+	for i := 0; i < n; i++ {
+		tr.ReplaceOrInsert(2 * i)
+	}
+	var buf bytes.Buffer
+	if err := Write(&buf, tr, btree.OrderedCodec[int](), opts); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func open(t *testing.T, data []byte) (*Table[int], *countingReader) {
+	t.Helper()
+	r := &countingReader{r: bytes.NewReader(data)}
+	tb, err := Open(r, int64(len(data)), btree.OrderedCodec[int](), btree.Less[int]())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tb, r
+}
+
+func TestRoundTrip(t *testing.T) {
+	for _, compression := range []Compression{NoCompression, Flate} {
+		data := write(t, 10000, Options{BlockSize: 256, Compression: compression})
+		tb, r := open(t, data)
+		if tb.Len() != 10000 || tb.Blocks() < 10 {
+			t.Fatalf("compression %d: Len() = %d with %d blocks", compression, tb.Len(), tb.Blocks())
+		}
+		for _, key := range []int{-1, 0, 1, 2, 5000, 5001, 19998, 19999} {
+			r.reads = 0
+			item, ok, err := tb.Get(key)
+			if err != nil || ok != (key >= 0 && key%2 == 0 && key < 20000) || (ok && item != key) {
+				t.Errorf("Get(%d) = %d, %v, %v", key, item, ok, err)
+			}
+			if r.reads != 1 {
+				t.Errorf("Get(%d) made %d reads, want 1", key, r.reads)
+			}
+		}
+		var got []int
+		if err := tb.AscendGreaterOrEqual(9991, func(item int) bool {
+			got = append(got, item)
+			return len(got) < 3
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if want := []int{9992, 9994, 9996}; !reflect.DeepEqual(got, want) {
+			t.Errorf("AscendGreaterOrEqual(9991) = %v, want %v", got, want)
+		}
+		tr := btree.NewOrderedG[int](8)
+		if err := tb.Load(tr); err != nil || tr.Len() != 10000 {
+			t.Fatalf("Load: %d items, %v", tr.Len(), err)
+		}
+		if min, _ := tr.Min(); min != 0 {
+			t.Errorf("Min() = %d after Load", min)
+		}
+	}
+}
+
+func TestCompression(t *testing.T) {
+	tr := btree.NewOrderedG[string](8)
+	for i := 0; i < 10000; i++ {
+		tr.ReplaceOrInsert(fmt.Sprintf("user/%08d/profile", i))
+	}
+	var plain, compressed bytes.Buffer
+	if err := Write(&plain, tr, btree.OrderedCodec[string](), Options{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := Write(&compressed, tr, btree.OrderedCodec[string](), Options{Compression: Flate}); err != nil {
+		t.Fatal(err)
+	}
+	if compressed.Len() >= plain.Len()/2 {
+		t.Errorf("compressed table is %d bytes, uncompressed %d", compressed.Len(), plain.Len())
+	}
+	tb, err := Open(bytes.NewReader(compressed.Bytes()), int64(compressed.Len()), btree.OrderedCodec[string](), btree.Less[string]())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item, ok, err := tb.Get("user/00001234/profile"); !ok || err != nil {
+		t.Errorf("Get = %q, %v, %v", item, ok, err)
+	}
+}
+
+func TestEmpty(t *testing.T) {
+	tb, _ := open(t, write(t, 0, Options{}))
+	if _, ok, err := tb.Get(1); ok || err != nil {
+		t.Errorf("Get(1) = %v, %v on an empty table", ok, err)
+	}
+	if err := tb.Ascend(func(int) bool { t.Error("iterated an empty table"); return true }); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestCorrupt(t *testing.T) {
+	data := write(t, 100, Options{})
+	for name, bad := range map[string][]byte{
+		"short":     data[:footerSize-1],
+		"magic":     append(append([]byte(nil), data[:len(data)-1]...), 'x'),
+		"truncated": data[1:],
+	} {
+		if _, err := Open(bytes.NewReader(bad), int64(len(bad)), btree.OrderedCodec[int](), btree.Less[int]()); err == nil {
+			t.Errorf("%s: Open succeeded", name)
+		}
+	}
+}
+
+var errFailed = errors.New("failed")
+
+// failingWriter fails its fail'th call to Write, and every one after that.
+type failingWriter struct {
+	fail, calls int
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	w.calls++
+	if w.calls >= w.fail {
+		return 0, errFailed
+	}
+	return len(p), nil
+}
+
+// failingCodec fails to encode the item fail.
+type failingCodec struct {
+	btree.ItemCodec[int]
+	fail int
+}
+
+func (c failingCodec) Append(dst []byte, item int) ([]byte, error) {
+	if item == c.fail {
+		return dst, errFailed
+	}
+	return c.ItemCodec.Append(dst, item)
+}
+
+func TestWriteErrors(t *testing.T) {
+	tr := btree.NewOrderedG[int](8)
+	for i := 0; i < 1000; i++ {
+		tr.ReplaceOrInsert(i)
+	}
+	for _, compression := range []Compression{NoCompression, Flate} {
+		opts := Options{BlockSize: 256, Compression: compression}
+		for _, fail := range []int{1, 2, 5} {
+			w := &failingWriter{fail: fail}
+			if err := Write(w, tr, btree.OrderedCodec[int](), opts); err != errFailed {
+				t.Errorf("compression %d: failing write %d: got %v, want %v", compression, fail, err, errFailed)
+			}
+			if w.calls != fail {
+				t.Errorf("compression %d: kept writing after write %d failed: %d writes", compression, fail, w.calls)
+			}
+		}
+		var buf bytes.Buffer
+		codec := failingCodec{btree.OrderedCodec[int](), 500}
+		if err := Write(&buf, tr, codec, opts); err != errFailed {
+			t.Errorf("compression %d: failing codec: got %v, want %v", compression, err, errFailed)
+		}
+	}
+}
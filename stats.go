@@ -0,0 +1,173 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Stats describes the shape of a tree, as returned by BTreeG.Stats.
+type Stats struct {
+	// Items is the number of items in the tree.
+	Items int
+	// Height is the number of levels in the tree; 0 for an empty tree and 1
+	// for a tree whose root is a leaf.
+	Height int
+	// InternalNodes and LeafNodes count the nodes with and without children.
+	InternalNodes, LeafNodes int
+	// FillFactor is the fraction of item slots in use across all nodes: Items
+	// divided by the number of nodes times the maximum items per node.
+	FillFactor float64
+	// FillHistogram counts nodes by how full they are.  FillHistogram[i]
+	// counts nodes holding between i/10 and (i+1)/10 of the maximum number
+	// of items; full nodes are counted in FillHistogram[9].
+	FillHistogram [10]int
+	// Comparisons counts calls to the tree's LessFunc, if the tree was
+	// created with WithComparisonCounting.
+	Comparisons ComparisonStats
+	// Latency holds histograms of the durations of operations, if the tree
+	// was created with WithLatencyHistograms, and is nil otherwise.
+	Latency *LatencyStats
+}
+
+// Stats returns statistics about the shape of the tree, computed in a single
+// traversal that visits every node, so it takes O(n/degree) time.  Use it to
+// tune the degree or to detect a tree left sparse by churn, which Rebuild
+// can repair.
+func (t *BTreeG[T]) Stats() Stats {
+	s := Stats{Items: t.length}
+	if c := t.cow.comparisons; c != nil {
+		s.Comparisons = c.stats()
+	}
+	if l := t.cow.latency; l != nil {
+		s.Latency = l.stats()
+	}
+	if t.root == nil || len(t.root.items) == 0 {
+		return s
+	}
+	maxItems := t.maxItems()
+	var walk func(n *node[T], depth int)
+	walk = func(n *node[T], depth int) {
+		if depth > s.Height {
+			s.Height = depth
+		}
+		bucket := len(n.items) * 10 / maxItems
+		if bucket > 9 {
+			bucket = 9
+		}
+		s.FillHistogram[bucket]++
+		if len(n.children) == 0 {
+			s.LeafNodes++
+			return
+		}
+		s.InternalNodes++
+		for _, c := range n.children {
+			walk(c, depth+1)
+		}
+	}
+	walk(t.root, 1)
+	s.FillFactor = float64(t.length) / float64((s.InternalNodes+s.LeafNodes)*maxItems)
+	return s
+}
+
+// FillFactor returns the fraction of the tree's item slots that are in use,
+// as reported by Stats.  Random inserts typically leave a tree around 70%
+// full; long runs of mixed inserts and deletes can leave it much emptier.  It
+// returns 0 for an empty tree.
+func (t *BTreeG[T]) FillFactor() float64 {
+	return t.Stats().FillFactor
+}
+
+// ShapeReport describes the shape of a tree in more detail than Stats, as
+// returned by BTreeG.ShapeReport.  Its String method formats it for logs and
+// debugging endpoints.
+type ShapeReport struct {
+	Stats
+	// Levels describes each level of the tree, starting with the root.
+	Levels []LevelShape
+	// ShallowestLeaf and DeepestLeaf are the depths of the shallowest and
+	// deepest leaves, counting the root as depth 0.  They are equal in any
+	// tree that passes Verify.
+	ShallowestLeaf, DeepestLeaf int
+}
+
+// LevelShape describes one level of a tree.
+type LevelShape struct {
+	// Nodes is the number of nodes at the level, and Items the number of
+	// items they hold.
+	Nodes, Items int
+	// FillFactor is the fraction of the level's item slots that are in
+	// use.
+	FillFactor float64
+}
+
+// ShapeReport returns a report on the shape of the tree: the statistics
+// returned by Stats, plus node and item counts for each level and the depths
+// of the leaves.  Like Stats it visits every node, so it takes O(n/degree)
+// time.  A tree whose lower levels are much emptier than a freshly built
+// one has been degraded by churn, and Rebuild can repair it.
+func (t *BTreeG[T]) ShapeReport() ShapeReport {
+	r := ShapeReport{Stats: t.Stats()}
+	if r.Height == 0 {
+		return r
+	}
+	r.Levels = make([]LevelShape, r.Height)
+	r.ShallowestLeaf = r.Height
+	var walk func(n *node[T], depth int)
+	walk = func(n *node[T], depth int) {
+		r.Levels[depth].Nodes++
+		r.Levels[depth].Items += len(n.items)
+		if len(n.children) == 0 {
+			if depth < r.ShallowestLeaf {
+				r.ShallowestLeaf = depth
+			}
+			if depth > r.DeepestLeaf {
+				r.DeepestLeaf = depth
+			}
+			return
+		}
+		for _, c := range n.children {
+			walk(c, depth+1)
+		}
+	}
+	walk(t.root, 0)
+	for i := range r.Levels {
+		l := &r.Levels[i]
+		l.FillFactor = float64(l.Items) / float64(l.Nodes*t.maxItems())
+	}
+	return r
+}
+
+// String formats the report as a few lines of text.
+func (r ShapeReport) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d items, height %d, %d internal and %d leaf nodes, %.0f%% full\n",
+		r.Items, r.Height, r.InternalNodes, r.LeafNodes, 100*r.FillFactor)
+	for i, l := range r.Levels {
+		fmt.Fprintf(&b, "level %d: %d nodes, %d items, %.0f%% full\n", i, l.Nodes, l.Items, 100*l.FillFactor)
+	}
+	if len(r.Levels) > 0 {
+		fmt.Fprintf(&b, "leaves at depth %d to %d\n", r.ShallowestLeaf, r.DeepestLeaf)
+	}
+	b.WriteString("fill:")
+	for i, n := range r.FillHistogram {
+		fmt.Fprintf(&b, " %d-%d%%:%d", i*10, (i+1)*10, n)
+	}
+	return b.String()
+}
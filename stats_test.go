@@ -0,0 +1,85 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStatsG(t *testing.T) {
+	tr := NewOrderedG[int](2)
+	if s := tr.Stats(); s != (Stats{}) {
+		t.Fatalf("empty tree stats: %+v", s)
+	}
+	for i := 0; i < 3; i++ {
+		tr.ReplaceOrInsert(i)
+	}
+	want := Stats{Items: 3, Height: 1, LeafNodes: 1, FillFactor: 1}
+	want.FillHistogram[9] = 1
+	if s := tr.Stats(); s != want {
+		t.Fatalf("full root stats: got %+v, want %+v", s, want)
+	}
+	// A fourth item splits the root into two leaves of 1 and 2 items.
+	tr.ReplaceOrInsert(3)
+	want = Stats{Items: 4, Height: 2, InternalNodes: 1, LeafNodes: 2, FillFactor: 4.0 / 9}
+	want.FillHistogram[3] = 2
+	want.FillHistogram[6] = 1
+	if s := tr.Stats(); s != want {
+		t.Fatalf("split stats: got %+v, want %+v", s, want)
+	}
+	for i := 4; i < 1000; i++ {
+		tr.ReplaceOrInsert(i)
+	}
+	s := tr.Stats()
+	total := 0
+	for _, c := range s.FillHistogram {
+		total += c
+	}
+	if total != s.InternalNodes+s.LeafNodes || s.Height < 5 || s.Items != 1000 {
+		t.Fatalf("inconsistent stats: %+v", s)
+	}
+}
+
+func TestShapeReport(t *testing.T) {
+	tr := NewOrderedG[int](4)
+	if r := tr.ShapeReport(); r.Height != 0 || len(r.Levels) != 0 {
+		t.Errorf("empty tree: %+v", r)
+	}
+	for i := 0; i < 1000; i++ {
+		tr.ReplaceOrInsert(i)
+	}
+	r := tr.ShapeReport()
+	if len(r.Levels) != r.Height || r.Levels[0].Nodes != 1 {
+		t.Fatalf("levels %+v for height %d", r.Levels, r.Height)
+	}
+	nodes, items := 0, 0
+	for _, l := range r.Levels {
+		nodes += l.Nodes
+		items += l.Items
+	}
+	if nodes != r.InternalNodes+r.LeafNodes || items != 1000 {
+		t.Errorf("levels hold %d nodes and %d items, want %d and 1000", nodes, items, r.InternalNodes+r.LeafNodes)
+	}
+	if r.ShallowestLeaf != r.Height-1 || r.DeepestLeaf != r.Height-1 {
+		t.Errorf("leaves at depth %d to %d, height %d", r.ShallowestLeaf, r.DeepestLeaf, r.Height)
+	}
+	if s := r.String(); !strings.Contains(s, "1000 items") || !strings.Contains(s, "level 0: 1 nodes") {
+		t.Errorf("String() = %q", s)
+	}
+}
@@ -0,0 +1,323 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// StripedG is a tree that is safe for concurrent use, and that lets writes
+// to different parts of the key space proceed in parallel.  The key space is
+// split at fixed boundaries into stripes, each a BTreeG with its own lock.
+// Operations on single items lock only their item's stripe; operations on
+// ranges lock every stripe the range overlaps, in order, before doing any
+// work, so they see and change the range atomically.
+//
+// Writes scale with the number of stripes when they are spread across them,
+// so the boundaries should split the expected keys evenly.  Iterators are
+// called with stripes locked, so they must not call methods of the StripedG.
+//
+// Len, Min and Max never lock, so monitoring can call them as often as it
+// likes without slowing down writers.
+type StripedG[T any] struct {
+	less    LessFunc[T]
+	bounds  []T
+	stripes []stripe[T]
+	length  int64 // accessed atomically
+}
+
+type stripe[T any] struct {
+	mu sync.RWMutex
+	t  *BTreeG[T]
+	// edges holds a *stripeEdges[T] describing t, published by writers
+	// for Min and Max to read without locking.
+	edges atomic.Value
+}
+
+// stripeEdges holds the smallest and largest items in a stripe.
+type stripeEdges[T any] struct {
+	min, max T
+	ok       bool // false if the stripe is empty
+}
+
+// publish updates the stripe's edges after a write.  The stripe must be
+// write-locked.
+func (st *stripe[T]) publish() {
+	var e stripeEdges[T]
+	e.min, e.ok = st.t.Min()
+	e.max, _ = st.t.Max()
+	st.edges.Store(&e)
+}
+
+func (st *stripe[T]) loadEdges() *stripeEdges[T] {
+	return st.edges.Load().(*stripeEdges[T])
+}
+
+// NewStripedG returns an empty StripedG whose stripes are trees of the given
+// degree ordered by less.  bounds are the smallest items of every stripe
+// but the first, in increasing order, so there are len(bounds)+1 stripes:
+// the first holds items less than bounds[0], stripe i holds items in
+// [bounds[i-1], bounds[i]), and the last holds items greater than or equal
+// to the last bound.
+func NewStripedG[T any](degree int, less LessFunc[T], bounds ...T) *StripedG[T] {
+	for i := 1; i < len(bounds); i++ {
+		if !less(bounds[i-1], bounds[i]) {
+			panic("btree: StripedG bounds are not in increasing order")
+		}
+	}
+	s := &StripedG[T]{
+		less:    less,
+		bounds:  append([]T(nil), bounds...),
+		stripes: make([]stripe[T], len(bounds)+1),
+	}
+	for i := range s.stripes {
+		s.stripes[i].t = NewG(degree, less)
+		s.stripes[i].publish()
+	}
+	return s
+}
+
+// stripeOf returns the index of the stripe that holds item.
+func (s *StripedG[T]) stripeOf(item T) int {
+	return sort.Search(len(s.bounds), func(i int) bool {
+		return s.less(item, s.bounds[i])
+	})
+}
+
+// lockRange locks, in order, the stripes holding items in [lo, hi], as
+// readers if read is true or writers otherwise, and returns them.  A
+// range's stripes are always locked in the same order, so concurrent range
+// operations can't deadlock.
+func (s *StripedG[T]) lockRange(lo, hi int, read bool) []stripe[T] {
+	stripes := s.stripes[lo : hi+1]
+	for i := range stripes {
+		if read {
+			stripes[i].mu.RLock()
+		} else {
+			stripes[i].mu.Lock()
+		}
+	}
+	return stripes
+}
+
+func unlockRange[T any](stripes []stripe[T], read bool) {
+	for i := range stripes {
+		if read {
+			stripes[i].mu.RUnlock()
+		} else {
+			stripes[i].mu.Unlock()
+		}
+	}
+}
+
+// ReplaceOrInsert adds item, returning the item it replaced, if any, as
+// BTreeG.ReplaceOrInsert does.
+func (s *StripedG[T]) ReplaceOrInsert(item T) (T, bool) {
+	st := &s.stripes[s.stripeOf(item)]
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	old, replaced := st.t.ReplaceOrInsert(item)
+	if !replaced {
+		atomic.AddInt64(&s.length, 1)
+	}
+	// Publish new edges only if item became one of them.
+	if e := st.loadEdges(); !e.ok || !s.less(e.min, item) || !s.less(item, e.max) {
+		st.publish()
+	}
+	return old, replaced
+}
+
+// Delete removes an item equal to item, returning it, as BTreeG.Delete
+// does.
+func (s *StripedG[T]) Delete(item T) (T, bool) {
+	st := &s.stripes[s.stripeOf(item)]
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	old, deleted := st.t.Delete(item)
+	if !deleted {
+		return old, false
+	}
+	atomic.AddInt64(&s.length, -1)
+	// Publish new edges only if old was one of them.
+	if e := st.loadEdges(); !s.less(e.min, old) || !s.less(old, e.max) {
+		st.publish()
+	}
+	return old, true
+}
+
+// Get looks for key, returning it, as BTreeG.Get does.
+func (s *StripedG[T]) Get(key T) (T, bool) {
+	st := &s.stripes[s.stripeOf(key)]
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+	return st.t.Get(key)
+}
+
+// Has returns true if key is in the tree.
+func (s *StripedG[T]) Has(key T) bool {
+	_, ok := s.Get(key)
+	return ok
+}
+
+// Len returns the number of items in the tree.  It never blocks.
+func (s *StripedG[T]) Len() int {
+	return int(atomic.LoadInt64(&s.length))
+}
+
+// Min returns the smallest item in the tree, or (zeroValue, false) if the
+// tree is empty.  It never blocks, but while other goroutines write to
+// several stripes, it may miss an item that was added to one stripe before
+// an item was removed from another.
+func (s *StripedG[T]) Min() (_ T, _ bool) {
+	for i := range s.stripes {
+		if e := s.stripes[i].loadEdges(); e.ok {
+			return e.min, true
+		}
+	}
+	return
+}
+
+// Max returns the largest item in the tree, or (zeroValue, false) if the
+// tree is empty.  Like Min, it never blocks.
+func (s *StripedG[T]) Max() (_ T, _ bool) {
+	for i := len(s.stripes) - 1; i >= 0; i-- {
+		if e := s.stripes[i].loadEdges(); e.ok {
+			return e.max, true
+		}
+	}
+	return
+}
+
+// Ascend calls iterator for every item in the tree, in order, until
+// iterator returns false.  It sees the tree as it was at a single moment.
+func (s *StripedG[T]) Ascend(iterator ItemIteratorG[T]) {
+	stripes := s.lockRange(0, len(s.stripes)-1, true)
+	defer unlockRange(stripes, true)
+	ok := true
+	for i := 0; i < len(stripes) && ok; i++ {
+		stripes[i].t.ascend(func(item T) bool {
+			ok = iterator(item)
+			return ok
+		})
+	}
+}
+
+// AscendRange calls iterator for every item within [greaterOrEqual,
+// lessThan), in order, until iterator returns false.  It sees the range as
+// it was at a single moment.
+func (s *StripedG[T]) AscendRange(greaterOrEqual, lessThan T, iterator ItemIteratorG[T]) {
+	if !s.less(greaterOrEqual, lessThan) {
+		return
+	}
+	stripes := s.lockRange(s.stripeOf(greaterOrEqual), s.stripeOf(lessThan), true)
+	defer unlockRange(stripes, true)
+	ok := true
+	for i := 0; i < len(stripes) && ok; i++ {
+		stripes[i].t.AscendRange(greaterOrEqual, lessThan, func(item T) bool {
+			ok = iterator(item)
+			return ok
+		})
+	}
+}
+
+// DeleteRange deletes every item within [greaterOrEqual, lessThan),
+// returning the number deleted.  Other goroutines see either all of the
+// range or none of it.
+func (s *StripedG[T]) DeleteRange(greaterOrEqual, lessThan T) int {
+	if !s.less(greaterOrEqual, lessThan) {
+		return 0
+	}
+	stripes := s.lockRange(s.stripeOf(greaterOrEqual), s.stripeOf(lessThan), false)
+	defer unlockRange(stripes, false)
+	deleted := 0
+	for i := range stripes {
+		t := stripes[i].t
+		for more := true; more; {
+			var n int
+			n, more = t.DeleteRangeLimit(greaterOrEqual, lessThan, 1024)
+			deleted += n
+		}
+		stripes[i].publish()
+	}
+	atomic.AddInt64(&s.length, -int64(deleted))
+	return deleted
+}
+
+// DeleteIf deletes every item for which pred returns true, returning the
+// number deleted.  Other goroutines see either all of the deletions or none
+// of them.
+//
+// DeleteIf locks every stripe and then purges them concurrently, using up
+// to parallelism goroutines.  Each goroutine takes the next unpurged stripe
+// whenever it finishes one, so a few large stripes don't leave the others
+// waiting.  A stripe losing more than half its items is rebuilt from the
+// rest, packed, rather than having items deleted one at a time.
+func (s *StripedG[T]) DeleteIf(parallelism int, pred func(T) bool) int {
+	stripes := s.lockRange(0, len(s.stripes)-1, false)
+	defer unlockRange(stripes, false)
+	if parallelism < 1 {
+		parallelism = 1
+	}
+	if parallelism > len(stripes) {
+		parallelism = len(stripes)
+	}
+	var next, deleted int64
+	var wg sync.WaitGroup
+	for w := 0; w < parallelism; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				i := int(atomic.AddInt64(&next, 1)) - 1
+				if i >= len(stripes) {
+					return
+				}
+				atomic.AddInt64(&deleted, int64(stripes[i].purge(pred)))
+			}
+		}()
+	}
+	wg.Wait()
+	atomic.AddInt64(&s.length, -deleted)
+	return int(deleted)
+}
+
+// purge deletes the items in the stripe for which pred returns true,
+// returning the number deleted.  The stripe must be locked.
+func (st *stripe[T]) purge(pred func(T) bool) int {
+	var kept, doomed []T
+	st.t.ascend(func(item T) bool {
+		if pred(item) {
+			doomed = append(doomed, item)
+		} else {
+			kept = append(kept, item)
+		}
+		return true
+	})
+	if 2*len(doomed) > st.t.Len() {
+		cow := *st.t.cow
+		st.t = st.t.built(&cow, kept)
+	} else {
+		for _, item := range doomed {
+			st.t.Delete(item)
+		}
+	}
+	st.publish()
+	return len(doomed)
+}
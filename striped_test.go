@@ -0,0 +1,174 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestStriped(t *testing.T) {
+	s := NewStripedG(*btreeDegree, Less[int](), 250, 500, 750)
+	var wg sync.WaitGroup
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := w; i < 1000; i += 4 {
+				s.ReplaceOrInsert(i)
+			}
+		}(w)
+	}
+	// Range scans run concurrently with the inserts.
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 20; i++ {
+			s.AscendRange(200, 800, func(item int) bool {
+				if item < 200 || item >= 800 {
+					t.Errorf("AscendRange(200, 800) visited %d", item)
+				}
+				return true
+			})
+		}
+	}()
+	wg.Wait()
+	if got := s.Len(); got != 1000 {
+		t.Fatalf("Len = %d, want 1000", got)
+	}
+	if got := s.DeleteRange(240, 510); got != 270 {
+		t.Errorf("DeleteRange(240, 510) = %d, want 270", got)
+	}
+	if s.Has(300) || !s.Has(510) || !s.Has(239) {
+		t.Error("DeleteRange deleted the wrong items")
+	}
+	var got []int
+	s.AscendRange(235, 515, func(item int) bool {
+		got = append(got, item)
+		return item < 512
+	})
+	if want := []int{235, 236, 237, 238, 239, 510, 511, 512}; !reflect.DeepEqual(got, want) {
+		t.Errorf("AscendRange = %v, want %v", got, want)
+	}
+	n, last := 0, -1
+	s.Ascend(func(item int) bool {
+		if item <= last {
+			t.Fatalf("Ascend visited %d after %d", item, last)
+		}
+		n, last = n+1, item
+		return true
+	})
+	if n != 730 {
+		t.Errorf("Ascend visited %d items, want 730", n)
+	}
+	if _, ok := s.Delete(999); !ok || s.Has(999) {
+		t.Error("Delete(999) failed")
+	}
+	expectPanic(t, "increasing order", func() { NewStripedG(2, Less[int](), 5, 5) })
+}
+
+func TestStripedDeleteIf(t *testing.T) {
+	s := NewStripedG(*btreeDegree, Less[int](), 100, 200, 300, 400)
+	for i := 0; i < 1000; i++ {
+		s.ReplaceOrInsert(i)
+	}
+	// Stripes 0-3 lose a third of their items; the last loses nearly all.
+	pred := func(i int) bool { return i%3 == 0 || i >= 410 }
+	want := 0
+	for i := 0; i < 1000; i++ {
+		if pred(i) {
+			want++
+		}
+	}
+	if got := s.DeleteIf(3, pred); got != want {
+		t.Errorf("DeleteIf = %d, want %d", got, want)
+	}
+	if got := s.Len(); got != 1000-want {
+		t.Errorf("Len = %d, want %d", got, 1000-want)
+	}
+	s.Ascend(func(i int) bool {
+		if pred(i) {
+			t.Errorf("%d survived DeleteIf", i)
+		}
+		return true
+	})
+	for i := range s.stripes {
+		if err := s.stripes[i].t.Verify(); err != nil {
+			t.Errorf("stripe %d: %v", i, err)
+		}
+	}
+	if !s.Has(401) || s.Has(500) {
+		t.Error("DeleteIf deleted the wrong items")
+	}
+	s.ReplaceOrInsert(500)
+	if !s.Has(500) {
+		t.Error("rebuilt stripe rejected an insert")
+	}
+}
+
+func TestStripedWaitFreeReads(t *testing.T) {
+	s := NewStripedG(*btreeDegree, Less[int](), 100, 200)
+	if _, ok := s.Min(); ok {
+		t.Error("Min of an empty tree succeeded")
+	}
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			min, minOK := s.Min()
+			max, maxOK := s.Max()
+			if n := s.Len(); n < 0 || n > 300 || minOK != maxOK || min > max {
+				t.Errorf("Len %d, Min %d, %v, Max %d, %v", n, min, minOK, max, maxOK)
+			}
+		}
+	}()
+	for i := 299; i >= 0; i-- {
+		s.ReplaceOrInsert(i)
+	}
+	close(stop)
+	wg.Wait()
+	check := func(wantLen, wantMin, wantMax int) {
+		t.Helper()
+		min, _ := s.Min()
+		max, _ := s.Max()
+		if s.Len() != wantLen || min != wantMin || max != wantMax {
+			t.Errorf("Len %d, Min %d, Max %d; want %d, %d, %d", s.Len(), min, max, wantLen, wantMin, wantMax)
+		}
+	}
+	check(300, 0, 299)
+	s.Delete(0)
+	s.Delete(299)
+	s.Delete(150)
+	check(297, 1, 298)
+	s.DeleteRange(0, 120)
+	check(178, 120, 298)
+	s.DeleteIf(2, func(i int) bool { return i > 250 })
+	check(130, 120, 250)
+	s.DeleteRange(0, 1000)
+	if _, ok := s.Max(); ok || s.Len() != 0 {
+		t.Error("emptied tree has a Max")
+	}
+}
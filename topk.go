@@ -0,0 +1,146 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+import "container/heap"
+
+// MaxTreeG is an AugmentedG whose summary is the largest value, selected
+// from each item by a value function, in each subtree.  Besides the
+// summaries, this lets it find the items with the largest values in a range
+// of keys, such as the hottest keys in a shard, without scanning the range.
+type MaxTreeG[T any, V Ordered] struct {
+	*AugmentedG[T, V]
+	value func(T) V
+}
+
+// NewMaxTreeG creates a new MaxTreeG with the given degree, ordered by less,
+// and with values selected by value.
+func NewMaxTreeG[T any, V Ordered](degree int, less LessFunc[T], value func(T) V) *MaxTreeG[T, V] {
+	aug := Augment[T, V]{
+		Of: value,
+		Combine: func(a, b V) V {
+			if a < b {
+				return b
+			}
+			return a
+		},
+	}
+	return &MaxTreeG[T, V]{AugmentedG: NewAugmentedG(degree, less, aug), value: value}
+}
+
+// Clone clones the tree lazily, like BTreeG.Clone.
+func (t *MaxTreeG[T, V]) Clone() *MaxTreeG[T, V] {
+	return &MaxTreeG[T, V]{AugmentedG: t.AugmentedG.Clone(), value: t.value}
+}
+
+// topKCandidate is either a single item or a whole subtree within the range
+// being searched, with the largest value it holds.
+type topKCandidate[T any, V Ordered] struct {
+	max  V
+	item T
+	s    *sumNode[T, V] // nil for a single item
+}
+
+type topKHeap[T any, V Ordered] []topKCandidate[T, V]
+
+func (h topKHeap[T, V]) Len() int            { return len(h) }
+func (h topKHeap[T, V]) Less(i, j int) bool  { return h[j].max < h[i].max }
+func (h topKHeap[T, V]) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *topKHeap[T, V]) Push(x interface{}) { *h = append(*h, x.(topKCandidate[T, V])) }
+func (h *topKHeap[T, V]) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// TopKInRange returns the k items within the range [greaterOrEqual,
+// lessThan) with the largest values, largest first.  Items with equal values
+// are returned in no particular order.
+//
+// The range is split into O(log n) whole subtrees and single items, which
+// are explored best first using their maxima, so TopKInRange takes roughly
+// O((k + log n) * degree * log n) time however large the range is.
+func (t *MaxTreeG[T, V]) TopKInRange(greaterOrEqual, lessThan T, k int) []T {
+	if t.root == nil || k <= 0 {
+		return nil
+	}
+	var h topKHeap[T, V]
+	t.rangePieces(&h, t.sumRoot(), optional(greaterOrEqual), optional(lessThan))
+	heap.Init(&h)
+	var out []T
+	for len(out) < k && len(h) > 0 {
+		c := heap.Pop(&h).(topKCandidate[T, V])
+		if c.s == nil {
+			out = append(out, c.item)
+			continue
+		}
+		n := c.s.n
+		for i, item := range n.items {
+			heap.Push(&h, topKCandidate[T, V]{max: t.value(item), item: item})
+			if len(n.children) > 0 {
+				heap.Push(&h, t.subtree(c.s.children[i]))
+			}
+		}
+		if len(n.children) > 0 {
+			heap.Push(&h, t.subtree(c.s.children[len(n.items)]))
+		}
+	}
+	return out
+}
+
+func (t *MaxTreeG[T, V]) subtree(s *sumNode[T, V]) topKCandidate[T, V] {
+	return topKCandidate[T, V]{max: s.sum, s: s}
+}
+
+// rangePieces appends to h the whole subtrees and single items that make up
+// the part of sn's subtree within [lo, hi), as addRange does for summaries.
+func (t *MaxTreeG[T, V]) rangePieces(h *topKHeap[T, V], sn *sumNode[T, V], lo, hi optionalItem[T]) {
+	n := sn.n
+	if !lo.valid && !hi.valid {
+		if len(n.items) > 0 {
+			*h = append(*h, t.subtree(sn))
+		}
+		return
+	}
+	i, j := 0, len(n.items)
+	if lo.valid {
+		i, _ = n.find(lo.item)
+	}
+	if hi.valid {
+		j, _ = n.find(hi.item)
+	}
+	if len(n.children) == 0 {
+		for k := i; k < j; k++ {
+			*h = append(*h, topKCandidate[T, V]{max: t.value(n.items[k]), item: n.items[k]})
+		}
+		return
+	}
+	if i >= j {
+		t.rangePieces(h, sn.children[i], lo, hi)
+		return
+	}
+	t.rangePieces(h, sn.children[i], lo, empty[T]())
+	for k := i; k < j; k++ {
+		*h = append(*h, topKCandidate[T, V]{max: t.value(n.items[k]), item: n.items[k]})
+		if k+1 < j {
+			*h = append(*h, t.subtree(sn.children[k+1]))
+		}
+	}
+	t.rangePieces(h, sn.children[j], empty[T](), hi)
+}
@@ -0,0 +1,69 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+import (
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestTopKInRange(t *testing.T) {
+	type hit struct{ key, count int }
+	tr := NewMaxTreeG(*btreeDegree, func(a, b hit) bool { return a.key < b.key }, func(h hit) int { return h.count })
+	counts := map[int]int{}
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 3000; i++ {
+		k := r.Intn(1000)
+		if r.Intn(5) == 0 {
+			tr.Delete(hit{key: k})
+			delete(counts, k)
+			continue
+		}
+		// Distinct counts keep the expected order unambiguous.
+		c := k*1000 + r.Intn(1000)
+		c = (c * 7919) % 1000003
+		tr.ReplaceOrInsert(hit{k, c})
+		counts[k] = c
+	}
+	for trial := 0; trial < 50; trial++ {
+		lo, hi := r.Intn(1100)-50, r.Intn(1100)-50
+		k := r.Intn(20)
+		var want []int
+		for key, c := range counts {
+			if key >= lo && key < hi {
+				want = append(want, c)
+			}
+		}
+		sort.Sort(sort.Reverse(sort.IntSlice(want)))
+		if len(want) > k {
+			want = want[:k]
+		}
+		var got []int
+		for _, h := range tr.TopKInRange(hit{key: lo}, hit{key: hi}, k) {
+			got = append(got, h.count)
+		}
+		if len(want) == 0 {
+			want = nil
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("TopKInRange(%d, %d, %d) = %v, want %v", lo, hi, k, got, want)
+		}
+	}
+}
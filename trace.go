@@ -0,0 +1,55 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+import "time"
+
+// TraceEvent describes a completed bulk operation, and is passed to the
+// tracer installed with WithTracer.  It has everything needed to record the
+// operation as a span in a tracing system or as a structured log entry, for
+// example:
+//
+//	btree.WithTracer[Item](func(e btree.TraceEvent) {
+//		slog.Info("btree", "op", e.Op, "items", e.Items, "duration", e.Duration)
+//	})
+type TraceEvent struct {
+	// Op is the name of the operation, such as "Rebuild".
+	Op string
+	// Items is the number of items the operation processed.
+	Items int
+	// Start is when the operation started, and Duration how long it took.
+	Start    time.Time
+	Duration time.Duration
+}
+
+// traceStart returns the start time of an operation, or the zero time if
+// tracing is off, to avoid reading the clock unnecessarily.
+func (t *BTreeG[T]) traceStart() time.Time {
+	if t.cow.tracer == nil {
+		return time.Time{}
+	}
+	return time.Now()
+}
+
+// traceEnd reports an operation that began at start, if tracing is on.
+func (t *BTreeG[T]) traceEnd(op string, start time.Time, items int) {
+	if t.cow.tracer == nil {
+		return
+	}
+	t.cow.tracer(TraceEvent{Op: op, Items: items, Start: start, Duration: time.Since(start)})
+}
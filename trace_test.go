@@ -0,0 +1,51 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTracer(t *testing.T) {
+	var ops []string
+	var counts []int
+	tr := NewWithOptions(WithOrdered[int](), WithTracer[int](func(e TraceEvent) {
+		if e.Start.IsZero() || e.Duration < 0 {
+			t.Errorf("bad timing in %+v", e)
+		}
+		ops = append(ops, e.Op)
+		counts = append(counts, e.Items)
+	}))
+	for i := 0; i < 100; i++ {
+		tr.ReplaceOrInsert(i)
+	}
+	tr.Rebuild()
+	tr.WithDegree(4)
+	tr.Freeze()
+	tr.Verify()
+	tr.SetLimit(50, nil, nil)
+	tr.Clear(false)
+	tr.ReplaceOrInsert(1)
+	tr.Clear(true)
+	wantOps := []string{"Rebuild", "WithDegree", "Freeze", "Verify", "SetLimit", "Clear"}
+	wantCounts := []int{100, 100, 100, 100, 100, 1}
+	if !reflect.DeepEqual(ops, wantOps) || !reflect.DeepEqual(counts, wantCounts) {
+		t.Errorf("got %v %v, want %v %v", ops, counts, wantOps, wantCounts)
+	}
+}
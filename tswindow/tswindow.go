@@ -0,0 +1,141 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+// Package tswindow indexes timestamped values for range aggregation, as
+// needed by metrics and rate-limiting pipelines.
+//
+// A Window is a btree.AugmentedG ordered by time, whose nodes carry the
+// count, sum, minimum, and maximum of their subtrees.  Aggregating any time
+// range takes O(log n) time however many points it covers, and old points
+// can be evicted from the front of the window as time passes.
+package tswindow
+
+import (
+	"time"
+
+	"github.com/google/btree"
+)
+
+// Aggregate summarizes the values of a set of points.
+type Aggregate struct {
+	Count    int
+	Sum      float64
+	Min, Max float64
+}
+
+// Mean returns the mean of the values, or NaN if there are none.
+func (a Aggregate) Mean() float64 {
+	return a.Sum / float64(a.Count)
+}
+
+// Point is a value recorded at a time.
+type Point struct {
+	Time  time.Time
+	Value float64
+}
+
+// point is a Point with a sequence number, so that a Window can hold several
+// points with the same time.
+type point struct {
+	Point
+	seq uint64
+}
+
+func less(a, b point) bool {
+	if !a.Time.Equal(b.Time) {
+		return a.Time.Before(b.Time)
+	}
+	return a.seq < b.seq
+}
+
+var augment = btree.Augment[point, Aggregate]{
+	Of: func(p point) Aggregate {
+		return Aggregate{Count: 1, Sum: p.Value, Min: p.Value, Max: p.Value}
+	},
+	Combine: func(a, b Aggregate) Aggregate {
+		out := Aggregate{Count: a.Count + b.Count, Sum: a.Sum + b.Sum, Min: a.Min, Max: a.Max}
+		if b.Min < out.Min {
+			out.Min = b.Min
+		}
+		if b.Max > out.Max {
+			out.Max = b.Max
+		}
+		return out
+	},
+}
+
+// Window holds timestamped values.  The zero value is not usable; create
+// windows with New.
+//
+// Write operations are not safe for concurrent use, but reads are.
+type Window struct {
+	t   *btree.AugmentedG[point, Aggregate]
+	seq uint64
+}
+
+// New returns an empty window.
+func New() *Window {
+	return &Window{t: btree.NewAugmentedG(btree.DefaultDegree, less, augment)}
+}
+
+// Len returns the number of points in the window.
+func (w *Window) Len() int {
+	return w.t.Len()
+}
+
+// Add records value at time t.  Points may be added in any order, and
+// several may share a time.  Adding points in time order is fastest.
+func (w *Window) Add(t time.Time, value float64) {
+	w.seq++
+	w.t.AppendMax(point{Point{t, value}, w.seq})
+}
+
+// Aggregate returns the aggregate of the points in [from, to), and false if
+// there are none.  It takes O(log n) time.
+func (w *Window) Aggregate(from, to time.Time) (Aggregate, bool) {
+	// Sequence numbers start at 1, so these sort before any point at the
+	// same time.
+	return w.t.SummaryRange(point{Point: Point{Time: from}}, point{Point: Point{Time: to}})
+}
+
+// Total returns the aggregate of every point in the window, and false if it
+// is empty.  It takes O(1) time.
+func (w *Window) Total() (Aggregate, bool) {
+	return w.t.Summary()
+}
+
+// EvictBefore removes every point earlier than t, returning how many were
+// removed.  Each removal takes O(log n) time.
+func (w *Window) EvictBefore(t time.Time) int {
+	n := 0
+	for {
+		p, ok := w.t.Min()
+		if !ok || !p.Time.Before(t) {
+			return n
+		}
+		w.t.DeleteMin()
+		n++
+	}
+}
+
+// Ascend calls iter for each point in [from, to) in time order, until iter
+// returns false.
+func (w *Window) Ascend(from, to time.Time, iter func(Point) bool) {
+	w.t.AscendRange(point{Point: Point{Time: from}}, point{Point: Point{Time: to}}, func(p point) bool {
+		return iter(p.Point)
+	})
+}
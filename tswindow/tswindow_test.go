@@ -0,0 +1,89 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package tswindow
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+var epoch = time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func at(sec int) time.Time {
+	return epoch.Add(time.Duration(sec) * time.Second)
+}
+
+func TestAggregate(t *testing.T) {
+	w := New()
+	var pts []Point
+	for _, i := range rand.Perm(1000) {
+		// Two points per second.
+		p := Point{at(i / 2), float64(i)}
+		pts = append(pts, p)
+		w.Add(p.Time, p.Value)
+	}
+	for k := 0; k < 100; k++ {
+		from, to := at(rand.Intn(550)-20), at(rand.Intn(550)-20)
+		want := Aggregate{Min: 1e9, Max: -1e9}
+		for _, p := range pts {
+			if !p.Time.Before(from) && p.Time.Before(to) {
+				want.Count++
+				want.Sum += p.Value
+				if p.Value < want.Min {
+					want.Min = p.Value
+				}
+				if p.Value > want.Max {
+					want.Max = p.Value
+				}
+			}
+		}
+		got, ok := w.Aggregate(from, to)
+		if ok != (want.Count > 0) || (ok && got != want) {
+			t.Fatalf("Aggregate(%v, %v) = %+v, %v; want %+v", from, to, got, ok, want)
+		}
+	}
+}
+
+func TestEvictBefore(t *testing.T) {
+	w := New()
+	for i := 0; i < 100; i++ {
+		w.Add(at(i), 1)
+	}
+	if n := w.EvictBefore(at(60)); n != 60 {
+		t.Errorf("evicted %d, want 60", n)
+	}
+	if n := w.EvictBefore(at(60)); n != 0 {
+		t.Errorf("evicted %d again", n)
+	}
+	total, _ := w.Total()
+	if total.Count != 40 || total.Sum != 40 || w.Len() != 40 {
+		t.Errorf("total after eviction %+v, Len %d", total, w.Len())
+	}
+	var first Point
+	w.Ascend(at(0), at(1000), func(p Point) bool {
+		first = p
+		return false
+	})
+	if !first.Time.Equal(at(60)) {
+		t.Errorf("first point at %v, want %v", first.Time, at(60))
+	}
+	if mean := total.Mean(); mean != 1 {
+		t.Errorf("mean %v, want 1", mean)
+	}
+}
@@ -0,0 +1,136 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// TreeGroup keeps several related trees, such as a table and its secondary
+// indexes, consistent with each other for readers.  It works like an
+// RCUTreeG for all of them at once: writers change private copy-on-write
+// clones of the trees in an Update, and then publish them together, and
+// readers see a GroupSnapshot of the trees as of the last published Update,
+// without locking and without ever waiting for a writer.  A reader that
+// looks an item up in an index and then fetches it from the table never
+// sees an index entry whose row isn't there yet.
+//
+// The trees may hold different types of item; each is added with
+// AddToGroup, which returns a GroupMember for reaching that tree in
+// updates and snapshots.
+type TreeGroup struct {
+	mu        sync.Mutex    // held by writers
+	private   []interface{} // the latest version of each *BTreeG; only touched with mu held
+	published atomic.Value  // *GroupSnapshot
+}
+
+// GroupSnapshot is a consistent, read-only view of the trees of a TreeGroup,
+// as of one Update.  It never changes, and is safe to read from any number
+// of goroutines.
+type GroupSnapshot struct {
+	trees []interface{}
+}
+
+// GroupTx holds the writable copies of a TreeGroup's trees during an Update.
+type GroupTx struct {
+	trees []interface{}
+}
+
+// GroupMember identifies a tree of type *BTreeG[T] in a TreeGroup.
+type GroupMember[T any] struct {
+	i int
+}
+
+// NewTreeGroup returns an empty TreeGroup.
+func NewTreeGroup() *TreeGroup {
+	g := &TreeGroup{}
+	g.published.Store(&GroupSnapshot{})
+	return g
+}
+
+// AddToGroup adds t to g, and publishes a snapshot including it.  g takes
+// ownership of t, which must not be used afterwards.  Snapshots published
+// before t was added don't include it.
+func AddToGroup[T any](g *TreeGroup, t *BTreeG[T]) GroupMember[T] {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.private = append(g.private, t)
+	g.publish(g.private)
+	return GroupMember[T]{i: len(g.private) - 1}
+}
+
+// publish publishes clones of trees, which become g's latest versions.
+func (g *TreeGroup) publish(trees []interface{}) {
+	s := &GroupSnapshot{trees: make([]interface{}, len(trees))}
+	for i, t := range trees {
+		s.trees[i] = t.(cloner).cloneAny()
+	}
+	g.private = trees
+	g.published.Store(s)
+}
+
+// cloner is implemented by every *BTreeG, so that a TreeGroup can clone
+// trees of any item type.
+type cloner interface {
+	cloneAny() interface{}
+}
+
+func (t *BTreeG[T]) cloneAny() interface{} {
+	return t.Clone()
+}
+
+// Snapshot returns the last published snapshot of the group's trees.
+func (g *TreeGroup) Snapshot() *GroupSnapshot {
+	return g.published.Load().(*GroupSnapshot)
+}
+
+// Read calls f with the last published snapshot of the group's trees, which
+// f must only read.  It never blocks.
+func (g *TreeGroup) Read(f func(s *GroupSnapshot)) {
+	f(g.Snapshot())
+}
+
+// Update calls f with private copies of the latest versions of the group's
+// trees, for f to modify, and then publishes them all at once.  Updates are
+// serialized: an Update waits for earlier ones to finish.  If f panics,
+// nothing is published and the trees are unchanged.
+//
+// Each Update clones every tree in the group, which takes O(1) time per
+// tree, and copies only the nodes that f changes.
+func (g *TreeGroup) Update(f func(tx *GroupTx)) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	tx := &GroupTx{trees: make([]interface{}, len(g.private))}
+	for i, t := range g.private {
+		tx.trees[i] = t.(cloner).cloneAny()
+	}
+	f(tx)
+	g.publish(tx.trees)
+}
+
+// In returns m's tree as of snapshot s.  It must only be read, never written
+// to.  In panics if m was added to the group after s was published.
+func (m GroupMember[T]) In(s *GroupSnapshot) *BTreeG[T] {
+	return s.trees[m.i].(*BTreeG[T])
+}
+
+// Write returns m's tree in the Update running tx, for writing.
+func (m GroupMember[T]) Write(tx *GroupTx) *BTreeG[T] {
+	return tx.trees[m.i].(*BTreeG[T])
+}
@@ -0,0 +1,98 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestTreeGroup(t *testing.T) {
+	type row struct {
+		id   int
+		name string
+	}
+	type nameEntry struct {
+		name string
+		id   int
+	}
+	g := NewTreeGroup()
+	rows := AddToGroup(g, NewG(*btreeDegree, func(a, b row) bool { return a.id < b.id }))
+	names := AddToGroup(g, NewG(*btreeDegree, func(a, b nameEntry) bool {
+		return a.name < b.name || (a.name == b.name && a.id < b.id)
+	}))
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				g.Read(func(s *GroupSnapshot) {
+					table, index := rows.In(s), names.In(s)
+					if table.Len() != index.Len() {
+						t.Errorf("snapshot has %d rows but %d index entries", table.Len(), index.Len())
+					}
+					index.Ascend(func(e nameEntry) bool {
+						if r, ok := table.Get(row{id: e.id}); !ok || r.name != e.name {
+							t.Errorf("index entry %v doesn't match row %v", e, r)
+							return false
+						}
+						return true
+					})
+				})
+			}
+		}()
+	}
+	for i := 0; i < 500; i++ {
+		g.Update(func(tx *GroupTx) {
+			table, index := rows.Write(tx), names.Write(tx)
+			// Rename an existing row, or add a new one.
+			id := i % 100
+			if old, ok := table.Get(row{id: id}); ok {
+				index.Delete(nameEntry{old.name, id})
+			}
+			r := row{id, fmt.Sprintf("name%d", i)}
+			table.ReplaceOrInsert(r)
+			index.ReplaceOrInsert(nameEntry{r.name, id})
+		})
+	}
+	close(stop)
+	wg.Wait()
+
+	s := g.Snapshot()
+	if rows.In(s).Len() != 100 || names.In(s).Len() != 100 {
+		t.Errorf("final snapshot has %d rows and %d index entries", rows.In(s).Len(), names.In(s).Len())
+	}
+	expectPanic(t, "boom", func() {
+		g.Update(func(tx *GroupTx) {
+			rows.Write(tx).Clear(false)
+			panic("boom")
+		})
+	})
+	if g.Snapshot() != s || rows.In(g.Snapshot()).Len() != 100 {
+		t.Error("a panicking Update published changes")
+	}
+}
@@ -0,0 +1,113 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+import "fmt"
+
+// Verify checks the tree's internal invariants and returns an error
+// describing the first violation it finds, or nil if there is none.  It
+// checks that:
+//   - every item is less than the item after it, according to the tree's
+//     LessFunc;
+//   - every node other than the root has between degree-1 and 2*degree-1
+//     items, and the root has at most 2*degree-1 (with SplitRightBiased,
+//     nodes on the right edge of the tree need only have one item);
+//   - every internal node has exactly one more child than it has items;
+//   - all leaves are at the same depth;
+//   - Len matches the number of items in the tree;
+//   - with WithKeyChecks, no item has changed since it was inserted.
+//
+// A tree can only fail these checks if its LessFunc is not a strict weak
+// ordering, or if items were modified in ways that changed their order
+// while they were in the tree.  Verify visits every item, so it takes O(n)
+// time.
+func (t *BTreeG[T]) Verify() error {
+	start := t.traceStart()
+	v := verifier[T]{t: t, leafDepth: -1}
+	defer func() { t.traceEnd("Verify", start, v.count) }()
+	if t.root != nil {
+		if err := v.node(t.root, 0, true); err != nil {
+			return err
+		}
+	}
+	if v.count != t.length {
+		return fmt.Errorf("btree: Len is %v but the tree holds %v items", t.length, v.count)
+	}
+	if t.keys != nil {
+		return t.verifyKeys()
+	}
+	return nil
+}
+
+// verifier holds the state of a Verify traversal.
+type verifier[T any] struct {
+	t         *BTreeG[T]
+	leafDepth int
+	count     int
+	last      optionalItem[T]
+}
+
+// node checks the subtree rooted at n.  edge is true if n is on the right
+// edge of the tree.
+func (v *verifier[T]) node(n *node[T], depth int, edge bool) error {
+	if len(n.items) > v.t.maxItems() {
+		return fmt.Errorf("btree: node at depth %v has %v items, more than the maximum of %v", depth, len(n.items), v.t.maxItems())
+	}
+	minItems := v.t.minItems()
+	if edge && v.t.cow.splitPolicy == SplitRightBiased {
+		minItems = 1
+	}
+	if n != v.t.root && len(n.items) < minItems {
+		return fmt.Errorf("btree: node at depth %v has %v items, fewer than the minimum of %v", depth, len(n.items), minItems)
+	}
+	if len(n.children) == 0 {
+		if v.leafDepth < 0 {
+			v.leafDepth = depth
+		} else if depth != v.leafDepth {
+			return fmt.Errorf("btree: leaves at depths %v and %v", v.leafDepth, depth)
+		}
+		for _, item := range n.items {
+			if err := v.item(item, depth); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if len(n.children) != len(n.items)+1 {
+		return fmt.Errorf("btree: node at depth %v has %v items but %v children", depth, len(n.items), len(n.children))
+	}
+	for i, item := range n.items {
+		if err := v.node(n.children[i], depth+1, false); err != nil {
+			return err
+		}
+		if err := v.item(item, depth); err != nil {
+			return err
+		}
+	}
+	return v.node(n.children[len(n.items)], depth+1, edge)
+}
+
+// item checks that item comes after the last item visited, in order.
+func (v *verifier[T]) item(item T, depth int) error {
+	if v.last.valid && !v.t.cow.less(v.last.item, item) {
+		return fmt.Errorf("btree: items %v and %v are out of order (at depth %v)", v.last.item, item, depth)
+	}
+	v.last = optional(item)
+	v.count++
+	return nil
+}
@@ -0,0 +1,69 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func TestVerifyG(t *testing.T) {
+	build := func() *BTreeG[int] {
+		tr := NewOrderedG[int](3)
+		for _, v := range rand.Perm(200) {
+			tr.ReplaceOrInsert(v)
+		}
+		for _, v := range rand.Perm(200)[:50] {
+			tr.Delete(v)
+		}
+		return tr
+	}
+	if err := build().Verify(); err != nil {
+		t.Fatalf("valid tree: %v", err)
+	}
+	if err := NewOrderedG[int](3).Verify(); err != nil {
+		t.Fatalf("empty tree: %v", err)
+	}
+	for _, c := range []struct {
+		corrupt func(tr *BTreeG[int])
+		want    string
+	}{
+		{func(tr *BTreeG[int]) {
+			c := tr.root.children[0]
+			c.items[0], c.items[1] = c.items[1], c.items[0]
+		}, "out of order"},
+		{func(tr *BTreeG[int]) { tr.length++ }, "Len is"},
+		{func(tr *BTreeG[int]) { tr.root.children = tr.root.children[:1] }, "children"},
+		{func(tr *BTreeG[int]) { tr.root.children[0].items = tr.root.children[0].items[:1] }, "fewer than the minimum"},
+		{func(tr *BTreeG[int]) {
+			c := tr.root.children[0]
+			c.items = append(c.items, make([]int, tr.maxItems())...)
+		}, "more than the maximum"},
+		{func(tr *BTreeG[int]) {
+			c := tr.root.children[0]
+			c.children[0] = c.children[0].children[0]
+		}, "leaves at depths"},
+	} {
+		tr := build()
+		c.corrupt(tr)
+		if err := tr.Verify(); err == nil || !strings.Contains(err.Error(), c.want) {
+			t.Errorf("got error %v, want one containing %q", err, c.want)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+// ViewG is a value derived from a tree's items, such as a count of items per
+// category, that the tree keeps up to date as items are added and removed,
+// instead of callers recomputing it by scanning.  See AddView.
+//
+// Aggregates over ranges of the tree's ordering, such as sums of prefixes,
+// are better served by AugmentedG.
+type ViewG[T any] interface {
+	// Add is called after item is added to the tree.
+	Add(item T)
+	// Remove is called after item is removed from the tree, including when
+	// it is replaced by an equal item.
+	Remove(item T)
+	// Reset is called when the tree is cleared.
+	Reset()
+	// Clone returns an independent copy of the view, for a clone of the
+	// tree.
+	Clone() ViewG[T]
+}
+
+// AddView registers v to be updated by every later change to t, after first
+// adding every item already in t to it.
+//
+// Clones of t get clones of its views, so a view and its tree can be read
+// consistently by cloning the tree, which is cheap, and reading the clone
+// and its Views while the original carries on changing.
+func (t *BTreeG[T]) AddView(v ViewG[T]) {
+	t.ascend(func(item T) bool {
+		v.Add(item)
+		return true
+	})
+	t.views = append(t.views, v)
+}
+
+// Views returns t's views, in the order they were added.
+func (t *BTreeG[T]) Views() []ViewG[T] {
+	return t.views
+}
+
+func cloneViews[T any](views []ViewG[T]) []ViewG[T] {
+	if views == nil {
+		return nil
+	}
+	out := make([]ViewG[T], len(views))
+	for i, v := range views {
+		out[i] = v.Clone()
+	}
+	return out
+}
+
+// BucketSumG is a ViewG holding, for each bucket, the sum of the values of
+// the tree's items in that bucket.  With a value function that returns 1, it
+// counts the items in each bucket.
+type BucketSumG[T any, K comparable, N Numeric] struct {
+	bucket func(T) K
+	value  func(T) N
+	sums   map[K]N
+	items  map[K]int // number of items in each bucket
+}
+
+// NewBucketSumG returns an empty BucketSumG placing items in buckets with
+// bucket and summing value.
+func NewBucketSumG[T any, K comparable, N Numeric](bucket func(T) K, value func(T) N) *BucketSumG[T, K, N] {
+	return &BucketSumG[T, K, N]{bucket: bucket, value: value, sums: make(map[K]N), items: make(map[K]int)}
+}
+
+// Get returns the sum for bucket k.
+func (b *BucketSumG[T, K, N]) Get(k K) N {
+	return b.sums[k]
+}
+
+// Len returns the number of buckets holding items.
+func (b *BucketSumG[T, K, N]) Len() int {
+	return len(b.sums)
+}
+
+// Range calls f for each bucket holding items and its sum, in no particular
+// order, until f returns false.
+func (b *BucketSumG[T, K, N]) Range(f func(k K, sum N) bool) {
+	for k, sum := range b.sums {
+		if !f(k, sum) {
+			return
+		}
+	}
+}
+
+// Add implements ViewG.
+func (b *BucketSumG[T, K, N]) Add(item T) {
+	k := b.bucket(item)
+	b.sums[k] += b.value(item)
+	b.items[k]++
+}
+
+// Remove implements ViewG.
+func (b *BucketSumG[T, K, N]) Remove(item T) {
+	k := b.bucket(item)
+	if b.items[k]--; b.items[k] == 0 {
+		delete(b.items, k)
+		delete(b.sums, k)
+		return
+	}
+	b.sums[k] -= b.value(item)
+}
+
+// Reset implements ViewG.
+func (b *BucketSumG[T, K, N]) Reset() {
+	b.sums = make(map[K]N)
+	b.items = make(map[K]int)
+}
+
+// Clone implements ViewG.
+func (b *BucketSumG[T, K, N]) Clone() ViewG[T] {
+	out := &BucketSumG[T, K, N]{
+		bucket: b.bucket,
+		value:  b.value,
+		sums:   make(map[K]N, len(b.sums)),
+		items:  make(map[K]int, len(b.items)),
+	}
+	for k, sum := range b.sums {
+		out.sums[k] = sum
+	}
+	for k, n := range b.items {
+		out.items[k] = n
+	}
+	return out
+}
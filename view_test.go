@@ -0,0 +1,84 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestBucketSumView(t *testing.T) {
+	type kv struct{ k, v int }
+	tr := NewG(2, func(a, b kv) bool { return a.k < b.k })
+	for i := 0; i < 10; i++ {
+		tr.ReplaceOrInsert(kv{i, i})
+	}
+	bucket := func(x kv) int { return x.k % 3 }
+	counts := NewBucketSumG(bucket, func(kv) int { return 1 })
+	sums := NewBucketSumG(bucket, func(x kv) int { return x.v })
+	tr.AddView(counts)
+	tr.AddView(sums)
+	want := map[kv]bool{}
+	tr.Ascend(func(x kv) bool {
+		want[x] = true
+		return true
+	})
+	for i := 0; i < 1000; i++ {
+		x := kv{rand.Intn(50), rand.Intn(100)}
+		switch rand.Intn(3) {
+		case 0, 1:
+			tr.ReplaceOrInsert(x)
+			for y := range want {
+				if y.k == x.k {
+					delete(want, y)
+				}
+			}
+			want[x] = true
+		case 2:
+			tr.Delete(x)
+			for y := range want {
+				if y.k == x.k {
+					delete(want, y)
+				}
+			}
+		}
+		if i == 500 {
+			snap := tr.Clone()
+			wantCount := snap.Views()[0].(*BucketSumG[kv, int, int]).Get(1)
+			defer func() {
+				if got := snap.Views()[0].(*BucketSumG[kv, int, int]).Get(1); got != wantCount {
+					t.Errorf("clone's view changed from %d to %d", wantCount, got)
+				}
+			}()
+		}
+	}
+	wantCounts, wantSums := map[int]int{}, map[int]int{}
+	for x := range want {
+		wantCounts[bucket(x)]++
+		wantSums[bucket(x)] += x.v
+	}
+	for b := 0; b < 3; b++ {
+		if counts.Get(b) != wantCounts[b] || sums.Get(b) != wantSums[b] {
+			t.Errorf("bucket %d: count %d sum %d, want %d %d", b, counts.Get(b), sums.Get(b), wantCounts[b], wantSums[b])
+		}
+	}
+	tr.Clear(false)
+	if counts.Len() != 0 || sums.Len() != 0 {
+		t.Errorf("views not reset by Clear")
+	}
+}
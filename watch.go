@@ -0,0 +1,97 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+// WatchOp is the kind of change described by a WatchEvent.
+type WatchOp int
+
+const (
+	// WatchInsert means Item was added to the tree.
+	WatchInsert WatchOp = iota
+	// WatchDelete means Item was removed from the tree, including by being
+	// replaced by an equal item, which is then reported by a WatchInsert.
+	WatchDelete
+	// WatchClear means the tree was cleared; Item is unset.
+	WatchClear
+)
+
+// WatchEvent describes a change to a tree, as passed to a Watch callback.
+type WatchEvent[T any] struct {
+	Op   WatchOp
+	Item T
+}
+
+// Watch calls fn for every later insert or delete of an item within the range
+// [greaterOrEqual, lessThan), and whenever the tree is cleared, until the
+// returned cancel function is called.  Changes made by every write method are
+// reported, including items evicted to stay within a limit (see SetLimit).
+//
+// fn is called synchronously from the write, after the tree has changed, and
+// must not modify the tree.  To receive changes on a channel, send to it from
+// fn.  Watches are registered as views (see ViewG), but unlike other views,
+// they aren't carried over to clones of the tree.
+func (t *BTreeG[T]) Watch(greaterOrEqual, lessThan T, fn func(WatchEvent[T])) (cancel func()) {
+	w := &watcher[T]{less: t.cow.less, lo: greaterOrEqual, hi: lessThan, fn: fn}
+	t.views = append(t.views, w)
+	return func() {
+		for i, v := range t.views {
+			if v == ViewG[T](w) {
+				t.views = append(t.views[:i:i], t.views[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// watcher is the view behind a Watch.  A watcher without fn, as made by
+// Clone, ignores changes.
+type watcher[T any] struct {
+	less   LessFunc[T]
+	lo, hi T
+	fn     func(WatchEvent[T])
+}
+
+func (w *watcher[T]) inRange(item T) bool {
+	return !w.less(item, w.lo) && w.less(item, w.hi)
+}
+
+// Add implements ViewG.
+func (w *watcher[T]) Add(item T) {
+	if w.fn != nil && w.inRange(item) {
+		w.fn(WatchEvent[T]{Op: WatchInsert, Item: item})
+	}
+}
+
+// Remove implements ViewG.
+func (w *watcher[T]) Remove(item T) {
+	if w.fn != nil && w.inRange(item) {
+		w.fn(WatchEvent[T]{Op: WatchDelete, Item: item})
+	}
+}
+
+// Reset implements ViewG.
+func (w *watcher[T]) Reset() {
+	if w.fn != nil {
+		w.fn(WatchEvent[T]{Op: WatchClear})
+	}
+}
+
+// Clone implements ViewG.
+func (w *watcher[T]) Clone() ViewG[T] {
+	return &watcher[T]{less: w.less, lo: w.lo, hi: w.hi}
+}
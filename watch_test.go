@@ -0,0 +1,60 @@
+// Copyright 2014-2022 Google Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build go1.18
+// +build go1.18
+
+package btree
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWatch(t *testing.T) {
+	tr := NewOrderedG[int](*btreeDegree)
+	tr.ReplaceOrInsert(15)
+	var got []WatchEvent[int]
+	cancel := tr.Watch(10, 20, func(e WatchEvent[int]) {
+		got = append(got, e)
+	})
+	other := tr.Watch(0, 5, func(WatchEvent[int]) {})
+	for i := 0; i < 30; i += 3 {
+		tr.ReplaceOrInsert(i)
+	}
+	tr.ReplaceOrInsert(12)
+	tr.Delete(15)
+	tr.Delete(25)
+	tr.DeleteMin()
+	tr.Clone().Delete(18) // clones don't report to t's watches
+	tr.Clear(false)
+	cancel()
+	tr.ReplaceOrInsert(11)
+	want := []WatchEvent[int]{
+		{WatchInsert, 12}, {WatchDelete, 15}, {WatchInsert, 15}, {WatchInsert, 18},
+		{WatchDelete, 12}, {WatchInsert, 12},
+		{WatchDelete, 15},
+		{WatchClear, 0},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if len(tr.Views()) != 1 {
+		t.Errorf("%d views after cancel, want 1", len(tr.Views()))
+	}
+	other()
+	if len(tr.Views()) != 0 {
+		t.Errorf("%d views after both cancelled, want 0", len(tr.Views()))
+	}
+}